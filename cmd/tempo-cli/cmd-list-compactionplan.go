@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/grafana/tempo/tempodb"
+)
+
+type listCompactionPlanCmd struct {
+	CompactorEndpoint string `arg:"" help:"compactor http endpoint"`
+	OrgID             string `help:"tenant to list the plan of. required in multitenant setups"`
+}
+
+func (l *listCompactionPlanCmd) Run(_ *globalOptions) error {
+	req, err := http.NewRequest(http.MethodGet, l.CompactorEndpoint+"/compactor/plan", nil)
+	if err != nil {
+		return err
+	}
+	if l.OrgID != "" {
+		req.Header.Set("X-Scope-OrgID", l.OrgID)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error querying compactor %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status querying compactor %s", resp.Status)
+	}
+
+	var plans []*tempodb.CompactionPlan
+	err = json.NewDecoder(resp.Body).Decode(&plans)
+	if err != nil {
+		return fmt.Errorf("error decoding compaction plan %w", err)
+	}
+
+	displayCompactionPlan(plans)
+
+	return nil
+}
+
+func displayCompactionPlan(plans []*tempodb.CompactionPlan) {
+	columns := []string{"tenant", "lvl", "owned", "inputs", "objects", "estimated size", "hash"}
+
+	out := make([][]string, 0)
+	for _, p := range plans {
+		line := make([]string, 0)
+
+		for _, c := range columns {
+			s := ""
+			switch c {
+			case "tenant":
+				s = p.TenantID
+			case "lvl":
+				s = strconv.Itoa(int(p.Level))
+			case "owned":
+				s = strconv.FormatBool(p.Owned)
+			case "inputs":
+				s = strconv.Itoa(len(p.Inputs))
+			case "objects":
+				s = humanize.Comma(int64(p.InputObjects))
+			case "estimated size":
+				s = humanize.Bytes(p.EstimatedSize)
+			case "hash":
+				s = p.Hash
+			}
+			line = append(line, s)
+		}
+		out = append(out, line)
+	}
+
+	fmt.Println()
+	w := tablewriter.NewWriter(os.Stdout)
+	w.SetHeader(columns)
+	w.AppendBulk(out)
+	w.Render()
+}
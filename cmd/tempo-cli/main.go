@@ -14,6 +14,7 @@ import (
 	"github.com/grafana/tempo/tempodb/backend/azure"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/s3"
+	"github.com/grafana/tempo/tempodb/backend/swift"
 )
 
 type globalOptions struct {
@@ -21,7 +22,7 @@ type globalOptions struct {
 }
 
 type backendOptions struct {
-	Backend string `help:"backend to connect to (s3/gcs/local/azure), optional, overrides backend in config file" enum:",s3,gcs,local,azure"`
+	Backend string `help:"backend to connect to (s3/gcs/local/azure/swift), optional, overrides backend in config file" enum:",s3,gcs,local,azure,swift"`
 	Bucket  string `help:"bucket to scan, optional, overrides bucket in config file"`
 
 	S3Endpoint string `name:"s3-endpoint" help:"s3 endpoint (s3.dualstack.us-east-2.amazonaws.com), optional, overrides endpoint in config file"`
@@ -36,6 +37,7 @@ var cli struct {
 		Block             listBlockCmd             `cmd:"" help:"List information about a block"`
 		Blocks            listBlocksCmd            `cmd:"" help:"List information about all blocks in a bucket"`
 		CompactionSummary listCompactionSummaryCmd `cmd:"" help:"List summary of data by compaction level"`
+		CompactionPlan    listCompactionPlanCmd    `cmd:"" help:"List the compactions planned by a compactor"`
 	} `cmd:""`
 
 	Query queryCmd `cmd:"" help:"query tempo api"`
@@ -80,6 +82,7 @@ func loadBackend(b *backendOptions, g *globalOptions) (backend.Reader, backend.C
 		cfg.StorageConfig.Trace.GCS.BucketName = b.Bucket
 		cfg.StorageConfig.Trace.S3.Bucket = b.Bucket
 		cfg.StorageConfig.Trace.Azure.ContainerName = b.Bucket
+		cfg.StorageConfig.Trace.Swift.ContainerName = b.Bucket
 	}
 
 	if b.S3Endpoint != "" {
@@ -99,6 +102,8 @@ func loadBackend(b *backendOptions, g *globalOptions) (backend.Reader, backend.C
 		r, _, c, err = s3.New(cfg.StorageConfig.Trace.S3)
 	case "azure":
 		r, _, c, err = azure.New(cfg.StorageConfig.Trace.Azure)
+	case "swift":
+		r, _, c, err = swift.New(cfg.StorageConfig.Trace.Swift)
 	default:
 		err = fmt.Errorf("unknown backend %s", cfg.StorageConfig.Trace.Backend)
 	}
@@ -68,12 +68,25 @@ type blockStats struct {
 	unifiedBlockMeta
 }
 
+func withoutTombstonesAndMarkers(ids []uuid.UUID) []uuid.UUID {
+	blockIDs := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !backend.IsTombstoneID(id) && !backend.IsCompactionMarkerID(id) {
+			blockIDs = append(blockIDs, id)
+		}
+	}
+	return blockIDs
+}
+
 func loadBucket(r backend.Reader, c backend.Compactor, tenantID string, windowRange time.Duration, includeCompacted bool) ([]blockStats, error) {
 	blockIDs, err := r.Blocks(context.Background(), tenantID)
 	if err != nil {
 		return nil, err
 	}
 
+	// tombstones of deleted traces and compaction markers are listed like blocks
+	blockIDs = withoutTombstonesAndMarkers(blockIDs)
+
 	fmt.Println("total blocks: ", len(blockIDs))
 
 	// Load in parallel
@@ -28,6 +28,7 @@ import (
 	tempo_storage "github.com/grafana/tempo/modules/storage"
 	tempo_ring "github.com/grafana/tempo/pkg/ring"
 	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/tempodb"
 )
 
 // The various modules that make up tempo.
@@ -196,6 +197,10 @@ func (t *App) initQueryFrontend() (services.Service, error) {
 }
 
 func (t *App) initCompactor() (services.Service, error) {
+	if err := tempodb.ValidateArchiveConfig(&t.cfg.Compactor.Compactor, &t.cfg.StorageConfig.Trace); err != nil {
+		return nil, fmt.Errorf("invalid compactor config: %w", err)
+	}
+
 	compactor, err := compactor.New(t.cfg.Compactor, t.store, t.overrides)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create compactor %w", err)
@@ -207,10 +212,33 @@ func (t *App) initCompactor() (services.Service, error) {
 		t.server.HTTP.Handle("/compactor/ring", t.compactor.Ring)
 	}
 
+	deleteHandler := middleware.Merge(
+		t.httpAuthMiddleware,
+	).Wrap(http.HandlerFunc(t.compactor.DeleteTracesHandler))
+	t.server.HTTP.Handle("/compactor/delete", deleteHandler)
+
+	planHandler := middleware.Merge(
+		t.httpAuthMiddleware,
+	).Wrap(http.HandlerFunc(t.compactor.PlanHandler))
+	t.server.HTTP.Handle("/compactor/plan", planHandler)
+
 	return t.compactor, nil
 }
 
 func (t *App) initStore() (services.Service, error) {
+	// only queriers read from the read backend.  compactors have to see their own compaction markers right away or
+	//  they compact the same blocks again, and blocks have to be marked and cleared in the bucket they were read from
+	if t.cfg.Target != Querier && t.cfg.StorageConfig.Trace.ReadBackend != nil {
+		level.Info(log.Logger).Log("msg", "read backend is only used by queriers.  ignoring it", "target", t.cfg.Target)
+		t.cfg.StorageConfig.Trace.ReadBackend = nil
+	}
+
+	// tenants may override the read limits and the stale tolerance of the blocklist poll
+	if t.cfg.StorageConfig.Trace.ReadLimits != nil {
+		t.cfg.StorageConfig.Trace.ReadLimits.Limits = t.overrides
+	}
+	t.cfg.StorageConfig.Trace.PollOverrides = t.overrides
+
 	store, err := tempo_storage.NewStore(t.cfg.StorageConfig, log.Logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create store %w", err)
@@ -256,7 +284,7 @@ func (t *App) setupModuleManager() error {
 	deps := map[string][]string{
 		// Server:       nil,
 		// Overrides:    nil,
-		// Store:        nil,
+		Store:         {Overrides},
 		MemberlistKV:  {Server},
 		QueryFrontend: {Server},
 		Ring:          {Server, MemberlistKV},
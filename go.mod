@@ -26,6 +26,7 @@ require (
 	github.com/jsternberg/zap-logfmt v1.0.0
 	github.com/klauspost/compress v1.11.7
 	github.com/minio/minio-go/v7 v7.0.5
+	github.com/ncw/swift v1.0.52
 	github.com/olekukonko/tablewriter v0.0.2
 	github.com/opentracing/opentracing-go v1.2.0
 	github.com/pierrec/lz4/v4 v4.1.3
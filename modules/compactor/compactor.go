@@ -12,6 +12,7 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/grafana/tempo/modules/overrides"
 	"github.com/grafana/tempo/modules/storage"
+	"github.com/grafana/tempo/tempodb"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 )
@@ -37,6 +38,10 @@ type Compactor struct {
 
 // New makes a new Compactor.
 func New(cfg Config, store storage.Store, overrides *overrides.Overrides) (*Compactor, error) {
+	if err := tempodb.ValidateCompactorConfig(&cfg.Compactor); err != nil {
+		return nil, fmt.Errorf("invalid compactor config: %w", err)
+	}
+
 	c := &Compactor{
 		cfg:       &cfg,
 		store:     store,
@@ -156,6 +161,26 @@ func (c *Compactor) BlockRetentionForTenant(tenantID string) time.Duration {
 	return c.overrides.BlockRetention(tenantID)
 }
 
+// BlockVersionForTenant implements CompactorOverrides
+func (c *Compactor) BlockVersionForTenant(tenantID string) string {
+	return c.overrides.BlockVersion(tenantID)
+}
+
+// CompactionWindowForTenant implements CompactorOverrides
+func (c *Compactor) CompactionWindowForTenant(tenantID string) time.Duration {
+	return c.overrides.CompactionWindow(tenantID)
+}
+
+// MaxBlockBytesForTenant implements CompactorOverrides
+func (c *Compactor) MaxBlockBytesForTenant(tenantID string) uint64 {
+	return c.overrides.MaxBlockBytes(tenantID)
+}
+
+// MaxCompactionObjectsForTenant implements CompactorOverrides
+func (c *Compactor) MaxCompactionObjectsForTenant(tenantID string) int {
+	return c.overrides.MaxCompactionObjects(tenantID)
+}
+
 func (c *Compactor) waitRingActive(ctx context.Context) error {
 	for {
 		// Check if the ingester is ACTIVE in the ring and our ring client
@@ -20,18 +20,29 @@ type Config struct {
 // RegisterFlagsAndApplyDefaults registers the flags.
 func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet) {
 	cfg.Compactor = tempodb.CompactorConfig{
-		ChunkSizeBytes:          10 * 1024 * 1024, // 10 MiB
-		FlushSizeBytes:          30 * 1024 * 1024, // 30 MiB
-		CompactedBlockRetention: time.Hour,
-		RetentionConcurrency:    tempodb.DefaultRetentionConcurrency,
+		ChunkSizeBytes:       10 * 1024 * 1024, // 10 MiB
+		FlushSizeBytes:       30 * 1024 * 1024, // 30 MiB
+		RetentionConcurrency: tempodb.DefaultRetentionConcurrency,
 	}
 
 	flagext.DefaultValues(&cfg.ShardingRing)
 	cfg.ShardingRing.KVStore.Store = "" // by default compactor is not sharded
 
 	f.DurationVar(&cfg.Compactor.BlockRetention, util.PrefixConfig(prefix, "compaction.block-retention"), 14*24*time.Hour, "Duration to keep blocks/traces.")
+	f.DurationVar(&cfg.Compactor.CompactedBlockRetention, util.PrefixConfig(prefix, "compaction.compacted-block-retention"), time.Hour, "Duration to keep blocks after they were compacted so queries in flight can finish.")
 	f.IntVar(&cfg.Compactor.MaxCompactionObjects, util.PrefixConfig(prefix, "compaction.max-objects-per-block"), 6000000, "Maximum number of traces in a compacted block.")
 	f.Uint64Var(&cfg.Compactor.MaxBlockBytes, util.PrefixConfig(prefix, "compaction.max-block-bytes"), 100*1024*1024*1024 /* 100GB */, "Maximum size of a compacted block.")
 	f.DurationVar(&cfg.Compactor.MaxCompactionRange, util.PrefixConfig(prefix, "compaction.compaction-window"), 4*time.Hour, "Maximum time window across which to compact blocks.")
+	f.BoolVar(&cfg.Compactor.VerifyChecksums, util.PrefixConfig(prefix, "compaction.verify-checksums"), false, "Verify the checksums of all objects of a block before compacting it.")
+	f.BoolVar(&cfg.Compactor.MigrateBlocks, util.PrefixConfig(prefix, "compaction.migrate-blocks"), false, "Rewrite blocks of older versions to the current version when there is nothing left to compact.")
+	f.DurationVar(&cfg.Compactor.ArchiveAfter, util.PrefixConfig(prefix, "compaction.archive-after"), 0, "Move the data of blocks older than this to archive_storage_class.  0 disables archiving.")
+	f.StringVar(&cfg.Compactor.ArchiveStorageClass, util.PrefixConfig(prefix, "compaction.archive-storage-class"), "", "Storage class blocks are archived to, e.g. STANDARD_IA or GLACIER_IR for S3, NEARLINE for GCS and Cool for Azure.")
+	f.IntVar(&cfg.Compactor.SplitShards, util.PrefixConfig(prefix, "compaction.split-shards"), 0, "Split blocks into this many shards by trace id before merging them so the shards can be compacted by different compactors.  0 disables splitting.")
+	f.DurationVar(&cfg.Compactor.SummarizeAfter, util.PrefixConfig(prefix, "compaction.summarize-after"), 0, "Rewrite blocks older than this to only keep the root spans of every trace.  0 disables summarizing.")
+	f.IntVar(&cfg.Compactor.Concurrency, util.PrefixConfig(prefix, "compaction.concurrency"), 1, "Number of compaction jobs run at the same time.")
+	f.Float64Var(&cfg.Compactor.MaxBytesPerSecond, util.PrefixConfig(prefix, "compaction.max-bytes-per-second"), 0, "Maximum rate at which the compactor reads and writes block data.  0 disables the limit.")
+	f.StringVar(&cfg.Compactor.MixedVersions, util.PrefixConfig(prefix, "compaction.mixed-versions"), tempodb.MixedVersionsConvert, "How blocks of different versions are compacted.  convert writes them into one block of the tenant's version, separate only compacts blocks of the same version together.")
+	f.IntVar(&cfg.Compactor.TargetBlocksPerWindow, util.PrefixConfig(prefix, "compaction.target-blocks-per-window"), 0, "Size compacted blocks by the ingest volume of a tenant so a compaction window ends up in about this many blocks.  0 always uses max-block-bytes.")
+	f.Uint64Var(&cfg.Compactor.MinBlockBytes, util.PrefixConfig(prefix, "compaction.min-block-bytes"), 100*1024*1024 /* 100MB */, "Lower bound of the size of compacted blocks sized by target-blocks-per-window.")
 	cfg.OverrideRingKey = ring.CompactorRingKey
 }
@@ -0,0 +1,76 @@
+package compactor
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/cortexproject/cortex/pkg/util/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/weaveworks/common/user"
+
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+// DeleteTracesHandler is a http.HandlerFunc that marks the traces passed in the traceID query parameters for
+//  deletion.  Deleted traces are hidden from queries after the next blocklist poll and compactors rewrite the blocks that
+//  contain them.
+func (c *Compactor) DeleteTracesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	byteIDs, err := util.ParseTraceIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ids := make([]common.ID, 0, len(byteIDs))
+	for _, id := range byteIDs {
+		ids = append(ids, id)
+	}
+
+	err = c.store.DeleteTraces(r.Context(), tenantID, ids)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	level.Info(log.Logger).Log("msg", "marked traces for deletion", "tenantID", tenantID, "traces", len(ids))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PlanHandler is a http.HandlerFunc that returns the compaction jobs currently planned for the tenant of the
+//  request without running them
+func (c *Compactor) PlanHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tenantID, err := user.ExtractOrgID(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	plans := c.store.PlanCompaction(tenantID)
+	if plans == nil {
+		plans = []*tempodb.CompactionPlan{}
+	}
+
+	w.Header().Set("Content-Type", util.JSONTypeHeaderValue)
+	err = json.NewEncoder(w).Encode(plans)
+	if err != nil {
+		level.Error(log.Logger).Log("msg", "failed to write compaction plan", "err", err)
+	}
+}
@@ -0,0 +1,96 @@
+package distributor
+
+import (
+	"path"
+	"unicode/utf8"
+
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+// attributeLimiter drops attributes whose names match any of the configured glob patterns and truncates
+//  string values longer than maxValueBytes.  It is applied to resource, span, event and link attributes
+//  before the batch is sent to the ingesters.
+type attributeLimiter struct {
+	dropPatterns  []string
+	maxValueBytes int
+
+	dropped   int
+	truncated int
+}
+
+func (l *attributeLimiter) enabled() bool {
+	return len(l.dropPatterns) > 0 || l.maxValueBytes > 0
+}
+
+func (l *attributeLimiter) limitBatch(batch *v1.ResourceSpans) {
+	if batch.Resource != nil {
+		var dropped int
+		batch.Resource.Attributes, dropped = l.limitAttributes(batch.Resource.Attributes)
+		batch.Resource.DroppedAttributesCount += uint32(dropped)
+	}
+
+	for _, ils := range batch.InstrumentationLibrarySpans {
+		for _, span := range ils.Spans {
+			var dropped int
+			span.Attributes, dropped = l.limitAttributes(span.Attributes)
+			span.DroppedAttributesCount += uint32(dropped)
+
+			for _, event := range span.Events {
+				event.Attributes, dropped = l.limitAttributes(event.Attributes)
+				event.DroppedAttributesCount += uint32(dropped)
+			}
+
+			for _, link := range span.Links {
+				link.Attributes, dropped = l.limitAttributes(link.Attributes)
+				link.DroppedAttributesCount += uint32(dropped)
+			}
+		}
+	}
+}
+
+// limitAttributes filters the passed attributes in place and returns them with the number dropped
+func (l *attributeLimiter) limitAttributes(attrs []*v1_common.KeyValue) ([]*v1_common.KeyValue, int) {
+	kept := attrs[:0]
+	for _, kv := range attrs {
+		if l.shouldDrop(kv.Key) {
+			l.dropped++
+			continue
+		}
+
+		if l.maxValueBytes > 0 && kv.Value != nil {
+			if s, ok := kv.Value.Value.(*v1_common.AnyValue_StringValue); ok && len(s.StringValue) > l.maxValueBytes {
+				s.StringValue = truncateUTF8(s.StringValue, l.maxValueBytes)
+				l.truncated++
+			}
+		}
+
+		kept = append(kept, kv)
+	}
+
+	dropped := len(attrs) - len(kept)
+	for i := len(kept); i < len(attrs); i++ {
+		attrs[i] = nil
+	}
+
+	return kept, dropped
+}
+
+func (l *attributeLimiter) shouldDrop(key string) bool {
+	for _, pattern := range l.dropPatterns {
+		// patterns are validated when the overrides are loaded
+		if match, _ := path.Match(pattern, key); match {
+			return true
+		}
+	}
+
+	return false
+}
+
+// truncateUTF8 truncates s to at most n bytes without splitting a multibyte character
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,121 @@
+package distributor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	v1_common "github.com/grafana/tempo/pkg/tempopb/common/v1"
+	v1_resource "github.com/grafana/tempo/pkg/tempopb/resource/v1"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+)
+
+func TestAttributeLimiter(t *testing.T) {
+	tests := []struct {
+		name              string
+		dropPatterns      []string
+		maxValueBytes     int
+		attrs             []*v1_common.KeyValue
+		expectedAttrs     []*v1_common.KeyValue
+		expectedDropped   int
+		expectedTruncated int
+	}{
+		{
+			name:          "disabled",
+			attrs:         []*v1_common.KeyValue{stringKV("db.statement", "select * from foo")},
+			expectedAttrs: []*v1_common.KeyValue{stringKV("db.statement", "select * from foo")},
+		},
+		{
+			name:            "drop exact",
+			dropPatterns:    []string{"db.statement"},
+			attrs:           []*v1_common.KeyValue{stringKV("db.statement", "select * from foo"), stringKV("db.system", "mysql")},
+			expectedAttrs:   []*v1_common.KeyValue{stringKV("db.system", "mysql")},
+			expectedDropped: 1,
+		},
+		{
+			name:            "drop glob",
+			dropPatterns:    []string{"db.*"},
+			attrs:           []*v1_common.KeyValue{stringKV("db.statement", "select * from foo"), stringKV("db.system", "mysql"), stringKV("http.url", "/foo")},
+			expectedAttrs:   []*v1_common.KeyValue{stringKV("http.url", "/foo")},
+			expectedDropped: 2,
+		},
+		{
+			name:              "truncate",
+			maxValueBytes:     6,
+			attrs:             []*v1_common.KeyValue{stringKV("db.statement", "select * from foo"), stringKV("db.system", "mysql"), intKV("http.status_code", 200)},
+			expectedAttrs:     []*v1_common.KeyValue{stringKV("db.statement", "select"), stringKV("db.system", "mysql"), intKV("http.status_code", 200)},
+			expectedTruncated: 1,
+		},
+		{
+			name:              "truncate multibyte",
+			maxValueBytes:     2,
+			attrs:             []*v1_common.KeyValue{stringKV("name", "año")},
+			expectedAttrs:     []*v1_common.KeyValue{stringKV("name", "a")},
+			expectedTruncated: 1,
+		},
+		{
+			name:              "drop and truncate",
+			dropPatterns:      []string{"http.*"},
+			maxValueBytes:     2,
+			attrs:             []*v1_common.KeyValue{stringKV("http.url", "/foo"), stringKV("db.system", "mysql")},
+			expectedAttrs:     []*v1_common.KeyValue{stringKV("db.system", "my")},
+			expectedDropped:   1,
+			expectedTruncated: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &attributeLimiter{
+				dropPatterns:  tt.dropPatterns,
+				maxValueBytes: tt.maxValueBytes,
+			}
+
+			event := &v1.Span_Event{Attributes: copyKVs(tt.attrs)}
+			link := &v1.Span_Link{Attributes: copyKVs(tt.attrs)}
+			span := &v1.Span{
+				Attributes: copyKVs(tt.attrs),
+				Events:     []*v1.Span_Event{event},
+				Links:      []*v1.Span_Link{link},
+			}
+			resource := &v1_resource.Resource{Attributes: copyKVs(tt.attrs)}
+			l.limitBatch(&v1.ResourceSpans{
+				Resource: resource,
+				InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{
+					{Spans: []*v1.Span{span}},
+				},
+			})
+
+			assert.Equal(t, tt.expectedAttrs, span.Attributes)
+			assert.Equal(t, tt.expectedAttrs, resource.Attributes)
+			assert.Equal(t, uint32(tt.expectedDropped), span.DroppedAttributesCount)
+			assert.Equal(t, uint32(tt.expectedDropped), resource.DroppedAttributesCount)
+			assert.Equal(t, tt.expectedAttrs, event.Attributes)
+			assert.Equal(t, tt.expectedAttrs, link.Attributes)
+			assert.Equal(t, uint32(tt.expectedDropped), event.DroppedAttributesCount)
+			assert.Equal(t, uint32(tt.expectedDropped), link.DroppedAttributesCount)
+			assert.Equal(t, 4*tt.expectedDropped, l.dropped)
+			assert.Equal(t, 4*tt.expectedTruncated, l.truncated)
+		})
+	}
+}
+
+func stringKV(k string, v string) *v1_common.KeyValue {
+	return &v1_common.KeyValue{Key: k, Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_StringValue{StringValue: v}}}
+}
+
+func intKV(k string, v int64) *v1_common.KeyValue {
+	return &v1_common.KeyValue{Key: k, Value: &v1_common.AnyValue{Value: &v1_common.AnyValue_IntValue{IntValue: v}}}
+}
+
+func copyKVs(kvs []*v1_common.KeyValue) []*v1_common.KeyValue {
+	var copied []*v1_common.KeyValue
+	for _, kv := range kvs {
+		if s, ok := kv.Value.Value.(*v1_common.AnyValue_StringValue); ok {
+			copied = append(copied, stringKV(kv.Key, s.StringValue))
+			continue
+		}
+		copied = append(copied, &v1_common.KeyValue{Key: kv.Key, Value: kv.Value})
+	}
+	return copied
+}
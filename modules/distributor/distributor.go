@@ -81,6 +81,16 @@ var (
 		Name:      "discarded_spans_total",
 		Help:      "The total number of samples that were discarded.",
 	}, []string{discardReasonLabel, "tenant"})
+	metricAttributesDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_attributes_dropped_total",
+		Help:      "The total number of attributes dropped per tenant",
+	}, []string{"tenant"})
+	metricAttributesTruncated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempo",
+		Name:      "distributor_attributes_truncated_total",
+		Help:      "The total number of attribute values truncated per tenant",
+	}, []string{"tenant"})
 )
 
 // Distributor coordinates replicates and distribution of log streams.
@@ -95,6 +105,7 @@ type Distributor struct {
 
 	// Per-user rate limiter.
 	ingestionRateLimiter *limiter.RateLimiter
+	overrides            *overrides.Overrides
 
 	// Manager for subservices
 	subservices        *services.Manager
@@ -151,6 +162,7 @@ func New(cfg Config, clientCfg ingester_client.Config, ingestersRing ring.ReadRi
 		pool:                 pool,
 		DistributorRing:      distributorRing,
 		ingestionRateLimiter: limiter.NewRateLimiter(ingestionRateStrategy, 10*time.Second),
+		overrides:            o,
 	}
 
 	cfgReceivers := cfg.Receivers
@@ -235,6 +247,8 @@ func (d *Distributor) Push(ctx context.Context, req *tempopb.PushRequest) (*temp
 			spanCount)
 	}
 
+	limitAttributes(req, userID, d.overrides)
+
 	keys, traces, err := requestsByTraceID(req, userID, spanCount)
 	if err != nil {
 		metricDiscardedSpans.WithLabelValues(reasonInternalError, userID).Add(float64(spanCount))
@@ -301,6 +315,26 @@ func (*Distributor) Check(_ context.Context, _ *grpc_health_v1.HealthCheckReques
 	return &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}, nil
 }
 
+// limitAttributes drops and truncates attributes according to the tenant's overrides
+func limitAttributes(req *tempopb.PushRequest, userID string, o *overrides.Overrides) {
+	l := &attributeLimiter{
+		dropPatterns:  o.DroppedAttributes(userID),
+		maxValueBytes: o.MaxAttributeValueBytes(userID),
+	}
+	if !l.enabled() {
+		return
+	}
+
+	l.limitBatch(req.Batch)
+
+	if l.dropped > 0 {
+		metricAttributesDropped.WithLabelValues(userID).Add(float64(l.dropped))
+	}
+	if l.truncated > 0 {
+		metricAttributesTruncated.WithLabelValues(userID).Add(float64(l.truncated))
+	}
+}
+
 func requestsByTraceID(req *tempopb.PushRequest, userID string, spanCount int) ([]uint32, []*tempopb.PushRequest, error) {
 	const expectedTracesPerBatch = 10 // roughly what we're seeing through metrics
 	expectedSpansPerTrace := spanCount / expectedTracesPerBatch
@@ -181,7 +181,7 @@ func (i *instance) CompleteBlock(blockID uuid.UUID) error {
 	}
 
 	// potentially long running operation placed outside blocksMtx
-	completeBlock, err := i.writer.CompleteBlock(completingBlock, i)
+	completeBlock, err := i.writer.CompleteBlockWithVersion(completingBlock, i, i.limiter.limits.BlockVersion(i.instanceID))
 	if err != nil {
 		metricFailedFlushes.Inc()
 		level.Error(log.Logger).Log("msg", "unable to complete block.", "tenantID", i.instanceID, "err", err)
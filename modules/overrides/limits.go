@@ -2,7 +2,11 @@ package overrides
 
 import (
 	"flag"
+	"fmt"
+	"path"
 	"time"
+
+	"github.com/grafana/tempo/tempodb/encoding"
 )
 
 const (
@@ -27,6 +31,9 @@ type Limits struct {
 	IngestionRateSpans    int    `yaml:"ingestion_rate_limit"`
 	IngestionBurstSize    int    `yaml:"ingestion_burst_size"`
 
+	DroppedAttributes      []string `yaml:"dropped_attributes"`
+	MaxAttributeValueBytes int      `yaml:"max_attribute_value_bytes"`
+
 	// Ingester enforced limits.
 	MaxLocalTracesPerUser  int `yaml:"max_traces_per_user"`
 	MaxGlobalTracesPerUser int `yaml:"max_global_traces_per_user"`
@@ -35,6 +42,21 @@ type Limits struct {
 	// Compactor enforced limits.
 	BlockRetention time.Duration `yaml:"block_retention"`
 
+	// Compaction policy of the tenant.  Zero values use the compactor config.
+	CompactionWindow     time.Duration `yaml:"compaction_window"`
+	MaxBlockBytes        uint64        `yaml:"max_block_bytes"`
+	MaxCompactionObjects int           `yaml:"max_compaction_objects"`
+
+	// How long live block metas of the tenant are reused by the blocklist poll.  Zero uses the storage config.
+	BlocklistPollStaleTolerance time.Duration `yaml:"blocklist_poll_stale_tolerance"`
+
+	// Backend read limits of the tenant.  Zero values use the read_limits of the storage config.
+	BackendReadBytesPerSecond    float64 `yaml:"backend_read_bytes_per_second"`
+	BackendReadRequestsPerSecond float64 `yaml:"backend_read_requests_per_second"`
+
+	// Block version used by the ingester and compactor when creating new blocks.  Empty uses the storage config.
+	BlockVersion string `yaml:"block_version"`
+
 	// Config for overrides, convenient if it goes here.
 	PerTenantOverrideConfig string        `yaml:"per_tenant_override_config"`
 	PerTenantOverridePeriod time.Duration `yaml:"per_tenant_override_period"`
@@ -46,6 +68,7 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&l.IngestionRateStrategy, "distributor.rate-limit-strategy", "local", "Whether the various ingestion rate limits should be applied individually to each distributor instance (local), or evenly shared across the cluster (global).")
 	f.IntVar(&l.IngestionRateSpans, "distributor.ingestion-rate-limit", 100000, "Per-user ingestion rate limit in spans per second.")
 	f.IntVar(&l.IngestionBurstSize, "distributor.ingestion-burst-size", 150000, "Per-user ingestion burst size in spans. Should be set to at least the number of spans expected in a single push request.")
+	f.IntVar(&l.MaxAttributeValueBytes, "distributor.max-attribute-value-bytes", 0, "Per-user maximum length in bytes of string attribute values.  Longer values are truncated.  0 to disable.")
 
 	// Ingester limits
 	f.IntVar(&l.MaxLocalTracesPerUser, "ingester.max-traces-per-user", 10e3, "Maximum number of active traces per user, per ingester. 0 to disable.")
@@ -55,3 +78,22 @@ func (l *Limits) RegisterFlags(f *flag.FlagSet) {
 	f.StringVar(&l.PerTenantOverrideConfig, "limits.per-user-override-config", "", "File name of per-user overrides.")
 	f.DurationVar(&l.PerTenantOverridePeriod, "limits.per-user-override-period", 10*time.Second, "Period with this to reload the overrides.")
 }
+
+// validate returns an error if the limits can not be used.  It is called for every tenant when overrides are loaded.
+func (l *Limits) validate() error {
+	if l.CompactionWindow != 0 && l.CompactionWindow < time.Second {
+		return fmt.Errorf("compaction_window must be at least 1s, got %s", l.CompactionWindow)
+	}
+
+	if err := encoding.ValidateVersion(l.BlockVersion); err != nil {
+		return fmt.Errorf("block_version: %w", err)
+	}
+
+	for _, pattern := range l.DroppedAttributes {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("dropped_attributes: invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return nil
+}
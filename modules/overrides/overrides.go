@@ -33,6 +33,12 @@ func loadPerTenantOverrides(r io.Reader) (interface{}, error) {
 		return nil, err
 	}
 
+	for tenantID, limits := range overrides.TenantLimits {
+		if err := limits.validate(); err != nil {
+			return nil, fmt.Errorf("invalid overrides for tenant %s: %w", tenantID, err)
+		}
+	}
+
 	return overrides, nil
 }
 
@@ -54,6 +60,10 @@ type Overrides struct {
 // are defaulted to those values.  As such, the last call to NewOverrides will
 // become the new global defaults.
 func NewOverrides(defaults Limits) (*Overrides, error) {
+	if err := defaults.validate(); err != nil {
+		return nil, fmt.Errorf("invalid default overrides: %w", err)
+	}
+
 	var tenantLimits TenantLimits
 	subservices := []services.Service(nil)
 
@@ -157,10 +167,55 @@ func (o *Overrides) IngestionBurstSize(userID string) int {
 	return o.getOverridesForUser(userID).IngestionBurstSize
 }
 
+// DroppedAttributes returns the glob patterns of attribute names dropped for this tenant
+func (o *Overrides) DroppedAttributes(userID string) []string {
+	return o.getOverridesForUser(userID).DroppedAttributes
+}
+
+// MaxAttributeValueBytes is the maximum length of string attribute values for this tenant
+func (o *Overrides) MaxAttributeValueBytes(userID string) int {
+	return o.getOverridesForUser(userID).MaxAttributeValueBytes
+}
+
 func (o *Overrides) BlockRetention(userID string) time.Duration {
 	return o.getOverridesForUser(userID).BlockRetention
 }
 
+// CompactionWindow is the time window blocks of this tenant are compacted in.  0 means the compactor config.
+func (o *Overrides) CompactionWindow(userID string) time.Duration {
+	return o.getOverridesForUser(userID).CompactionWindow
+}
+
+// MaxBlockBytes is the maximum size of compacted blocks of this tenant.  0 means the compactor config.
+func (o *Overrides) MaxBlockBytes(userID string) uint64 {
+	return o.getOverridesForUser(userID).MaxBlockBytes
+}
+
+// MaxCompactionObjects is the maximum number of traces in compacted blocks of this tenant.  0 or less means the compactor config.
+func (o *Overrides) MaxCompactionObjects(userID string) int {
+	return o.getOverridesForUser(userID).MaxCompactionObjects
+}
+
+// BlocklistPollStaleTolerance is how long live block metas of this tenant are reused.  0 means the storage config.
+func (o *Overrides) BlocklistPollStaleTolerance(userID string) time.Duration {
+	return o.getOverridesForUser(userID).BlocklistPollStaleTolerance
+}
+
+// BackendReadBytesPerSecond is the rate at which this tenant may read from the backend.  0 means the storage config.
+func (o *Overrides) BackendReadBytesPerSecond(userID string) float64 {
+	return o.getOverridesForUser(userID).BackendReadBytesPerSecond
+}
+
+// BackendReadRequestsPerSecond is the rate of backend read requests of this tenant.  0 means the storage config.
+func (o *Overrides) BackendReadRequestsPerSecond(userID string) float64 {
+	return o.getOverridesForUser(userID).BackendReadRequestsPerSecond
+}
+
+// BlockVersion is the version of newly created blocks for this tenant.  Empty means the configured default.
+func (o *Overrides) BlockVersion(userID string) string {
+	return o.getOverridesForUser(userID).BlockVersion
+}
+
 func (o *Overrides) getOverridesForUser(userID string) *Limits {
 	if o.tenantLimits != nil {
 		l := o.tenantLimits(userID)
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -147,3 +148,95 @@ func TestOverrides(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadPerTenantOverridesValidates(t *testing.T) {
+	tests := []struct {
+		name      string
+		overrides string
+		expectErr bool
+	}{
+		{
+			name: "valid compaction window",
+			overrides: `
+overrides:
+  user1:
+    compaction_window: 1s
+`,
+		},
+		{
+			name: "compaction window below a second",
+			overrides: `
+overrides:
+  user1:
+    compaction_window: 500ms
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid block version",
+			overrides: `
+overrides:
+  user1:
+    block_version: v1
+`,
+		},
+		{
+			name: "unsupported block version",
+			overrides: `
+overrides:
+  user1:
+    block_version: v9
+`,
+			expectErr: true,
+		},
+		{
+			name: "valid dropped attributes",
+			overrides: `
+overrides:
+  user1:
+    dropped_attributes: ["db.*", "http.url"]
+`,
+		},
+		{
+			name: "malformed dropped attributes pattern",
+			overrides: `
+overrides:
+  user1:
+    dropped_attributes: ["db.[*"]
+`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadPerTenantOverrides(strings.NewReader(tt.overrides))
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewOverridesValidatesDefaults(t *testing.T) {
+	_, err := NewOverrides(Limits{DroppedAttributes: []string{"db.["}})
+	assert.Error(t, err)
+}
+
+func TestBlockVersion(t *testing.T) {
+	overrides, err := NewOverrides(Limits{})
+	require.NoError(t, err)
+
+	overrides.tenantLimits = func(userID string) *Limits {
+		switch userID {
+		case "user1":
+			return &Limits{BlockVersion: "v1"}
+		}
+		return nil
+	}
+
+	assert.Equal(t, "v1", overrides.BlockVersion("user1"))
+	assert.Equal(t, "", overrides.BlockVersion("user2"))
+}
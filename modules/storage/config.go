@@ -10,7 +10,10 @@ import (
 	"github.com/grafana/tempo/tempodb/backend/azure"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/backend/retry"
 	"github.com/grafana/tempo/tempodb/backend/s3"
+	"github.com/grafana/tempo/tempodb/backend/swift"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/pool"
 	"github.com/grafana/tempo/tempodb/wal"
@@ -28,8 +31,9 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 
 	cfg.Trace.BlocklistPollConcurrency = tempodb.DefaultBlocklistPollConcurrency
 
-	f.StringVar(&cfg.Trace.Backend, util.PrefixConfig(prefix, "trace.backend"), "", "Trace backend (s3, azure, gcs, local)")
+	f.StringVar(&cfg.Trace.Backend, util.PrefixConfig(prefix, "trace.backend"), "", "Trace backend (s3, azure, gcs, swift, local)")
 	f.DurationVar(&cfg.Trace.BlocklistPoll, util.PrefixConfig(prefix, "trace.maintenance-cycle"), DefaultBlocklistPoll, "Period at which to run the maintenance cycle.")
+	f.DurationVar(&cfg.Trace.BlocklistPollStaleTolerance, util.PrefixConfig(prefix, "trace.blocklist-poll-stale-tolerance"), 0, "How long live block metas are reused before they are polled again.  Blocks listed in compaction markers leave the blocklist right away but other changes to live metas, like archiving, are seen only once stale.  0 polls every block meta each cycle.")
 
 	cfg.Trace.WAL = &wal.Config{}
 	f.StringVar(&cfg.Trace.WAL.Filepath, util.PrefixConfig(prefix, "trace.wal.path"), "/var/tempo/wal", "Path at which store WAL blocks.")
@@ -38,6 +42,7 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	f.Float64Var(&cfg.Trace.Block.BloomFP, util.PrefixConfig(prefix, "trace.block.bloom-filter-false-positive"), .05, "Bloom False Positive.")
 	f.IntVar(&cfg.Trace.Block.IndexDownsampleBytes, util.PrefixConfig(prefix, "trace.block.index-downsample-bytes"), 1024*1024, "Number of bytes (before compression) per index record.")
 	f.IntVar(&cfg.Trace.Block.IndexPageSizeBytes, util.PrefixConfig(prefix, "trace.block.index-page-size-bytes"), 250*1024, "Number of bytes per index page.")
+	f.StringVar(&cfg.Trace.Block.Version, util.PrefixConfig(prefix, "trace.block.version"), "", "Block version of newly created blocks.  Empty uses the latest version.")
 	cfg.Trace.Block.Encoding = backend.EncZstd
 
 	cfg.Trace.Azure = &azure.Config{}
@@ -45,6 +50,10 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	f.StringVar(&cfg.Trace.Azure.StorageAccountKey.Value, util.PrefixConfig(prefix, "trace.azure.storage-account-key"), "", "Azure storage access key.")
 	f.StringVar(&cfg.Trace.Azure.ContainerName, util.PrefixConfig(prefix, "trace.azure.container-name"), "", "Azure container name to store blocks in.")
 	f.StringVar(&cfg.Trace.Azure.Endpoint, util.PrefixConfig(prefix, "trace.azure.endpoint"), "blob.core.windows.net", "Azure endpoint to push blocks to.")
+	f.BoolVar(&cfg.Trace.Azure.UseManagedIdentity, util.PrefixConfig(prefix, "trace.azure.use-managed-identity"), false, "Authenticate with the managed identity of the instance instead of the storage account key.")
+	f.BoolVar(&cfg.Trace.Azure.UseFederatedToken, util.PrefixConfig(prefix, "trace.azure.use-federated-token"), false, "Authenticate with a federated token (AKS workload identity) instead of the storage account key.")
+	f.StringVar(&cfg.Trace.Azure.UserAssignedID, util.PrefixConfig(prefix, "trace.azure.user-assigned-id"), "", "Client id of the user assigned identity to authenticate as.")
+	f.BoolVar(&cfg.Trace.Azure.ImmutableStorage, util.PrefixConfig(prefix, "trace.azure.immutable-storage"), false, "Never replace block metas so the container can have an immutability policy.")
 	f.IntVar(&cfg.Trace.Azure.MaxBuffers, util.PrefixConfig(prefix, "trace.azure.max-buffers"), 4, "Number of simultaneous uploads.")
 	cfg.Trace.Azure.BufferSize = 3 * 1024 * 1024
 
@@ -53,13 +62,53 @@ func (cfg *Config) RegisterFlagsAndApplyDefaults(prefix string, f *flag.FlagSet)
 	f.StringVar(&cfg.Trace.S3.Endpoint, util.PrefixConfig(prefix, "trace.s3.endpoint"), "", "s3 endpoint to push blocks to.")
 	f.StringVar(&cfg.Trace.S3.AccessKey.Value, util.PrefixConfig(prefix, "trace.s3.access_key"), "", "s3 access key.")
 	f.StringVar(&cfg.Trace.S3.SecretKey.Value, util.PrefixConfig(prefix, "trace.s3.secret_key"), "", "s3 secret key.")
+	f.StringVar(&cfg.Trace.S3.SSEKMSKeyID, util.PrefixConfig(prefix, "trace.s3.sse_kms_key_id"), "", "s3 KMS key id used to encrypt objects.  Empty uses the bucket default encryption.")
+	f.BoolVar(&cfg.Trace.S3.SSEBucketKeyEnabled, util.PrefixConfig(prefix, "trace.s3.sse_bucket_key_enabled"), false, "Use an s3 bucket key to reduce KMS requests when encrypting with sse_kms_key_id.")
+	f.BoolVar(&cfg.Trace.S3.PrefixSharding, util.PrefixConfig(prefix, "trace.s3.prefix_sharding"), false, "Write new blocks under hashed key prefixes to spread requests across s3 partitions.")
+	f.BoolVar(&cfg.Trace.S3.RequesterPays, util.PrefixConfig(prefix, "trace.s3.requester_pays"), false, "Pay for requests to s3 buckets with requester pays enabled.")
+	f.BoolVar(&cfg.Trace.S3.ObjectLock, util.PrefixConfig(prefix, "trace.s3.object_lock"), false, "Send the content md5 of all uploads as required by buckets with object lock enabled.")
 
 	cfg.Trace.GCS = &gcs.Config{}
 	f.StringVar(&cfg.Trace.GCS.BucketName, util.PrefixConfig(prefix, "trace.gcs.bucket"), "", "gcs bucket to store traces in.")
+	f.StringVar(&cfg.Trace.GCS.KMSKeyName, util.PrefixConfig(prefix, "trace.gcs.kms-key-name"), "", "Cloud KMS key used to encrypt objects.  Empty uses the bucket default encryption.")
+	f.StringVar(&cfg.Trace.GCS.UserProject, util.PrefixConfig(prefix, "trace.gcs.user-project"), "", "Project billed for requests to requester pays buckets.")
 	cfg.Trace.GCS.ChunkBufferSize = 10 * 1024 * 1024
 
+	cfg.Trace.Swift = &swift.Config{}
+	f.IntVar(&cfg.Trace.Swift.AuthVersion, util.PrefixConfig(prefix, "trace.swift.auth-version"), 0, "Swift authentication API version.  0 to autodetect.")
+	f.StringVar(&cfg.Trace.Swift.AuthURL, util.PrefixConfig(prefix, "trace.swift.auth-url"), "", "Swift authentication url.")
+	f.StringVar(&cfg.Trace.Swift.Username, util.PrefixConfig(prefix, "trace.swift.username"), "", "Swift username.")
+	f.StringVar(&cfg.Trace.Swift.UserDomainName, util.PrefixConfig(prefix, "trace.swift.user-domain-name"), "", "Swift user's domain name.")
+	f.StringVar(&cfg.Trace.Swift.UserDomainID, util.PrefixConfig(prefix, "trace.swift.user-domain-id"), "", "Swift user's domain id.")
+	f.StringVar(&cfg.Trace.Swift.UserID, util.PrefixConfig(prefix, "trace.swift.user-id"), "", "Swift user id.")
+	f.StringVar(&cfg.Trace.Swift.Password.Value, util.PrefixConfig(prefix, "trace.swift.password"), "", "Swift password.")
+	f.StringVar(&cfg.Trace.Swift.DomainID, util.PrefixConfig(prefix, "trace.swift.domain-id"), "", "Swift user's domain id.  Used if user-domain-id is not set.")
+	f.StringVar(&cfg.Trace.Swift.DomainName, util.PrefixConfig(prefix, "trace.swift.domain-name"), "", "Swift user's domain name.  Used if user-domain-name is not set.")
+	f.StringVar(&cfg.Trace.Swift.ProjectID, util.PrefixConfig(prefix, "trace.swift.project-id"), "", "Swift project id (v2, v3 auth only).")
+	f.StringVar(&cfg.Trace.Swift.ProjectName, util.PrefixConfig(prefix, "trace.swift.project-name"), "", "Swift project name (v2, v3 auth only).")
+	f.StringVar(&cfg.Trace.Swift.ProjectDomainID, util.PrefixConfig(prefix, "trace.swift.project-domain-id"), "", "Id of the project's domain (v3 auth only).  Only needed if it differs from the user domain.")
+	f.StringVar(&cfg.Trace.Swift.ProjectDomainName, util.PrefixConfig(prefix, "trace.swift.project-domain-name"), "", "Name of the project's domain (v3 auth only).  Only needed if it differs from the user domain.")
+	f.StringVar(&cfg.Trace.Swift.RegionName, util.PrefixConfig(prefix, "trace.swift.region-name"), "", "Swift region to use (v2, v3 auth only).")
+	f.StringVar(&cfg.Trace.Swift.ContainerName, util.PrefixConfig(prefix, "trace.swift.container-name"), "", "Swift container to store blocks in.")
+	f.StringVar(&cfg.Trace.Swift.SegmentContainerName, util.PrefixConfig(prefix, "trace.swift.segment-container-name"), "", "Swift container to store segments of large objects in.  Defaults to the container name with a _segments suffix.")
+	f.Int64Var(&cfg.Trace.Swift.SegmentSizeBytes, util.PrefixConfig(prefix, "trace.swift.segment-size-bytes"), 100*1024*1024, "Objects larger than this are uploaded as segmented large objects.")
+	f.IntVar(&cfg.Trace.Swift.MaxRetries, util.PrefixConfig(prefix, "trace.swift.max-retries"), 3, "Max retries on request errors.")
+	f.DurationVar(&cfg.Trace.Swift.ConnectTimeout, util.PrefixConfig(prefix, "trace.swift.connect-timeout"), 10*time.Second, "Time after which a connection attempt is aborted.")
+	f.DurationVar(&cfg.Trace.Swift.RequestTimeout, util.PrefixConfig(prefix, "trace.swift.request-timeout"), 60*time.Second, "Time after which an idle request is aborted.")
+
 	cfg.Trace.Local = &local.Config{}
 	f.StringVar(&cfg.Trace.Local.Path, util.PrefixConfig(prefix, "trace.local.path"), "", "path to store traces at.")
+	f.BoolVar(&cfg.Trace.Local.SharedFilesystem, util.PrefixConfig(prefix, "trace.local.shared-filesystem"), false, "Lock blocks while changing them so multiple components can share the path.")
+
+	cfg.Trace.Retry = &retry.Config{}
+	f.IntVar(&cfg.Trace.Retry.MaxRetries, util.PrefixConfig(prefix, "trace.retry.max-retries"), 0, "Number of times to retry backend operations that failed with a transient error.  0 disables retries.")
+	f.DurationVar(&cfg.Trace.Retry.MinBackoff, util.PrefixConfig(prefix, "trace.retry.min-backoff"), 100*time.Millisecond, "Minimum delay before retrying a backend operation.")
+	f.DurationVar(&cfg.Trace.Retry.MaxBackoff, util.PrefixConfig(prefix, "trace.retry.max-backoff"), 5*time.Second, "Maximum delay before retrying a backend operation.")
+	f.DurationVar(&cfg.Trace.Retry.MaxRetryDuration, util.PrefixConfig(prefix, "trace.retry.max-retry-duration"), 30*time.Second, "Total time a backend operation may spend retrying.  0 for no limit.")
+
+	cfg.Trace.ReadLimits = &throttle.Config{}
+	f.Float64Var(&cfg.Trace.ReadLimits.BytesPerSecond, util.PrefixConfig(prefix, "trace.read-limits.bytes-per-second"), 0, "Bytes per second a single tenant may read from the backend.  0 for no limit.")
+	f.Float64Var(&cfg.Trace.ReadLimits.RequestsPerSecond, util.PrefixConfig(prefix, "trace.read-limits.requests-per-second"), 0, "Backend read requests per second of a single tenant.  0 for no limit.")
 
 	cfg.Trace.Pool = &pool.Config{}
 	f.IntVar(&cfg.Trace.Pool.MaxWorkers, util.PrefixConfig(prefix, "trace.pool.max-workers"), 50, "Workers in the worker pool.")
@@ -31,6 +31,25 @@ func ParseTraceID(r *http.Request) ([]byte, error) {
 	return byteID, nil
 }
 
+// ParseTraceIDs returns the trace ids passed in all traceID query parameters of the request
+func ParseTraceIDs(r *http.Request) ([][]byte, error) {
+	traceIDs := r.URL.Query()[TraceIDVar]
+	if len(traceIDs) == 0 {
+		return nil, fmt.Errorf("please provide a traceID")
+	}
+
+	byteIDs := make([][]byte, 0, len(traceIDs))
+	for _, traceID := range traceIDs {
+		byteID, err := hexStringToTraceID(traceID)
+		if err != nil {
+			return nil, err
+		}
+		byteIDs = append(byteIDs, byteID)
+	}
+
+	return byteIDs, nil
+}
+
 func hexStringToTraceID(id string) ([]byte, error) {
 	// the encoding/hex package does not like odd length strings.
 	// just append a bit here
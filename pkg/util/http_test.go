@@ -1,9 +1,11 @@
 package util
 
 import (
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestHexStringToTraceID(t *testing.T) {
@@ -51,3 +53,21 @@ func TestHexStringToTraceID(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTraceIDs(t *testing.T) {
+	r := httptest.NewRequest("POST", "/compactor/delete?traceID=12&traceID=1234567890abcdef", nil)
+	ids, err := ParseTraceIDs(r)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{
+		{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x12},
+		{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x12, 0x34, 0x56, 0x78, 0x90, 0xab, 0xcd, 0xef},
+	}, ids)
+
+	r = httptest.NewRequest("POST", "/compactor/delete", nil)
+	_, err = ParseTraceIDs(r)
+	assert.Error(t, err)
+
+	r = httptest.NewRequest("POST", "/compactor/delete?traceID=xyz", nil)
+	_, err = ParseTraceIDs(r)
+	assert.Error(t, err)
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"bytes"
+	"encoding/binary"
 	"hash"
 	"hash/fnv"
 	"sort"
@@ -50,8 +51,9 @@ func CombineTraces(objA []byte, objB []byte) (_ []byte, wasCombined bool, _ erro
 }
 
 // CombineTraceProtos combines two trace protos into one.  Note that it is destructive.
-//  All spans are combined into traceA.  spanCountA, B, and Total are returned for
-//  logging purposes.
+//  All spans are combined into traceA.  Spans are identified by span id and start time and every span is kept
+//  once, including spans that are duplicated within traceA or traceB, e.g. by replicated writes.
+//  spanCountA, B, and Total are returned for logging purposes.
 func CombineTraceProtos(traceA, traceB *tempopb.Trace) (*tempopb.Trace, int, int, int) {
 	// if one or the other is nil just return 0 for the one that's nil and -1 for the other.  this will be a clear indication this
 	// code path was taken without unnecessarily counting spans
@@ -67,18 +69,46 @@ func CombineTraceProtos(traceA, traceB *tempopb.Trace) (*tempopb.Trace, int, int
 	spanCountB := 0
 	spanCountTotal := 0
 
-	h := fnv.New32()
+	h := fnv.New64a()
 
-	spansInA := make(map[uint32]struct{})
+	// collect the spans in A, dropping duplicates within A
+	spansInA := make(map[uint64]struct{})
+	uniqueBatches := traceA.Batches[:0]
 	for _, batchA := range traceA.Batches {
+		uniqueILS := batchA.InstrumentationLibrarySpans[:0]
+
 		for _, ilsA := range batchA.InstrumentationLibrarySpans {
+			spanCountA += len(ilsA.Spans)
+
+			uniqueSpans := ilsA.Spans[:0]
 			for _, spanA := range ilsA.Spans {
-				spansInA[tokenForID(h, spanA.SpanId)] = struct{}{}
+				token := tokenForSpan(h, spanA)
+				if _, ok := spansInA[token]; ok {
+					continue
+				}
+				spansInA[token] = struct{}{}
+				uniqueSpans = append(uniqueSpans, spanA)
 			}
-			spanCountA += len(ilsA.Spans)
-			spanCountTotal += len(ilsA.Spans)
+
+			if len(uniqueSpans) > 0 || len(ilsA.Spans) == 0 {
+				spanCountTotal += len(uniqueSpans)
+				ilsA.Spans = uniqueSpans
+				uniqueILS = append(uniqueILS, ilsA)
+			}
+		}
+
+		if len(uniqueILS) > 0 || len(batchA.InstrumentationLibrarySpans) == 0 {
+			batchA.InstrumentationLibrarySpans = uniqueILS
+			uniqueBatches = append(uniqueBatches, batchA)
 		}
 	}
+	traceA.Batches = uniqueBatches
+
+	// traceB is traceA.  every span was just collected
+	if traceA == traceB {
+		SortTrace(traceA)
+		return traceA, spanCountA, spanCountA, spanCountTotal
+	}
 
 	// loop through every span and copy spans in B that don't exist to A
 	for _, batchB := range traceB.Batches {
@@ -87,9 +117,10 @@ func CombineTraceProtos(traceA, traceB *tempopb.Trace) (*tempopb.Trace, int, int
 		for _, ilsB := range batchB.InstrumentationLibrarySpans {
 			notFoundSpans := ilsB.Spans[:0]
 			for _, spanB := range ilsB.Spans {
-				// if found in A, remove from the batch
-				_, ok := spansInA[tokenForID(h, spanB.SpanId)]
-				if !ok {
+				// if found in A or earlier in B, remove from the batch
+				token := tokenForSpan(h, spanB)
+				if _, ok := spansInA[token]; !ok {
+					spansInA[token] = struct{}{}
 					notFoundSpans = append(notFoundSpans, spanB)
 				}
 			}
@@ -114,6 +145,48 @@ func CombineTraceProtos(traceA, traceB *tempopb.Trace) (*tempopb.Trace, int, int
 	return traceA, spanCountA, spanCountB, spanCountTotal
 }
 
+// SummarizeTrace drops all spans whose parent is part of the trace.  Root spans and the local roots of
+//  incomplete traces are kept together with their resources.  Note that it is destructive.
+func SummarizeTrace(t *tempopb.Trace) *tempopb.Trace {
+	h := fnv.New64a()
+
+	spanIDs := make(map[uint64]struct{})
+	for _, b := range t.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			for _, s := range ils.Spans {
+				spanIDs[tokenForID(h, s.SpanId)] = struct{}{}
+			}
+		}
+	}
+
+	batches := t.Batches[:0]
+	for _, b := range t.Batches {
+		ilss := b.InstrumentationLibrarySpans[:0]
+		for _, ils := range b.InstrumentationLibrarySpans {
+			roots := ils.Spans[:0]
+			for _, s := range ils.Spans {
+				if _, ok := spanIDs[tokenForID(h, s.ParentSpanId)]; len(s.ParentSpanId) > 0 && ok {
+					continue
+				}
+				roots = append(roots, s)
+			}
+
+			if len(roots) > 0 {
+				ils.Spans = roots
+				ilss = append(ilss, ils)
+			}
+		}
+
+		if len(ilss) > 0 {
+			b.InstrumentationLibrarySpans = ilss
+			batches = append(batches, b)
+		}
+	}
+	t.Batches = batches
+
+	return t
+}
+
 func SortTrace(t *tempopb.Trace) {
 	// Sort bottom up by span start times
 	for _, b := range t.Batches {
@@ -155,8 +228,20 @@ func compareSpans(a *v1.Span, b *v1.Span) bool {
 	return a.StartTimeUnixNano < b.StartTimeUnixNano
 }
 
-func tokenForID(h hash.Hash32, b []byte) uint32 {
+// tokenForSpan identifies a span by its id and start time
+func tokenForSpan(h hash.Hash64, span *v1.Span) uint64 {
+	h.Reset()
+	_, _ = h.Write(span.SpanId)
+
+	var start [8]byte
+	binary.BigEndian.PutUint64(start[:], span.StartTimeUnixNano)
+	_, _ = h.Write(start[:])
+
+	return h.Sum64()
+}
+
+func tokenForID(h hash.Hash64, b []byte) uint64 {
 	h.Reset()
 	_, _ = h.Write(b)
-	return h.Sum32()
+	return h.Sum64()
 }
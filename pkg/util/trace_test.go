@@ -154,6 +154,65 @@ func TestCombineProtos(t *testing.T) {
 	}
 }
 
+func TestCombineProtosDedupesSpans(t *testing.T) {
+	makeSpan := func(id byte, start uint64) *v1.Span {
+		return &v1.Span{SpanId: []byte{id}, StartTimeUnixNano: start}
+	}
+	makeTrace := func(spans ...*v1.Span) *tempopb.Trace {
+		return &tempopb.Trace{
+			Batches: []*v1.ResourceSpans{
+				{
+					InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{
+						{Spans: spans},
+					},
+				},
+			},
+		}
+	}
+
+	// a replica of every span in A and B, a duplicate within each trace and a reused span id with a different start time
+	traceA := makeTrace(makeSpan(1, 10), makeSpan(2, 20), makeSpan(1, 10))
+	traceB := makeTrace(makeSpan(3, 30), makeSpan(2, 20), makeSpan(3, 30), makeSpan(1, 40))
+
+	actual, spanCountA, spanCountB, spanCountTotal := CombineTraceProtos(traceA, traceB)
+	assert.Equal(t, 3, spanCountA)
+	assert.Equal(t, 4, spanCountB)
+	assert.Equal(t, 4, spanCountTotal)
+
+	var spans []*v1.Span
+	for _, b := range actual.Batches {
+		for _, ils := range b.InstrumentationLibrarySpans {
+			spans = append(spans, ils.Spans...)
+		}
+	}
+	assert.Equal(t, []*v1.Span{makeSpan(1, 10), makeSpan(2, 20), makeSpan(3, 30), makeSpan(1, 40)}, spans)
+}
+
+func TestSummarizeTrace(t *testing.T) {
+	root := &v1.Span{SpanId: []byte{0x01}}
+	child := &v1.Span{SpanId: []byte{0x02}, ParentSpanId: []byte{0x01}}
+	leaf := &v1.Span{SpanId: []byte{0x03}, ParentSpanId: []byte{0x02}}
+	// the parent of an orphan is not part of the trace.  it is the root of what is left
+	orphan := &v1.Span{SpanId: []byte{0x04}, ParentSpanId: []byte{0x05}}
+
+	trace := &tempopb.Trace{
+		Batches: []*v1.ResourceSpans{
+			{InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: []*v1.Span{root, child}}}},
+			{InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: []*v1.Span{leaf}}}},
+			{InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: []*v1.Span{orphan}}}},
+		},
+	}
+
+	expected := &tempopb.Trace{
+		Batches: []*v1.ResourceSpans{
+			{InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: []*v1.Span{root}}}},
+			{InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: []*v1.Span{orphan}}}},
+		},
+	}
+
+	assert.Equal(t, expected, SummarizeTrace(trace))
+}
+
 func BenchmarkCombineTraces(b *testing.B) {
 	t1 := test.MakeTrace(10, []byte{0x01, 0x02})
 	t2 := test.MakeTrace(10, []byte{0x01, 0x03})
@@ -0,0 +1,117 @@
+package tempodb
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+)
+
+var errBlockCompacted = errors.New("block was compacted")
+
+var (
+	metricArchivedBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "archive_blocks_total",
+		Help:      "Total number of blocks moved to the archive storage class.",
+	})
+	metricArchiveErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "archive_errors_total",
+		Help:      "Total number of errors occurring while moving blocks to the archive storage class.",
+	})
+)
+
+// archiveRetentionMargin is how long before their retention blocks are no longer archived.  Retention marks blocks
+//  compacted outside of the maintenance cycle and must not do so while the block is archived.
+const archiveRetentionMargin = time.Hour
+
+// doArchiving moves blocks that ended more than archive_after ago to the archive storage class.  Only the data
+//  object is moved.  The index and bloom filters are read by every search and stay in the default storage class.
+//  Blocks are only archived by the compactor that owns their compaction.  Archiving runs in the maintenance cycle
+//  after compaction and deletion so a block is never archived while this compactor rewrites it.
+func (rw *readerWriter) doArchiving(tenantID string, start time.Time) {
+	cutoff := time.Now().Add(-rw.compactorCfg.ArchiveAfter)
+	retentionCutoff := time.Now().Add(-rw.retentionForTenant(tenantID) + archiveRetentionMargin)
+
+	for _, b := range rw.blocklist(tenantID) {
+		if b.StorageClass == rw.compactorCfg.ArchiveStorageClass || !b.EndTime.Before(cutoff) || b.EndTime.Before(retentionCutoff) {
+			continue
+		}
+		if !rw.ownsBlock(tenantID, b) {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "archiving block", "blockID", b.BlockID, "tenantID", tenantID, "storageClass", rw.compactorCfg.ArchiveStorageClass)
+		err := rw.archive(b)
+		if err == backend.ErrMetaDoesNotExist || err == errBlockCompacted {
+			level.Warn(rw.logger).Log("msg", "block was compacted during archiving", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+		} else if err != nil {
+			level.Error(rw.logger).Log("msg", "failed to archive block", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricArchiveErrors.Inc()
+		} else {
+			metricArchivedBlocks.Inc()
+		}
+
+		if start.Add(rw.cfg.BlocklistPoll).Before(time.Now()) {
+			level.Info(rw.logger).Log("msg", "archived blocks for a maintenance cycle, bailing out", "tenantID", tenantID)
+			return
+		}
+	}
+}
+
+// archive moves the data object of the passed block to the archive storage class and records the storage class
+//  in the block meta
+func (rw *readerWriter) archive(meta *backend.BlockMeta) error {
+	ctx := backend.WithCaller(context.Background(), backend.CallerCompactor)
+	storageClass := rw.compactorCfg.ArchiveStorageClass
+
+	// confirm the block was not compacted since the last poll, e.g. by a compactor that owned it before the ring
+	//  changed.  writing the meta would bring it back
+	_, err := rw.r.BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	if err != nil {
+		return err
+	}
+
+	err = rw.c.ArchiveObject(encoding.NameObjects, meta.BlockID, meta.TenantID, storageClass)
+	if err != nil {
+		return err
+	}
+
+	// moving the object may take long.  confirm again that the block was not compacted in the meantime
+	_, err = rw.c.CompactedBlockMeta(meta.BlockID, meta.TenantID)
+	if err == nil {
+		return errBlockCompacted
+	}
+	if err != backend.ErrMetaDoesNotExist {
+		return err
+	}
+	_, err = rw.r.BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	if err != nil {
+		return err
+	}
+
+	archivedMeta := *meta
+	archivedMeta.StorageClass = storageClass
+	err = rw.w.WriteBlockMeta(ctx, &archivedMeta)
+	if err != nil {
+		return err
+	}
+
+	rw.updateBlocklist(meta.TenantID, []*backend.BlockMeta{&archivedMeta}, []*backend.BlockMeta{meta}, nil)
+	return nil
+}
+
+// storageClassLabel returns the metric label for the storage class of a block
+func storageClassLabel(meta *backend.BlockMeta) string {
+	if meta.StorageClass == "" {
+		return "default"
+	}
+	return meta.StorageClass
+}
@@ -0,0 +1,185 @@
+package tempodb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+type archivingCompactor struct {
+	backend.Compactor
+
+	archived []string
+	// compact marks the block compacted while its object is archived
+	compact bool
+}
+
+func (c *archivingCompactor) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	c.archived = append(c.archived, name+":"+storageClass)
+	if c.compact {
+		return c.Compactor.MarkBlockCompacted(blockID, tenantID)
+	}
+	return nil
+}
+
+func TestArchive(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          24 * time.Hour,
+		CompactedBlockRetention: time.Hour,
+		ArchiveAfter:            time.Nanosecond,
+		ArchiveStorageClass:     "STANDARD_IA",
+	}, &mockSharder{}, &mockOverrides{})
+
+	rw := r.(*readerWriter)
+	compactor := &archivingCompactor{Compactor: rw.c}
+	rw.c = compactor
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID)
+	require.NoError(t, err)
+	complete, err := w.CompleteBlock(head, &mockSharder{})
+	require.NoError(t, err)
+	blockID := complete.BlockMeta().BlockID
+	require.NoError(t, w.WriteBlock(context.Background(), complete))
+
+	rw.pollBlocklist()
+	require.Len(t, rw.blocklist(testTenantID), 1)
+	assert.Equal(t, "", rw.blocklist(testTenantID)[0].StorageClass)
+
+	// blocks close to their retention are left to retention
+	rw.compactorCfg.BlockRetention = time.Hour
+	rw.doArchiving(testTenantID, time.Now())
+	assert.Len(t, compactor.archived, 0)
+	rw.compactorCfg.BlockRetention = 24 * time.Hour
+
+	// the data object is archived and the storage class recorded in the meta
+	rw.doArchiving(testTenantID, time.Now())
+	assert.Equal(t, []string{"data:STANDARD_IA"}, compactor.archived)
+	require.Len(t, rw.blocklist(testTenantID), 1)
+	assert.Equal(t, "STANDARD_IA", rw.blocklist(testTenantID)[0].StorageClass)
+
+	meta, err := rw.r.BlockMeta(context.Background(), blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, "STANDARD_IA", meta.StorageClass)
+
+	// archived blocks are not archived again
+	rw.pollBlocklist()
+	rw.doArchiving(testTenantID, time.Now())
+	assert.Len(t, compactor.archived, 1)
+	checkBlocklists(t, blockID, 1, 0, rw)
+}
+
+func TestArchiveCompactedBlock(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          24 * time.Hour,
+		CompactedBlockRetention: time.Hour,
+		ArchiveAfter:            time.Nanosecond,
+		ArchiveStorageClass:     "STANDARD_IA",
+	}, &mockSharder{}, &mockOverrides{})
+
+	rw := r.(*readerWriter)
+	compactor := &archivingCompactor{Compactor: rw.c, compact: true}
+	rw.c = compactor
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID)
+	require.NoError(t, err)
+	complete, err := w.CompleteBlock(head, &mockSharder{})
+	require.NoError(t, err)
+	blockID := complete.BlockMeta().BlockID
+	require.NoError(t, w.WriteBlock(context.Background(), complete))
+
+	rw.pollBlocklist()
+	require.Len(t, rw.blocklist(testTenantID), 1)
+
+	// a block compacted while it is archived is not brought back
+	rw.doArchiving(testTenantID, time.Now())
+	assert.Len(t, compactor.archived, 1)
+
+	_, err = rw.r.BlockMeta(context.Background(), blockID, testTenantID)
+	assert.Equal(t, backend.ErrMetaDoesNotExist, err)
+
+	rw.pollBlocklist()
+	checkBlocklists(t, blockID, 0, 1, rw)
+}
+
+func TestValidateArchiveConfig(t *testing.T) {
+	assert.NoError(t, ValidateArchiveConfig(&CompactorConfig{}, &Config{Backend: "local"}))
+	assert.NoError(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour}, &Config{Backend: "s3"}))
+	assert.Error(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour}, &Config{Backend: "local"}))
+	assert.Error(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour}, &Config{Backend: "swift"}))
+	assert.Error(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour}, &Config{
+		Backend: "gcs",
+		Replica: &ReplicaConfig{BackendConfig: BackendConfig{Backend: "local"}},
+	}))
+
+	// offline tiers can not be searched
+	assert.NoError(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour, ArchiveStorageClass: "GLACIER_IR"}, &Config{Backend: "s3"}))
+	assert.Error(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour, ArchiveStorageClass: "GLACIER"}, &Config{Backend: "s3"}))
+	assert.Error(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour, ArchiveStorageClass: "DEEP_ARCHIVE"}, &Config{Backend: "s3"}))
+	assert.NoError(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour, ArchiveStorageClass: "Cool"}, &Config{Backend: "azure"}))
+	assert.Error(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour, ArchiveStorageClass: "Archive"}, &Config{Backend: "azure"}))
+	assert.Error(t, ValidateArchiveConfig(&CompactorConfig{ArchiveAfter: time.Hour, ArchiveStorageClass: "GLACIER"}, &Config{
+		Backend: "gcs",
+		Replica: &ReplicaConfig{BackendConfig: BackendConfig{Backend: "s3"}},
+	}))
+}
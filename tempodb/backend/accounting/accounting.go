@@ -0,0 +1,185 @@
+package accounting
+
+import (
+	"context"
+	"io"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const (
+	operationGet    = "GET"
+	operationPut    = "PUT"
+	operationList   = "LIST"
+	operationCopy   = "COPY"
+	operationDelete = "DELETE"
+)
+
+var (
+	metricRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_requests_total",
+		Help:      "Total number of backend requests by tenant, caller and operation.",
+	}, []string{"tenant", "caller", "operation"})
+	metricBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_bytes_total",
+		Help:      "Total number of bytes read from and written to the backend by tenant, caller and operation.",
+	}, []string{"tenant", "caller", "operation"})
+)
+
+type readerWriter struct {
+	nextReader backend.Reader
+	nextWriter backend.Writer
+	nextComp   backend.Compactor
+}
+
+// New wraps the passed backend and counts requests and bytes per tenant, caller and operation.  The caller is taken
+//  from the request context, see backend.WithCaller.  Compactor methods take no context and are attributed to the
+//  compactor, except for reading compacted metas which is done by the poller.
+func New(r backend.Reader, w backend.Writer, c backend.Compactor) (backend.Reader, backend.Writer, backend.Compactor) {
+	rw := &readerWriter{
+		nextReader: r,
+		nextWriter: w,
+		nextComp:   c,
+	}
+	return rw, rw, rw
+}
+
+// Write implements backend.Writer
+func (rw *readerWriter) Write(ctx context.Context, name string, blockID uuid.UUID, tenantID string, buffer []byte) error {
+	count(ctx, tenantID, operationPut, len(buffer))
+	return rw.nextWriter.Write(ctx, name, blockID, tenantID, buffer)
+}
+
+// WriteReader implements backend.Writer
+func (rw *readerWriter) WriteReader(ctx context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, size int64) error {
+	count(ctx, tenantID, operationPut, 0)
+	return rw.nextWriter.WriteReader(ctx, name, blockID, tenantID, newCountingReader(data, metricBytes.WithLabelValues(tenantID, backend.CallerFromContext(ctx), operationPut)), size)
+}
+
+// WriteBlockMeta implements backend.Writer
+func (rw *readerWriter) WriteBlockMeta(ctx context.Context, meta *backend.BlockMeta) error {
+	count(ctx, meta.TenantID, operationPut, 0)
+	return rw.nextWriter.WriteBlockMeta(ctx, meta)
+}
+
+// Append implements backend.Writer
+func (rw *readerWriter) Append(ctx context.Context, name string, blockID uuid.UUID, tenantID string, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	count(ctx, tenantID, operationPut, len(buffer))
+	return rw.nextWriter.Append(ctx, name, blockID, tenantID, tracker, buffer)
+}
+
+// CloseAppend implements backend.Writer
+func (rw *readerWriter) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	return rw.nextWriter.CloseAppend(ctx, tracker)
+}
+
+// Tenants implements backend.Reader
+func (rw *readerWriter) Tenants(ctx context.Context) ([]string, error) {
+	count(ctx, "", operationList, 0)
+	return rw.nextReader.Tenants(ctx)
+}
+
+// Blocks implements backend.Reader
+func (rw *readerWriter) Blocks(ctx context.Context, tenantID string) ([]uuid.UUID, error) {
+	count(ctx, tenantID, operationList, 0)
+	return rw.nextReader.Blocks(ctx, tenantID)
+}
+
+// BlockMeta implements backend.Reader
+func (rw *readerWriter) BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*backend.BlockMeta, error) {
+	count(ctx, tenantID, operationGet, 0)
+	return rw.nextReader.BlockMeta(ctx, blockID, tenantID)
+}
+
+// Read implements backend.Reader
+func (rw *readerWriter) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error) {
+	b, err := rw.nextReader.Read(ctx, name, blockID, tenantID)
+	count(ctx, tenantID, operationGet, len(b))
+	return b, err
+}
+
+// ReadRange implements backend.Reader
+func (rw *readerWriter) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte) error {
+	count(ctx, tenantID, operationGet, len(buffer))
+	return rw.nextReader.ReadRange(ctx, name, blockID, tenantID, offset, buffer)
+}
+
+// Shutdown implements backend.Reader
+func (rw *readerWriter) Shutdown() {
+	rw.nextReader.Shutdown()
+}
+
+// MarkBlockCompacted implements backend.Compactor
+func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error {
+	countCompactor(tenantID, backend.CallerCompactor, operationCopy)
+	return rw.nextComp.MarkBlockCompacted(blockID, tenantID)
+}
+
+// ClearBlock implements backend.Compactor
+func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
+	countCompactor(tenantID, backend.CallerCompactor, operationDelete)
+	return rw.nextComp.ClearBlock(blockID, tenantID)
+}
+
+// CompactedBlockMeta implements backend.Compactor
+func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	countCompactor(tenantID, backend.CallerPoller, operationGet)
+	return rw.nextComp.CompactedBlockMeta(blockID, tenantID)
+}
+
+// ArchiveObject implements backend.Compactor
+func (rw *readerWriter) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	countCompactor(tenantID, backend.CallerCompactor, operationCopy)
+	return rw.nextComp.ArchiveObject(name, blockID, tenantID, storageClass)
+}
+
+func count(ctx context.Context, tenantID string, operation string, bytes int) {
+	caller := backend.CallerFromContext(ctx)
+	metricRequests.WithLabelValues(tenantID, caller, operation).Inc()
+	if bytes > 0 {
+		metricBytes.WithLabelValues(tenantID, caller, operation).Add(float64(bytes))
+	}
+}
+
+func countCompactor(tenantID string, caller string, operation string) {
+	metricRequests.WithLabelValues(tenantID, caller, operation).Inc()
+}
+
+// newCountingReader returns a reader that counts the bytes of streamed writes as they are read by the backend.  The
+//  reader stays seekable if data is so writes can still be retried.
+func newCountingReader(data io.Reader, bytes prometheus.Counter) io.Reader {
+	r := &countingReader{
+		r:     data,
+		bytes: bytes,
+	}
+	if s, ok := data.(io.Seeker); ok {
+		return &countingReadSeeker{countingReader: r, s: s}
+	}
+	return r
+}
+
+type countingReader struct {
+	r     io.Reader
+	bytes prometheus.Counter
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.bytes.Add(float64(n))
+	return n, err
+}
+
+type countingReadSeeker struct {
+	*countingReader
+	s io.Seeker
+}
+
+func (c *countingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	return c.s.Seek(offset, whence)
+}
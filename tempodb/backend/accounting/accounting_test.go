@@ -0,0 +1,85 @@
+package accounting
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+type mockBackend struct {
+	backend.Reader
+	backend.Writer
+	backend.Compactor
+
+	seekable bool
+}
+
+func (m *mockBackend) Read(context.Context, string, uuid.UUID, string) ([]byte, error) {
+	return make([]byte, 100), nil
+}
+
+func (m *mockBackend) ReadRange(context.Context, string, uuid.UUID, string, uint64, []byte) error {
+	return nil
+}
+
+func (m *mockBackend) WriteReader(_ context.Context, _ string, _ uuid.UUID, _ string, data io.Reader, _ int64) error {
+	_, m.seekable = data.(io.Seeker)
+	_, err := ioutil.ReadAll(data)
+	return err
+}
+
+func (m *mockBackend) ClearBlock(uuid.UUID, string) error {
+	return nil
+}
+
+func value(t *testing.T, tenantID string, caller string, operation string) (float64, float64) {
+	requests, err := test.GetCounterValue(metricRequests.WithLabelValues(tenantID, caller, operation))
+	require.NoError(t, err)
+	bytes, err := test.GetCounterValue(metricBytes.WithLabelValues(tenantID, caller, operation))
+	require.NoError(t, err)
+	return requests, bytes
+}
+
+func TestAccounting(t *testing.T) {
+	next := &mockBackend{}
+	r, w, c := New(next, next, next)
+
+	ctx := backend.WithCaller(context.Background(), backend.CallerQuerier)
+	_, err := r.Read(ctx, "bloom-0", uuid.New(), "a")
+	require.NoError(t, err)
+	require.NoError(t, r.ReadRange(ctx, "data", uuid.New(), "a", 0, make([]byte, 50)))
+
+	requests, bytesRead := value(t, "a", backend.CallerQuerier, operationGet)
+	assert.Equal(t, 2.0, requests)
+	assert.Equal(t, 150.0, bytesRead)
+
+	// streamed writes are counted as they are read and stay seekable
+	ctx = backend.WithCaller(context.Background(), backend.CallerIngester)
+	require.NoError(t, w.WriteReader(ctx, "data", uuid.New(), "b", bytes.NewReader(make([]byte, 30)), 30))
+	assert.True(t, next.seekable)
+	require.NoError(t, w.WriteReader(ctx, "data", uuid.New(), "b", bytes.NewBuffer(make([]byte, 30)), 30))
+	assert.False(t, next.seekable)
+
+	requests, bytesWritten := value(t, "b", backend.CallerIngester, operationPut)
+	assert.Equal(t, 2.0, requests)
+	assert.Equal(t, 60.0, bytesWritten)
+
+	// requests without a caller and compactor requests
+	_, err = r.Read(context.Background(), "index", uuid.New(), "c")
+	require.NoError(t, err)
+	requests, _ = value(t, "c", "unknown", operationGet)
+	assert.Equal(t, 1.0, requests)
+
+	require.NoError(t, c.ClearBlock(uuid.New(), "c"))
+	requests, _ = value(t, "c", backend.CallerCompactor, operationDelete)
+	assert.Equal(t, 1.0, requests)
+}
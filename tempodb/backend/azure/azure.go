@@ -57,7 +57,7 @@ func (rw *readerWriter) Write(ctx context.Context, name string, blockID uuid.UUI
 
 // WriteReader implements backend.Writer
 func (rw *readerWriter) WriteReader(ctx context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, _ int64) error {
-	return rw.writer(ctx, bufio.NewReader(data), util.ObjectFileName(blockID, tenantID, name))
+	return rw.writer(ctx, bufio.NewReader(data), util.ObjectFileName(blockID, tenantID, name), blob.BlobAccessConditions{})
 }
 
 // WriteBlockMeta implements backend.Writer
@@ -70,6 +70,10 @@ func (rw *readerWriter) WriteBlockMeta(ctx context.Context, meta *backend.BlockM
 		return err
 	}
 
+	if rw.cfg.ImmutableStorage {
+		return rw.writeBlockMetaVersion(ctx, blockID, tenantID, bMeta)
+	}
+
 	err = rw.writeAll(ctx, util.MetaFileName(blockID, tenantID), bMeta)
 	if err != nil {
 		return err
@@ -167,6 +171,13 @@ func (rw *readerWriter) Blocks(ctx context.Context, tenantID string) ([]uuid.UUI
 // BlockMeta implements backend.Reader
 func (rw *readerWriter) BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*backend.BlockMeta, error) {
 	name := util.MetaFileName(blockID, tenantID)
+	if rw.cfg.ImmutableStorage {
+		var err error
+		name, err = rw.latestMetaName(ctx, blockID, tenantID)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	bytes, err := rw.readAll(ctx, name)
 	if err != nil {
@@ -194,7 +205,11 @@ func (rw *readerWriter) Read(ctx context.Context, name string, blockID uuid.UUID
 	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "Read")
 	defer span.Finish()
 
-	return rw.readAll(derivedCtx, util.ObjectFileName(blockID, tenantID, name))
+	bytes, err := rw.readAll(derivedCtx, util.ObjectFileName(blockID, tenantID, name))
+	if isNotFoundError(err) {
+		return nil, backend.ErrDoesNotExist
+	}
+	return bytes, err
 }
 
 // ReadRange implements backend.Reader
@@ -202,7 +217,11 @@ func (rw *readerWriter) ReadRange(ctx context.Context, name string, blockID uuid
 	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "ReadRange")
 	defer span.Finish()
 
-	return rw.readRange(derivedCtx, util.ObjectFileName(blockID, tenantID, name), int64(offset), buffer)
+	err := rw.readRange(derivedCtx, util.ObjectFileName(blockID, tenantID, name), int64(offset), buffer)
+	if isNotFoundError(err) {
+		return backend.ErrDoesNotExist
+	}
+	return err
 }
 
 // Shutdown implements backend.Reader
@@ -210,7 +229,7 @@ func (rw *readerWriter) Shutdown() {
 }
 
 func (rw *readerWriter) writeAll(ctx context.Context, name string, b []byte) error {
-	err := rw.writer(ctx, bytes.NewReader(b), name)
+	err := rw.writer(ctx, bytes.NewReader(b), name, blob.BlobAccessConditions{})
 	if err != nil {
 		return err
 	}
@@ -230,13 +249,14 @@ func (rw *readerWriter) append(ctx context.Context, src []byte, name string) (st
 
 }
 
-func (rw *readerWriter) writer(ctx context.Context, src io.Reader, name string) error {
+func (rw *readerWriter) writer(ctx context.Context, src io.Reader, name string, conditions blob.BlobAccessConditions) error {
 	blobURL := rw.containerURL.NewBlockBlobURL(name)
 
 	if _, err := blob.UploadStreamToBlockBlob(ctx, src, blobURL,
 		blob.UploadStreamToBlockBlobOptions{
-			BufferSize: rw.cfg.BufferSize,
-			MaxBuffers: rw.cfg.MaxBuffers,
+			BufferSize:       rw.cfg.BufferSize,
+			MaxBuffers:       rw.cfg.MaxBuffers,
+			AccessConditions: conditions,
 		},
 	); err != nil {
 		return errors.Wrapf(err, "cannot upload blob, name: %s", name)
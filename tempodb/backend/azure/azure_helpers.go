@@ -13,7 +13,7 @@ import (
 const maxRetries = 3
 
 func GetContainerURL(ctx context.Context, conf *Config) (blob.ContainerURL, error) {
-	c, err := blob.NewSharedKeyCredential(conf.StorageAccountName.String(), conf.StorageAccountKey.String())
+	c, err := getCredential(conf)
 	if err != nil {
 		return blob.ContainerURL{}, err
 	}
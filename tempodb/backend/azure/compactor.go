@@ -35,6 +35,10 @@ func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) e
 	compactedMetaFilename := util.CompactedMetaFileName(blockID, tenantID)
 	ctx := context.TODO()
 
+	if rw.cfg.ImmutableStorage {
+		return rw.markBlockCompactedImmutable(ctx, blockID, tenantID)
+	}
+
 	src, err := rw.readAll(ctx, metaFilename)
 	if err != nil {
 		return err
@@ -51,6 +55,7 @@ func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) e
 
 func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
 	var warning error
+	var locked bool
 	if len(tenantID) == 0 {
 		return fmt.Errorf("empty tenant id")
 	}
@@ -76,6 +81,10 @@ func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
 
 		for _, blob := range list.Segment.BlobItems {
 			err = rw.delete(ctx, blob.Name)
+			if isImmutableError(err) {
+				locked = true
+				continue
+			}
 			if err != nil {
 				warning = err
 				continue
@@ -88,6 +97,9 @@ func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
 
 	}
 
+	if warning == nil && locked {
+		return backend.ErrObjectLocked
+	}
 	return warning
 }
 
@@ -159,3 +171,27 @@ func (rw *readerWriter) delete(ctx context.Context, name string) error {
 	}
 	return nil
 }
+
+// ArchiveObject implements backend.Compactor.  The storage class is the access tier of the blob.  Blobs in the
+//  Archive tier can not be read so Cool is the only useful tier.
+func (rw *readerWriter) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	if len(tenantID) == 0 {
+		return backend.ErrEmptyTenantID
+	}
+	if blockID == uuid.Nil {
+		return backend.ErrEmptyBlockID
+	}
+
+	objectName := util.ObjectFileName(blockID, tenantID, name)
+	ctx := context.TODO()
+
+	blobURL, err := GetBlobURL(ctx, rw.cfg, objectName)
+	if err != nil {
+		return errors.Wrapf(err, "cannot get Azure blob URL, name: %s", objectName)
+	}
+
+	if _, err = blobURL.SetTier(ctx, blob.AccessTierType(storageClass), blob.LeaseAccessConditions{}); err != nil {
+		return errors.Wrapf(err, "error setting access tier of blob, name: %s", objectName)
+	}
+	return nil
+}
@@ -9,4 +9,8 @@ type Config struct {
 	Endpoint           string         `yaml:"endpoint-suffix"`
 	MaxBuffers         int            `yaml:"max-buffers"`
 	BufferSize         int            `yaml:"buffer-size"`
+	UseManagedIdentity bool           `yaml:"use-managed-identity"`
+	UseFederatedToken  bool           `yaml:"use-federated-token"`
+	UserAssignedID     string         `yaml:"user-assigned-id"`
+	ImmutableStorage   bool           `yaml:"immutable-storage"`
 }
@@ -0,0 +1,221 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	blob "github.com/Azure/azure-storage-blob-go/azblob"
+	log_util "github.com/cortexproject/cortex/pkg/util/log"
+	"github.com/go-kit/kit/log/level"
+)
+
+const (
+	// storageResource is the resource tokens are requested for.  Access to individual containers is
+	//  scoped by the role assignments of the identity.
+	storageResource = "https://storage.azure.com/"
+	storageScope    = storageResource + ".default"
+
+	imdsEndpoint         = "http://169.254.169.254/metadata/identity/oauth2/token"
+	defaultAuthorityHost = "https://login.microsoftonline.com/"
+
+	// environment variables injected by the AKS workload identity webhook
+	envAuthorityHost      = "AZURE_AUTHORITY_HOST"
+	envClientID           = "AZURE_CLIENT_ID"
+	envTenantID           = "AZURE_TENANT_ID"
+	envFederatedTokenFile = "AZURE_FEDERATED_TOKEN_FILE"
+
+	tokenRequestTimeout = 30 * time.Second
+	// tokens are refreshed this long before they expire
+	tokenRefreshMargin = 5 * time.Minute
+	// wait this long before retrying a failed refresh
+	tokenRetryInterval = 30 * time.Second
+)
+
+var (
+	// token credentials refresh themselves in the background so only one is created per config
+	tokenCredentials    = map[*Config]blob.TokenCredential{}
+	tokenCredentialsMtx sync.Mutex
+)
+
+type tokenResponse struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+type tokenFetcher func(ctx context.Context) (*tokenResponse, error)
+
+// getCredential returns the credential for the configured authentication method
+func getCredential(conf *Config) (blob.Credential, error) {
+	var fetch tokenFetcher
+	switch {
+	case conf.UseFederatedToken:
+		fetch = federatedTokenFetcher(conf)
+	case conf.UseManagedIdentity:
+		fetch = managedIdentityFetcher(conf)
+	default:
+		return blob.NewSharedKeyCredential(conf.StorageAccountName.String(), conf.StorageAccountKey.String())
+	}
+
+	tokenCredentialsMtx.Lock()
+	defer tokenCredentialsMtx.Unlock()
+
+	if c, ok := tokenCredentials[conf]; ok {
+		return c, nil
+	}
+
+	c, err := newTokenCredential(fetch)
+	if err != nil {
+		return nil, err
+	}
+	tokenCredentials[conf] = c
+
+	return c, nil
+}
+
+// newTokenCredential fetches an initial token and returns a credential that keeps it refreshed
+func newTokenCredential(fetch tokenFetcher) (blob.TokenCredential, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenRequestTimeout)
+	defer cancel()
+
+	initial, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the refresher is called immediately by NewTokenCredential.  use the initial token for that call
+	first := true
+	refresher := func(c blob.TokenCredential) time.Duration {
+		if first {
+			first = false
+			return initial.refreshIn()
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), tokenRequestTimeout)
+		defer cancel()
+
+		token, err := fetch(ctx)
+		if err != nil {
+			level.Error(log_util.Logger).Log("msg", "failed to refresh azure token", "err", err)
+			return tokenRetryInterval
+		}
+
+		c.SetToken(token.AccessToken)
+		return token.refreshIn()
+	}
+
+	return blob.NewTokenCredential(initial.AccessToken, refresher), nil
+}
+
+// managedIdentityFetcher requests tokens from the instance metadata service.  If a user assigned
+//  identity is configured it is requested by client id.
+func managedIdentityFetcher(conf *Config) tokenFetcher {
+	return func(ctx context.Context) (*tokenResponse, error) {
+		params := url.Values{}
+		params.Set("api-version", "2018-02-01")
+		params.Set("resource", storageResource)
+		if conf.UserAssignedID != "" {
+			params.Set("client_id", conf.UserAssignedID)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, imdsEndpoint+"?"+params.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Metadata", "true")
+
+		return doTokenRequest(req)
+	}
+}
+
+// federatedTokenFetcher exchanges the federated service account token projected into the pod for an
+//  azure AD token.  This is the flow used by AKS workload identity.
+func federatedTokenFetcher(conf *Config) tokenFetcher {
+	return func(ctx context.Context) (*tokenResponse, error) {
+		clientID := os.Getenv(envClientID)
+		if conf.UserAssignedID != "" {
+			clientID = conf.UserAssignedID
+		}
+		tenantID := os.Getenv(envTenantID)
+		tokenFile := os.Getenv(envFederatedTokenFile)
+		if clientID == "" || tenantID == "" || tokenFile == "" {
+			return nil, fmt.Errorf("federated token authentication requires %s, %s and %s to be set", envClientID, envTenantID, envFederatedTokenFile)
+		}
+
+		authorityHost := os.Getenv(envAuthorityHost)
+		if authorityHost == "" {
+			authorityHost = defaultAuthorityHost
+		}
+
+		// the token file is rotated by kubelet so it is read on every request
+		assertion, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read federated token: %w", err)
+		}
+
+		form := url.Values{}
+		form.Set("grant_type", "client_credentials")
+		form.Set("client_id", clientID)
+		form.Set("scope", storageScope)
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+
+		endpoint := strings.TrimSuffix(authorityHost, "/") + "/" + tenantID + "/oauth2/v2.0/token"
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		return doTokenRequest(req)
+	}
+}
+
+func doTokenRequest(req *http.Request) (*tokenResponse, error) {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request to %s failed with status %d: %s", req.URL.Host, resp.StatusCode, string(body))
+	}
+
+	token := &tokenResponse{}
+	err = json.Unmarshal(body, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("token response from %s did not contain an access token", req.URL.Host)
+	}
+
+	return token, nil
+}
+
+// refreshIn returns how long to wait before refreshing the token
+func (t *tokenResponse) refreshIn() time.Duration {
+	seconds, err := t.ExpiresIn.Int64()
+	if err != nil {
+		return tokenRetryInterval
+	}
+
+	d := time.Duration(seconds)*time.Second - tokenRefreshMargin
+	if d < tokenRetryInterval {
+		return tokenRetryInterval
+	}
+	return d
+}
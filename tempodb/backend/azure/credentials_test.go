@@ -0,0 +1,74 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFederatedTokenFetcher(t *testing.T) {
+	expectedClientID := "client"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/tenant/oauth2/v2.0/token", r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, expectedClientID, r.PostForm.Get("client_id"))
+		assert.Equal(t, "federated-token", r.PostForm.Get("client_assertion"))
+		assert.Equal(t, storageScope, r.PostForm.Get("scope"))
+
+		_, _ = w.Write([]byte(`{"access_token":"access-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	tokenFile := path.Join(t.TempDir(), "token")
+	require.NoError(t, ioutil.WriteFile(tokenFile, []byte("federated-token\n"), 0644))
+
+	for k, v := range map[string]string{
+		envAuthorityHost:      server.URL,
+		envClientID:           "client",
+		envTenantID:           "tenant",
+		envFederatedTokenFile: tokenFile,
+	} {
+		os.Setenv(k, v)
+		defer os.Unsetenv(k)
+	}
+
+	token, err := federatedTokenFetcher(&Config{})(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "access-token", token.AccessToken)
+	assert.Equal(t, time.Hour-tokenRefreshMargin, token.refreshIn())
+
+	// the configured user assigned id takes precedence over the environment
+	expectedClientID = "other"
+	_, err = federatedTokenFetcher(&Config{UserAssignedID: "other"})(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestFederatedTokenFetcherMissingEnv(t *testing.T) {
+	_, err := federatedTokenFetcher(&Config{})(context.Background())
+	assert.Error(t, err)
+}
+
+func TestTokenRefreshIn(t *testing.T) {
+	tests := []struct {
+		expiresIn string
+		expected  time.Duration
+	}{
+		{expiresIn: "3600", expected: 55 * time.Minute},
+		{expiresIn: "60", expected: tokenRetryInterval},
+		{expiresIn: "", expected: tokenRetryInterval},
+	}
+
+	for _, tt := range tests {
+		token := &tokenResponse{ExpiresIn: json.Number(tt.expiresIn)}
+		assert.Equal(t, tt.expected, token.refreshIn())
+	}
+}
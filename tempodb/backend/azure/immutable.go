@@ -0,0 +1,156 @@
+package azure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	blob "github.com/Azure/azure-storage-blob-go/azblob"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/util"
+)
+
+// Containers with an immutability policy do not allow blobs to be replaced or deleted until their retention expires.
+//  With immutable storage enabled meta.json is never replaced.  Updated metas are written as new meta versions and
+//  the newest version is read.  A block is compacted once meta.compacted.json exists, even if meta.json could
+//  not be deleted.
+
+const (
+	metaPrefix                 = "meta"
+	metaFileName               = "meta.json"
+	compactedMetaFileName      = "meta.compacted.json"
+	serviceCodeImmutablePrefix = "BlobImmutable"
+)
+
+// metaVersionRegexp matches the names of meta versions.  The zero padded timestamp makes versions sort by age.
+var metaVersionRegexp = regexp.MustCompile(`^meta\.[0-9]{20}\.json$`)
+
+func metaVersionName(blockID uuid.UUID, tenantID string, t time.Time) string {
+	return util.ObjectFileName(blockID, tenantID, fmt.Sprintf("meta.%020d.json", t.UnixNano()))
+}
+
+// writeBlockMetaVersion writes meta.json if it does not exist yet and a new meta version otherwise
+func (rw *readerWriter) writeBlockMetaVersion(ctx context.Context, blockID uuid.UUID, tenantID string, bMeta []byte) error {
+	err := rw.writer(ctx, bytes.NewReader(bMeta), util.MetaFileName(blockID, tenantID), blob.BlobAccessConditions{
+		ModifiedAccessConditions: blob.ModifiedAccessConditions{IfNoneMatch: blob.ETagAny},
+	})
+	if err == nil || !(isExistsError(err) || isImmutableError(err)) {
+		return err
+	}
+
+	return rw.writeAll(ctx, metaVersionName(blockID, tenantID, time.Now()), bMeta)
+}
+
+// latestMetaName returns the name of the newest meta of a block.  backend.ErrMetaDoesNotExist is returned if the
+//  block has no meta or was compacted.
+func (rw *readerWriter) latestMetaName(ctx context.Context, blockID uuid.UUID, tenantID string) (string, error) {
+	var versions []string
+	hasMeta := false
+	marker := blob.Marker{}
+
+	for {
+		list, err := rw.containerURL.ListBlobsFlatSegment(ctx, marker, blob.ListBlobsSegmentOptions{
+			Prefix: util.ObjectFileName(blockID, tenantID, metaPrefix),
+		})
+		if err != nil {
+			return "", errors.Wrapf(err, "error listing metas of block %s", blockID)
+		}
+		marker = list.NextMarker
+
+		for _, b := range list.Segment.BlobItems {
+			name := path.Base(b.Name)
+			switch {
+			case name == compactedMetaFileName:
+				return "", backend.ErrMetaDoesNotExist
+			case name == metaFileName:
+				hasMeta = true
+			case metaVersionRegexp.MatchString(name):
+				versions = append(versions, b.Name)
+			}
+		}
+
+		if !marker.NotDone() {
+			break
+		}
+	}
+
+	if len(versions) > 0 {
+		sort.Strings(versions)
+		return versions[len(versions)-1], nil
+	}
+	if hasMeta {
+		return util.MetaFileName(blockID, tenantID), nil
+	}
+	return "", backend.ErrMetaDoesNotExist
+}
+
+// markBlockCompactedImmutable writes the compacted meta and then attempts to delete meta.json.  The block is
+//  considered compacted if the delete is prevented by the immutability policy.
+func (rw *readerWriter) markBlockCompactedImmutable(ctx context.Context, blockID uuid.UUID, tenantID string) error {
+	meta, err := rw.BlockMeta(ctx, blockID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	bMeta, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	err = rw.writer(ctx, bytes.NewReader(bMeta), util.CompactedMetaFileName(blockID, tenantID), blob.BlobAccessConditions{
+		ModifiedAccessConditions: blob.ModifiedAccessConditions{IfNoneMatch: blob.ETagAny},
+	})
+	if err != nil && !isExistsError(err) && !isImmutableError(err) {
+		return err
+	}
+
+	err = rw.delete(ctx, util.MetaFileName(blockID, tenantID))
+	if err != nil && !isImmutableError(err) && !isNotFoundError(err) {
+		return err
+	}
+	return nil
+}
+
+// isImmutableError returns true if a blob could not be changed because of an immutability policy or legal hold
+func isImmutableError(err error) bool {
+	ret, ok := storageError(err)
+	return ok && strings.HasPrefix(string(ret.ServiceCode()), serviceCodeImmutablePrefix)
+}
+
+// isNotFoundError returns true if the blob does not exist.  Only newer meta versions may exist.
+func isNotFoundError(err error) bool {
+	ret, ok := storageError(err)
+	return ok && ret.ServiceCode() == blob.ServiceCodeBlobNotFound
+}
+
+// isExistsError returns true if a conditional write failed because the blob already exists
+func isExistsError(err error) bool {
+	ret, ok := storageError(err)
+	return ok && (ret.ServiceCode() == blob.ServiceCodeBlobAlreadyExists || ret.ServiceCode() == blob.ServiceCodeConditionNotMet)
+}
+
+// storageError returns the storage error wrapped by err.  errors.Cause can not be used since storage errors
+//  have a cause themselves.
+func storageError(err error) (blob.StorageError, bool) {
+	for err != nil {
+		if ret, ok := err.(blob.StorageError); ok {
+			return ret, true
+		}
+
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			return nil, false
+		}
+		err = cause.Cause()
+	}
+	return nil, false
+}
@@ -0,0 +1,189 @@
+package azure
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/util/flagext"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/util"
+)
+
+const (
+	testAccount   = "account"
+	testContainer = "container"
+)
+
+// fakeContainer serves listing and deleting blobs of a container.  Locked blobs can not be deleted like blobs
+//  protected by an immutability policy.
+type fakeContainer struct {
+	mtx    sync.Mutex
+	blobs  map[string]bool
+	locked map[string]bool
+}
+
+func (f *fakeContainer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	name := strings.TrimPrefix(r.URL.Path, "/"+testAccount+"/"+testContainer+"/")
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Query().Get("comp") == "list":
+		prefix := r.URL.Query().Get("prefix")
+		var names []string
+		for n := range f.blobs {
+			if strings.HasPrefix(n, prefix) {
+				names = append(names, n)
+			}
+		}
+		sort.Strings(names)
+
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><EnumerationResults ContainerName="%s"><Blobs>`, testContainer)
+		for _, n := range names {
+			fmt.Fprintf(w, `<Blob><Name>%s</Name><Properties></Properties></Blob>`, n)
+		}
+		fmt.Fprint(w, `</Blobs><NextMarker /></EnumerationResults>`)
+	case r.Method == http.MethodDelete:
+		if !f.blobs[name] {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if f.locked[name] {
+			w.Header().Set("x-ms-error-code", "BlobImmutableDueToPolicy")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		delete(f.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func newFakeReaderWriter(t *testing.T, blobs ...string) (*readerWriter, *fakeContainer) {
+	f := &fakeContainer{
+		blobs:  map[string]bool{},
+		locked: map[string]bool{},
+	}
+	for _, b := range blobs {
+		f.blobs[b] = true
+	}
+
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	cfg := &Config{
+		StorageAccountName: flagext.Secret{Value: testAccount},
+		StorageAccountKey:  flagext.Secret{Value: base64.StdEncoding.EncodeToString([]byte("key"))},
+		ContainerName:      testContainer,
+		Endpoint:           u.Host,
+		ImmutableStorage:   true,
+	}
+
+	containerURL, err := GetContainerURL(context.Background(), cfg)
+	require.NoError(t, err)
+
+	return &readerWriter{cfg: cfg, containerURL: containerURL}, f
+}
+
+func TestMetaVersionName(t *testing.T) {
+	blockID := uuid.New()
+	now := time.Now()
+
+	first := metaVersionName(blockID, "test", now)
+	second := metaVersionName(blockID, "test", now.Add(time.Millisecond))
+	assert.True(t, metaVersionRegexp.MatchString(path.Base(first)), first)
+	assert.Equal(t, "test/"+blockID.String(), path.Dir(first))
+
+	// versions sort by the time they were written
+	names := []string{second, first}
+	sort.Strings(names)
+	assert.Equal(t, []string{first, second}, names)
+
+	assert.False(t, metaVersionRegexp.MatchString(metaFileName))
+	assert.False(t, metaVersionRegexp.MatchString(compactedMetaFileName))
+}
+
+func TestLatestMetaName(t *testing.T) {
+	blockID := uuid.New()
+	now := time.Now()
+	meta := util.MetaFileName(blockID, "test")
+	first := metaVersionName(blockID, "test", now)
+	second := metaVersionName(blockID, "test", now.Add(time.Second))
+	data := util.ObjectFileName(blockID, "test", "data")
+
+	tests := []struct {
+		name     string
+		blobs    []string
+		expected string
+		err      error
+	}{
+		{
+			name:  "no meta",
+			blobs: []string{data},
+			err:   backend.ErrMetaDoesNotExist,
+		},
+		{
+			name:     "meta",
+			blobs:    []string{meta, data},
+			expected: meta,
+		},
+		{
+			name:     "newest version wins",
+			blobs:    []string{meta, second, first, data},
+			expected: second,
+		},
+		{
+			name:  "compacted",
+			blobs: []string{meta, first, util.CompactedMetaFileName(blockID, "test")},
+			err:   backend.ErrMetaDoesNotExist,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw, _ := newFakeReaderWriter(t, tt.blobs...)
+
+			name, err := rw.latestMetaName(context.Background(), blockID, "test")
+			assert.Equal(t, tt.err, err)
+			assert.Equal(t, tt.expected, name)
+		})
+	}
+}
+
+func TestClearBlockLocked(t *testing.T) {
+	blockID := uuid.New()
+	meta := util.MetaFileName(blockID, "test")
+	data := util.ObjectFileName(blockID, "test", "data")
+
+	rw, f := newFakeReaderWriter(t, meta, data)
+	f.locked[data] = true
+
+	// unlocked blobs are deleted and the block is reported as locked
+	assert.Equal(t, backend.ErrObjectLocked, rw.ClearBlock(blockID, "test"))
+	assert.Equal(t, map[string]bool{data: true}, f.blobs)
+
+	// once the policy expires the block is cleared
+	f.locked = map[string]bool{}
+	assert.NoError(t, rw.ClearBlock(blockID, "test"))
+	assert.Len(t, f.blobs, 0)
+}
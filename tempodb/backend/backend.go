@@ -9,9 +9,12 @@ import (
 )
 
 var (
-	ErrMetaDoesNotExist = fmt.Errorf("meta does not exist")
-	ErrEmptyTenantID    = fmt.Errorf("empty tenant id")
-	ErrEmptyBlockID     = fmt.Errorf("empty block id")
+	ErrMetaDoesNotExist    = fmt.Errorf("meta does not exist")
+	ErrDoesNotExist        = fmt.Errorf("object does not exist")
+	ErrEmptyTenantID       = fmt.Errorf("empty tenant id")
+	ErrEmptyBlockID        = fmt.Errorf("empty block id")
+	ErrArchiveNotSupported = fmt.Errorf("archiving objects is not supported by this backend")
+	ErrObjectLocked        = fmt.Errorf("object is protected by a retention policy")
 )
 
 // AppendTracker is an empty interface usable by the backend to track a long running append operation
@@ -31,9 +34,11 @@ type Writer interface {
 
 // Reader is a collection of methods to read data from tempodb backends
 type Reader interface {
-	// Reader is for reading entire objects from the backend.  It is expected that there will be an attempt to retrieve this from cache
+	// Reader is for reading entire objects from the backend.  It is expected that there will be an attempt to retrieve this from cache.
+	//  ErrDoesNotExist is returned if the object does not exist.
 	Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error)
 	// ReadRange is for reading parts of large objects from the backend.  It is expected this will _not_ be cached.
+	//  ErrDoesNotExist is returned if the object does not exist.
 	ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte) error
 
 	Tenants(ctx context.Context) ([]string, error)
@@ -48,4 +53,6 @@ type Compactor interface {
 	MarkBlockCompacted(blockID uuid.UUID, tenantID string) error
 	ClearBlock(blockID uuid.UUID, tenantID string) error
 	CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*CompactedBlockMeta, error)
+	// ArchiveObject moves an object to a cheaper storage class.  The object remains readable, possibly with higher latency.
+	ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error
 }
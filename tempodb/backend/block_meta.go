@@ -2,6 +2,7 @@ package backend
 
 import (
 	"bytes"
+	"context"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,6 +28,26 @@ type BlockMeta struct {
 	Encoding        Encoding  `json:"encoding"`
 	IndexPageSize   uint32    `json:"indexPageSize"`
 	TotalRecords    uint32    `json:"totalRecords"`
+
+	Checksums    map[string]uint64 `json:"checksums,omitempty"`    // xxhash of every object in the block keyed by object name
+	StorageClass string            `json:"storageClass,omitempty"` // storage class the data object was archived to.  empty if never archived
+	ShardIndex   uint32            `json:"shardIndex,omitempty"`   // trace id range of the block if it was split by the compactor
+	ShardCount   uint32            `json:"shardCount,omitempty"`   // number of shards the block was split into.  0 if never split
+	Summary      bool              `json:"summary,omitempty"`      // true if the block only contains the root spans of its traces
+}
+
+type blockMetaKey struct{}
+
+// WithBlockMeta returns a context that identifies the block being written with it.  Backends may use it to
+//  describe written objects.
+func WithBlockMeta(ctx context.Context, meta *BlockMeta) context.Context {
+	return context.WithValue(ctx, blockMetaKey{}, meta)
+}
+
+// BlockMetaFromContext returns the meta set by WithBlockMeta or nil
+func BlockMetaFromContext(ctx context.Context) *BlockMeta {
+	meta, _ := ctx.Value(blockMetaKey{}).(*BlockMeta)
+	return meta
 }
 
 func NewBlockMeta(tenantID string, blockID uuid.UUID, version string, encoding Encoding) *BlockMeta {
@@ -58,3 +79,11 @@ func (b *BlockMeta) ObjectAdded(id []byte) {
 
 	b.TotalObjects++
 }
+
+// SetChecksum records the checksum of the named object in the block
+func (b *BlockMeta) SetChecksum(name string, checksum uint64) {
+	if b.Checksums == nil {
+		b.Checksums = map[string]uint64{}
+	}
+	b.Checksums[name] = checksum
+}
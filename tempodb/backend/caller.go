@@ -0,0 +1,28 @@
+package backend
+
+import "context"
+
+// Callers attributed with backend requests
+const (
+	CallerIngester  = "ingester"
+	CallerQuerier   = "querier"
+	CallerCompactor = "compactor"
+	CallerPoller    = "poller"
+
+	callerUnknown = "unknown"
+)
+
+type callerKey struct{}
+
+// WithCaller returns a context that attributes backend requests made with it to the passed caller
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller set by WithCaller or "unknown"
+func CallerFromContext(ctx context.Context) string {
+	if caller, ok := ctx.Value(callerKey{}).(string); ok {
+		return caller
+	}
+	return callerUnknown
+}
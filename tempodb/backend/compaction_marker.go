@@ -0,0 +1,28 @@
+package backend
+
+import (
+	"github.com/google/uuid"
+)
+
+// NewCompactionMarkerID returns the id of a new compaction marker.  Compaction markers list blocks of a tenant that
+//  were marked compacted so pollers see compactions without fetching the meta of every block.  They are stored
+//  like blocks without a meta.  Their ids start with 8 0xff bytes, which a random (version 4) block id never does,
+//  so they are told apart from blocks when listing.
+func NewCompactionMarkerID() uuid.UUID {
+	id := uuid.New()
+	for i := 0; i < 8; i++ {
+		id[i] = 0xff
+	}
+	return id
+}
+
+// IsCompactionMarkerID returns true if the passed id is the id of a compaction marker.  Every consumer of
+//  Reader.Blocks() must skip these ids.
+func IsCompactionMarkerID(id uuid.UUID) bool {
+	for i := 0; i < 8; i++ {
+		if id[i] != 0xff {
+			return false
+		}
+	}
+	return true
+}
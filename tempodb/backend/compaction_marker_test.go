@@ -0,0 +1,17 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsCompactionMarkerID(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		assert.True(t, IsCompactionMarkerID(NewCompactionMarkerID()))
+		assert.False(t, IsCompactionMarkerID(uuid.New()))
+		assert.False(t, IsCompactionMarkerID(NewTombstoneID()))
+		assert.False(t, IsTombstoneID(NewCompactionMarkerID()))
+	}
+}
@@ -21,9 +21,11 @@ func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) e
 	dst := rw.bucket.Object(compactedMetaFilename)
 
 	ctx := context.TODO()
-	_, err := dst.CopierFrom(src).Run(ctx)
+	copier := dst.CopierFrom(src)
+	copier.DestinationKMSKeyName = rw.cfg.KMSKeyName
+	_, err := copier.Run(ctx)
 	if err != nil {
-		return err
+		return rw.wrapKMSError(err)
 	}
 
 	return src.Delete(ctx)
@@ -83,3 +85,23 @@ func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (
 
 	return out, err
 }
+
+// ArchiveObject implements backend.Compactor.  The object is rewritten in place with the new storage class.
+func (rw *readerWriter) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	ctx := context.TODO()
+	obj := rw.bucket.Object(util.ObjectFileName(blockID, tenantID, name))
+
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+	if attrs.StorageClass == storageClass {
+		return nil
+	}
+
+	copier := obj.CopierFrom(obj)
+	copier.StorageClass = storageClass
+	copier.DestinationKMSKeyName = rw.cfg.KMSKeyName
+	_, err = copier.Run(ctx)
+	return rw.wrapKMSError(err)
+}
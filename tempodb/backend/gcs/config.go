@@ -5,4 +5,6 @@ type Config struct {
 	ChunkBufferSize int    `yaml:"chunk_buffer_size"`
 	Endpoint        string `yaml:"endpoint"`
 	Insecure        bool   `yaml:"insecure"`
+	KMSKeyName      string `yaml:"kms_key_name"`
+	UserProject     string `yaml:"user_project"`
 }
@@ -9,6 +9,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"regexp"
 	"strings"
 	"time"
 
@@ -19,11 +20,19 @@ import (
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/opentracing/opentracing-go"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 	google_http "google.golang.org/api/transport/http"
 )
 
+// kmsKeyNameRegexp matches the resource name of a Cloud KMS key
+var kmsKeyNameRegexp = regexp.MustCompile(`^projects/[^/]+/locations/[^/]+/keyRings/[^/]+/cryptoKeys/[^/]+$`)
+
+// kmsProbeObject is written, read and deleted at startup to check that the kms key can be used.  It is stored at
+//  the root of the bucket where it is not mistaken for a tenant.
+const kmsProbeObject = "tempo_kms_probe"
+
 type readerWriter struct {
 	cfg    *Config
 	client *storage.Client
@@ -33,6 +42,10 @@ type readerWriter struct {
 func New(cfg *Config) (backend.Reader, backend.Writer, backend.Compactor, error) {
 	ctx := context.Background()
 
+	if cfg.KMSKeyName != "" && !kmsKeyNameRegexp.MatchString(cfg.KMSKeyName) {
+		return nil, nil, nil, fmt.Errorf("invalid kms key name %s, expected projects/<project>/locations/<location>/keyRings/<ring>/cryptoKeys/<key>", cfg.KMSKeyName)
+	}
+
 	customTransport := http.DefaultTransport.(*http.Transport).Clone()
 	transportOptions := []option.ClientOption{
 		option.WithScopes(storage.ScopeReadWrite),
@@ -64,6 +77,10 @@ func New(cfg *Config) (backend.Reader, backend.Writer, backend.Compactor, error)
 	}
 
 	bucket := client.Bucket(cfg.BucketName)
+	// requests to requester pays buckets are billed to the user project
+	if cfg.UserProject != "" {
+		bucket = bucket.UserProject(cfg.UserProject)
+	}
 
 	// Check bucket exists by getting attrs
 	if _, err = bucket.Attrs(ctx); err != nil {
@@ -76,9 +93,35 @@ func New(cfg *Config) (backend.Reader, backend.Writer, backend.Compactor, error)
 		bucket: bucket,
 	}
 
+	if cfg.KMSKeyName != "" {
+		if err = rw.probeKMSKey(ctx); err != nil {
+			return nil, nil, nil, errors.Wrap(err, "checking kms key")
+		}
+	}
+
 	return rw, rw, rw, nil
 }
 
+// probeKMSKey writes, reads and deletes an object encrypted with the kms key so a missing key or missing
+//  permissions fail startup instead of the first flush
+func (rw *readerWriter) probeKMSKey(ctx context.Context) error {
+	w := rw.writer(ctx, kmsProbeObject)
+	if _, err := w.Write([]byte(kmsProbeObject)); err != nil {
+		w.Close()
+		return rw.wrapKMSError(err)
+	}
+	if err := w.Close(); err != nil {
+		return rw.wrapKMSError(err)
+	}
+
+	_, err := rw.readAll(ctx, kmsProbeObject)
+	if err != nil {
+		return rw.wrapKMSError(err)
+	}
+
+	return rw.bucket.Object(kmsProbeObject).Delete(ctx)
+}
+
 // Write implements backend.Writer
 func (rw *readerWriter) Write(ctx context.Context, name string, blockID uuid.UUID, tenantID string, buffer []byte) error {
 	return rw.WriteReader(ctx, name, blockID, tenantID, bytes.NewBuffer(buffer), int64(len(buffer)))
@@ -90,10 +133,10 @@ func (rw *readerWriter) WriteReader(ctx context.Context, name string, blockID uu
 	_, err := io.Copy(w, data)
 	if err != nil {
 		w.Close()
-		return err
+		return rw.wrapKMSError(err)
 	}
 
-	return w.Close()
+	return rw.wrapKMSError(w.Close())
 }
 
 // WriteBlockMeta implements backend.Writer
@@ -125,7 +168,7 @@ func (rw *readerWriter) Append(ctx context.Context, name string, blockID uuid.UU
 
 	_, err := w.Write(buffer)
 	if err != nil {
-		return nil, err
+		return nil, rw.wrapKMSError(err)
 	}
 
 	return w, nil
@@ -138,7 +181,7 @@ func (rw *readerWriter) CloseAppend(_ context.Context, tracker backend.AppendTra
 	}
 
 	w := tracker.(*storage.Writer)
-	return w.Close()
+	return rw.wrapKMSError(w.Close())
 }
 
 // Tenants implements backend.Reader
@@ -159,6 +202,10 @@ func (rw *readerWriter) Tenants(ctx context.Context) ([]string, error) {
 			return tenants, errors.Wrap(err, "iterating tenants")
 		}
 
+		// objects at the root of the bucket like the kms probe are not tenants
+		if attrs.Prefix == "" {
+			continue
+		}
 		tenants = append(tenants, strings.TrimSuffix(attrs.Prefix, "/"))
 	}
 
@@ -227,6 +274,9 @@ func (rw *readerWriter) Read(ctx context.Context, name string, blockID uuid.UUID
 	span.SetTag("object", name)
 
 	bytes, err := rw.readAll(derivedCtx, util.ObjectFileName(blockID, tenantID, name))
+	if err == storage.ErrObjectNotExist {
+		return nil, backend.ErrDoesNotExist
+	}
 	if err != nil {
 		span.SetTag("error", true)
 	}
@@ -239,6 +289,9 @@ func (rw *readerWriter) ReadRange(ctx context.Context, name string, blockID uuid
 	defer span.Finish()
 
 	err := rw.readRange(derivedCtx, util.ObjectFileName(blockID, tenantID, name), int64(offset), buffer)
+	if err == storage.ErrObjectNotExist {
+		return backend.ErrDoesNotExist
+	}
 	if err != nil {
 		span.SetTag("error", true)
 	}
@@ -259,12 +312,43 @@ func (rw *readerWriter) writeAll(ctx context.Context, name string, b []byte) err
 	}
 
 	err = w.Close()
-	return err
+	return rw.wrapKMSError(err)
+}
+
+// wrapKMSError adds the configured kms key to errors caused by the key so an unavailable key is easy to identify
+func (rw *readerWriter) wrapKMSError(err error) error {
+	if rw.cfg.KMSKeyName == "" || !isKMSError(err) {
+		return err
+	}
+	return errors.Wrapf(err, "error writing object encrypted with kms key %s", rw.cfg.KMSKeyName)
+}
+
+// isKMSError returns true if err is returned by gcs because a kms key is missing, disabled or not permitted.  Other
+//  errors like network errors, server errors or cancelled requests are unrelated to the key.
+func isKMSError(err error) bool {
+	var apiErr *googleapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	if apiErr.Code != http.StatusBadRequest && apiErr.Code != http.StatusForbidden {
+		return false
+	}
+
+	if strings.Contains(strings.ToLower(apiErr.Message), "kms") {
+		return true
+	}
+	for _, item := range apiErr.Errors {
+		if strings.Contains(strings.ToLower(item.Message), "kms") {
+			return true
+		}
+	}
+	return false
 }
 
 func (rw *readerWriter) writer(ctx context.Context, name string) *storage.Writer {
 	w := rw.bucket.Object(name).NewWriter(ctx)
 	w.ChunkSize = rw.cfg.ChunkBufferSize
+	w.KMSKeyName = rw.cfg.KMSKeyName
 	return w
 }
 
@@ -0,0 +1,123 @@
+package gcs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+)
+
+const testKMSKeyName = "projects/p/locations/global/keyRings/r/cryptoKeys/k"
+
+func TestInvalidKMSKeyName(t *testing.T) {
+	for _, name := range []string{
+		"key",
+		"projects/p/locations/l/keyRings/r",
+		"projects/p/locations/l/keyRings/r/cryptoKeys/k/cryptoKeyVersions/1",
+	} {
+		_, _, _, err := New(&Config{BucketName: "bucket", KMSKeyName: name})
+		assert.Error(t, err, name)
+		assert.Contains(t, err.Error(), "invalid kms key name", name)
+	}
+}
+
+func TestKMSKeyNameRegexp(t *testing.T) {
+	assert.True(t, kmsKeyNameRegexp.MatchString(testKMSKeyName))
+}
+
+// fakeGCSServer serves the requests of the kms probe.  Uploads fail with uploadErr if it is set.
+func fakeGCSServer(t *testing.T, uploadErr string) (*httptest.Server, map[string]int) {
+	requests := map[string]int{}
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method]++
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case r.Method == http.MethodGet && strings.HasSuffix(r.URL.Path, "/b/bucket"):
+			fmt.Fprint(w, `{"name": "bucket"}`)
+		case r.Method == http.MethodPost && uploadErr != "":
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, `{"error": {"code": 403, "message": %q, "errors": [{"reason": "forbidden", "message": %q}]}}`, uploadErr, uploadErr)
+		case r.Method == http.MethodPost:
+			fmt.Fprintf(w, `{"name": %q, "bucket": "bucket"}`, kmsProbeObject)
+		case r.Method == http.MethodGet:
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, kmsProbeObject)
+		case r.Method == http.MethodDelete:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests
+}
+
+func TestKMSKeyProbe(t *testing.T) {
+	server, requests := fakeGCSServer(t, "")
+	_, _, _, err := New(&Config{BucketName: "bucket", KMSKeyName: testKMSKeyName, Endpoint: server.URL + "/storage/v1/", Insecure: true})
+	require.NoError(t, err)
+
+	// the probe object is written and deleted again
+	assert.Equal(t, 1, requests[http.MethodPost])
+	assert.Equal(t, 1, requests[http.MethodDelete])
+
+	server, _ = fakeGCSServer(t, "Permission denied on Cloud KMS key. Please ensure that your Cloud Storage service account has been authorized to use this key.")
+	_, _, _, err = New(&Config{BucketName: "bucket", KMSKeyName: testKMSKeyName, Endpoint: server.URL + "/storage/v1/", Insecure: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), testKMSKeyName)
+
+	// without a key nothing is written
+	server, requests = fakeGCSServer(t, "")
+	_, _, _, err = New(&Config{BucketName: "bucket", Endpoint: server.URL + "/storage/v1/", Insecure: true})
+	require.NoError(t, err)
+	assert.Equal(t, 0, requests[http.MethodPost])
+}
+
+func TestIsKMSError(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected bool
+	}{
+		{
+			err:      &googleapi.Error{Code: http.StatusForbidden, Message: "Permission denied on Cloud KMS key."},
+			expected: true,
+		},
+		{
+			err:      fmt.Errorf("writing: %w", &googleapi.Error{Code: http.StatusBadRequest, Errors: []googleapi.ErrorItem{{Reason: "invalid", Message: "Cloud KMS key is disabled."}}}),
+			expected: true,
+		},
+		{
+			err:      &googleapi.Error{Code: http.StatusForbidden, Message: "Access denied."},
+			expected: false,
+		},
+		{
+			err:      &googleapi.Error{Code: http.StatusServiceUnavailable, Message: "Cloud KMS is unavailable."},
+			expected: false,
+		},
+		{
+			err:      context.Canceled,
+			expected: false,
+		},
+		{
+			err:      errors.New("connection reset by peer"),
+			expected: false,
+		},
+		{
+			err:      nil,
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, isKMSError(tt.err), "%v", tt.err)
+	}
+}
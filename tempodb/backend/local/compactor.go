@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -17,7 +18,20 @@ func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) e
 	metaFilename := rw.metaFileName(blockID, tenantID)
 	compactedMetaFilename := rw.compactedMetaFileName(blockID, tenantID)
 
-	return os.Rename(metaFilename, compactedMetaFilename)
+	unlock, err := rw.lockBlock(blockID, tenantID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = os.Rename(metaFilename, compactedMetaFilename)
+	if err != nil {
+		return err
+	}
+
+	// the compacted time is read from the mod time, which rename preserves
+	now := time.Now()
+	return os.Chtimes(compactedMetaFilename, now, now)
 }
 
 func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
@@ -29,7 +43,25 @@ func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
 		return fmt.Errorf("empty block id")
 	}
 
-	return os.RemoveAll(rw.rootPath(blockID, tenantID))
+	unlock, err := rw.lockBlock(blockID, tenantID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	err = os.RemoveAll(rw.rootPath(blockID, tenantID))
+	if err != nil {
+		return err
+	}
+
+	if rw.cfg.SharedFilesystem {
+		err = os.Remove(rw.lockFileName(blockID, tenantID))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
@@ -61,3 +93,8 @@ func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (
 func (rw *readerWriter) compactedMetaFileName(blockID uuid.UUID, tenantID string) string {
 	return path.Join(rw.rootPath(blockID, tenantID), "meta.compacted.json")
 }
+
+// ArchiveObject implements backend.Compactor
+func (rw *readerWriter) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	return backend.ErrArchiveNotSupported
+}
@@ -2,4 +2,8 @@ package local
 
 type Config struct {
 	Path string `yaml:"path"`
+
+	// SharedFilesystem serializes changes to a block across processes with advisory file locks.  Enable it when
+	//  multiple components use the same path on a shared filesystem such as NFS.
+	SharedFilesystem bool `yaml:"shared_filesystem"`
 }
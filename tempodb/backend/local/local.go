@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"io/ioutil"
 	"os"
@@ -19,6 +20,10 @@ type readerWriter struct {
 }
 
 func New(cfg *Config) (backend.Reader, backend.Writer, backend.Compactor, error) {
+	if cfg.SharedFilesystem && !lockingSupported {
+		return nil, nil, nil, errors.New("shared_filesystem is not supported on this platform")
+	}
+
 	err := os.MkdirAll(cfg.Path, os.ModePerm)
 	if err != nil {
 		return nil, nil, nil, err
@@ -63,24 +68,25 @@ func (rw *readerWriter) WriteBlockMeta(ctx context.Context, meta *backend.BlockM
 	blockID := meta.BlockID
 	tenantID := meta.TenantID
 
-	blockFolder := rw.rootPath(blockID, tenantID)
-	err := os.MkdirAll(blockFolder, os.ModePerm)
+	bMeta, err := json.Marshal(meta)
 	if err != nil {
 		return err
 	}
 
-	bMeta, err := json.Marshal(meta)
+	unlock, err := rw.lockBlock(blockID, tenantID)
 	if err != nil {
 		return err
 	}
+	defer unlock()
 
-	metaFileName := rw.metaFileName(blockID, tenantID)
-	err = ioutil.WriteFile(metaFileName, bMeta, 0644)
+	// the folder is created while holding the lock so it can not be cleared before the meta is written
+	blockFolder := rw.rootPath(blockID, tenantID)
+	err = os.MkdirAll(blockFolder, os.ModePerm)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return writeFileAtomic(rw.metaFileName(blockID, tenantID), bMeta)
 }
 
 // Append implements backend.Writer
@@ -186,7 +192,11 @@ func (rw *readerWriter) BlockMeta(ctx context.Context, blockID uuid.UUID, tenant
 // Read implements backend.Reader
 func (rw *readerWriter) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error) {
 	filename := rw.objectFileName(blockID, tenantID, name)
-	return ioutil.ReadFile(filename)
+	bytes, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, backend.ErrDoesNotExist
+	}
+	return bytes, err
 }
 
 // ReadRange implements backend.Reader
@@ -194,6 +204,9 @@ func (rw *readerWriter) ReadRange(ctx context.Context, name string, blockID uuid
 	filename := rw.objectFileName(blockID, tenantID, name)
 
 	f, err := os.OpenFile(filename, os.O_RDONLY, 0644)
+	if os.IsNotExist(err) {
+		return backend.ErrDoesNotExist
+	}
 	if err != nil {
 		return err
 	}
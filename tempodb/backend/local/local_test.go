@@ -67,6 +67,9 @@ func TestReadWrite(t *testing.T) {
 	assert.NoError(t, err, "unexpected error reading")
 	assert.Equal(t, fakeObject, actualObject)
 
+	_, err = r.Read(ctx, "missing", blockID, tenantIDs[0])
+	assert.Equal(t, backend.ErrDoesNotExist, err)
+
 	actualReadRange := make([]byte, 5)
 	err = r.ReadRange(ctx, objectReaderName, blockID, tenantIDs[0], 5, actualReadRange)
 	assert.NoError(t, err, "unexpected error range")
@@ -0,0 +1,59 @@
+package local
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+)
+
+type unlockFunc func() error
+
+// lockBlock takes an exclusive advisory lock on the passed block.  The lock file is kept in the tenant folder so
+//  it survives clearing the block folder.  On NFS flock is emulated with byte range locks by the client.
+func (rw *readerWriter) lockBlock(blockID uuid.UUID, tenantID string) (unlockFunc, error) {
+	if !rw.cfg.SharedFilesystem {
+		return func() error { return nil }, nil
+	}
+
+	tenantFolder := filepath.Join(rw.cfg.Path, tenantID)
+	err := os.MkdirAll(tenantFolder, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	return lockFile(rw.lockFileName(blockID, tenantID))
+}
+
+// writeFileAtomic writes the file to a temporary file in the same folder and renames it into place so readers
+//  on other nodes never see a partially written file.
+func writeFileAtomic(filename string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func (rw *readerWriter) lockFileName(blockID uuid.UUID, tenantID string) string {
+	return filepath.Join(rw.cfg.Path, tenantID, "."+blockID.String()+".lock")
+}
@@ -0,0 +1,71 @@
+//go:build !windows
+// +build !windows
+
+package local
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockingSupported is true if blocks can be locked with shared_filesystem
+const lockingSupported = true
+
+// lockFile takes an exclusive flock on the passed file.  Lock files are removed when their block is cleared,
+//  so after the lock is acquired the file is confirmed to still be the one at the path.  Otherwise a process
+//  holding a lock on the removed file and a process locking a newly created file would both hold the lock.
+func lockFile(filename string) (unlockFunc, error) {
+	for {
+		f, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+
+		err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+
+		same, err := isSameFile(f, filename)
+		if err != nil {
+			unlockAndClose(f)
+			return nil, err
+		}
+		if !same {
+			unlockAndClose(f)
+			continue
+		}
+
+		return func() error {
+			return unlockAndClose(f)
+		}, nil
+	}
+}
+
+// isSameFile returns true if the open file is still the file at filename
+func isSameFile(f *os.File, filename string) (bool, error) {
+	openInfo, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	pathInfo, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return os.SameFile(openInfo, pathInfo), nil
+}
+
+func unlockAndClose(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+	closeErr := f.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
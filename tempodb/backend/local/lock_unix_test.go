@@ -0,0 +1,125 @@
+//go:build !windows
+// +build !windows
+
+package local
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+func TestLockFileRemovedWhileWaiting(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	filename := filepath.Join(tempDir, ".lock")
+
+	unlock1, err := lockFile(filename)
+	require.NoError(t, err)
+
+	// waits on the lock of the first file
+	acquired := make(chan unlockFunc)
+	go func() {
+		unlock, err := lockFile(filename)
+		assert.NoError(t, err)
+		acquired <- unlock
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// the lock file is removed by its holder and a new one is created and locked
+	require.NoError(t, os.Remove(filename))
+	unlock3, err := lockFile(filename)
+	require.NoError(t, err)
+	require.NoError(t, unlock1())
+
+	// the waiting lock must not be granted on the removed file
+	select {
+	case <-acquired:
+		t.Fatal("lock acquired on removed file while the new file is locked")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	require.NoError(t, unlock3())
+	select {
+	case unlock2 := <-acquired:
+		require.NoError(t, unlock2())
+	case <-time.After(time.Second):
+		t.Fatal("lock not acquired after the new file was unlocked")
+	}
+}
+
+func TestSharedFilesystem(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Path:             tempDir,
+		SharedFilesystem: true,
+	})
+	assert.NoError(t, err, "unexpected error creating local backend")
+	rw := r.(*readerWriter)
+
+	ctx := context.Background()
+	blockID := uuid.New()
+	tenantID := "fake"
+	fakeMeta := &backend.BlockMeta{
+		BlockID:  blockID,
+		TenantID: tenantID,
+	}
+
+	err = w.WriteBlockMeta(ctx, fakeMeta)
+	assert.NoError(t, err)
+
+	// only the meta is left in the block folder and the lock file does not show up as a block
+	files, err := ioutil.ReadDir(rw.rootPath(blockID, tenantID))
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "meta.json", files[0].Name())
+
+	blocks, err := r.Blocks(ctx, tenantID)
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{blockID}, blocks)
+
+	// backdate the meta to confirm the compacted time is the time of compaction
+	old := time.Now().Add(-time.Hour)
+	err = os.Chtimes(rw.metaFileName(blockID, tenantID), old, old)
+	assert.NoError(t, err)
+
+	err = c.MarkBlockCompacted(blockID, tenantID)
+	assert.NoError(t, err)
+
+	compactedMeta, err := c.CompactedBlockMeta(blockID, tenantID)
+	assert.NoError(t, err)
+	assert.True(t, compactedMeta.CompactedTime.After(old.Add(time.Minute)))
+
+	// a held lock blocks other holders
+	unlock, err := rw.lockBlock(blockID, tenantID)
+	assert.NoError(t, err)
+
+	f, err := os.Open(rw.lockFileName(blockID, tenantID))
+	assert.NoError(t, err)
+	err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	assert.Equal(t, syscall.EWOULDBLOCK, err)
+	f.Close()
+
+	assert.NoError(t, unlock())
+
+	err = c.ClearBlock(blockID, tenantID)
+	assert.NoError(t, err)
+
+	_, err = os.Stat(rw.lockFileName(blockID, tenantID))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,14 @@
+//go:build windows
+// +build windows
+
+package local
+
+import "errors"
+
+// lockingSupported is false on windows.  Sharing the local backend between processes with shared_filesystem is
+//  only supported on unix systems where flock is available.
+const lockingSupported = false
+
+func lockFile(filename string) (unlockFunc, error) {
+	return nil, errors.New("file locking is not supported on windows")
+}
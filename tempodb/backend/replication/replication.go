@@ -0,0 +1,410 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var (
+	metricReplicaErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "replication_errors_total",
+		Help:      "Total number of failed writes to the replica backend.",
+	}, []string{"operation"})
+	metricReadFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "replication_read_fallbacks_total",
+		Help:      "Total number of reads served by the replica backend after the primary failed.",
+	}, []string{"operation"})
+)
+
+// incompleteTTL is how long a block is remembered as incomplete in the replica.  It bounds the memory used by blocks
+//  whose meta is never written, for example after a crash, and is far longer than writing a block takes.
+const incompleteTTL = 24 * time.Hour
+
+// errReplicaIncomplete is recorded for appends to a block that failed to be written to the replica before
+var errReplicaIncomplete = errors.New("block is incomplete in the replica")
+
+// Backend is a reader, writer and compactor of a single backend
+type Backend struct {
+	Reader    backend.Reader
+	Writer    backend.Writer
+	Compactor backend.Compactor
+}
+
+type readerWriter struct {
+	primary     Backend
+	replica     Backend
+	failOnError bool
+	logger      log.Logger
+
+	// incomplete are the blocks that failed to be written to the replica and when they failed.  Their partial
+	//  objects are cleared and their remaining objects and meta are not written to the replica so it never lists
+	//  a block with missing or partial objects.
+	incomplete    map[blockKey]time.Time
+	incompleteMtx sync.Mutex
+}
+
+type blockKey struct {
+	tenantID string
+	blockID  uuid.UUID
+}
+
+// appendTracker tracks an append to both backends.  Once an append to the replica fails the replica is skipped
+//  for the remainder of the object.
+type appendTracker struct {
+	primary    backend.AppendTracker
+	replica    backend.AppendTracker
+	replicaErr error
+}
+
+// New returns a backend that writes to both the primary and the replica and reads from the primary, falling back
+//  to the replica if the primary fails.  Unless failOnError is set errors writing the replica are logged and
+//  counted but do not fail the write.
+func New(primary Backend, replica Backend, failOnError bool, logger log.Logger) (backend.Reader, backend.Writer, backend.Compactor) {
+	rw := &readerWriter{
+		primary:     primary,
+		replica:     replica,
+		failOnError: failOnError,
+		logger:      logger,
+		incomplete:  map[blockKey]time.Time{},
+	}
+
+	return rw, rw, rw
+}
+
+// Tenants implements backend.Reader
+func (rw *readerWriter) Tenants(ctx context.Context) ([]string, error) {
+	tenants, err := rw.primary.Reader.Tenants(ctx)
+	if err != nil && rw.fallback("Tenants", err) {
+		return rw.replica.Reader.Tenants(ctx)
+	}
+	return tenants, err
+}
+
+// Blocks implements backend.Reader
+func (rw *readerWriter) Blocks(ctx context.Context, tenantID string) ([]uuid.UUID, error) {
+	blocks, err := rw.primary.Reader.Blocks(ctx, tenantID)
+	if err != nil && rw.fallback("Blocks", err) {
+		return rw.replica.Reader.Blocks(ctx, tenantID)
+	}
+	return blocks, err
+}
+
+// BlockMeta implements backend.Reader
+func (rw *readerWriter) BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*backend.BlockMeta, error) {
+	meta, err := rw.primary.Reader.BlockMeta(ctx, blockID, tenantID)
+	if err != nil && rw.fallback("BlockMeta", err) {
+		return rw.replica.Reader.BlockMeta(ctx, blockID, tenantID)
+	}
+	return meta, err
+}
+
+// Read implements backend.Reader
+func (rw *readerWriter) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error) {
+	bytes, err := rw.primary.Reader.Read(ctx, name, blockID, tenantID)
+	if err != nil && rw.fallback("Read", err) {
+		return rw.replica.Reader.Read(ctx, name, blockID, tenantID)
+	}
+	return bytes, err
+}
+
+// ReadRange implements backend.Reader
+func (rw *readerWriter) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte) error {
+	err := rw.primary.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer)
+	if err != nil && rw.fallback("ReadRange", err) {
+		return rw.replica.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer)
+	}
+	return err
+}
+
+// Shutdown implements backend.Reader
+func (rw *readerWriter) Shutdown() {
+	rw.primary.Reader.Shutdown()
+	rw.replica.Reader.Shutdown()
+}
+
+// Write implements backend.Writer
+func (rw *readerWriter) Write(ctx context.Context, name string, blockID uuid.UUID, tenantID string, buffer []byte) error {
+	err := rw.primary.Writer.Write(ctx, name, blockID, tenantID, buffer)
+	if err != nil {
+		return err
+	}
+
+	if rw.isIncomplete(tenantID, blockID) {
+		return nil
+	}
+	return rw.replicaBlockError("Write", tenantID, blockID, rw.replica.Writer.Write(ctx, name, blockID, tenantID, buffer))
+}
+
+// WriteReader implements backend.Writer.  Seekable data is written to the primary and then rewound and written to
+//  the replica so both backends can retry their write.  Other data is streamed to both backends at the same time.
+func (rw *readerWriter) WriteReader(ctx context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, size int64) error {
+	if rw.isIncomplete(tenantID, blockID) {
+		return rw.primary.Writer.WriteReader(ctx, name, blockID, tenantID, data, size)
+	}
+
+	if seeker, ok := data.(io.Seeker); ok {
+		if start, err := seeker.Seek(0, io.SeekCurrent); err == nil {
+			return rw.writeSeeker(ctx, name, blockID, tenantID, data, seeker, start, size)
+		}
+	}
+
+	pr, pw := io.Pipe()
+
+	replicaErr := make(chan error, 1)
+	go func() {
+		err := rw.replica.Writer.WriteReader(ctx, name, blockID, tenantID, pr, size)
+		// drain the pipe so a failed replica does not block the primary
+		_, _ = io.Copy(ioutil.Discard, pr)
+		replicaErr <- err
+	}()
+
+	err := rw.primary.Writer.WriteReader(ctx, name, blockID, tenantID, io.TeeReader(data, pw), size)
+	if err != nil {
+		pw.CloseWithError(err)
+		<-replicaErr
+		return err
+	}
+	pw.Close()
+
+	return rw.replicaBlockError("WriteReader", tenantID, blockID, <-replicaErr)
+}
+
+// writeSeeker writes data to the primary and then rewinds it to start and writes it to the replica
+func (rw *readerWriter) writeSeeker(ctx context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, seeker io.Seeker, start int64, size int64) error {
+	err := rw.primary.Writer.WriteReader(ctx, name, blockID, tenantID, data, size)
+	if err != nil {
+		return err
+	}
+
+	_, err = seeker.Seek(start, io.SeekStart)
+	if err == nil {
+		err = rw.replica.Writer.WriteReader(ctx, name, blockID, tenantID, data, size)
+	}
+	return rw.replicaBlockError("WriteReader", tenantID, blockID, err)
+}
+
+// WriteBlockMeta implements backend.Writer.  The meta of a block that is incomplete in the replica is only written
+//  to the primary.
+func (rw *readerWriter) WriteBlockMeta(ctx context.Context, meta *backend.BlockMeta) error {
+	err := rw.primary.Writer.WriteBlockMeta(ctx, meta)
+	if err != nil {
+		return err
+	}
+
+	if rw.isIncomplete(meta.TenantID, meta.BlockID) {
+		level.Error(rw.logger).Log("msg", "not writing meta of block that is incomplete in replica backend", "blockID", meta.BlockID, "tenantID", meta.TenantID)
+		metricReplicaErrors.WithLabelValues("WriteBlockMeta").Inc()
+		rw.forgetIncomplete(meta.TenantID, meta.BlockID)
+		return nil
+	}
+	return rw.replicaBlockError("WriteBlockMeta", meta.TenantID, meta.BlockID, rw.replica.Writer.WriteBlockMeta(ctx, meta))
+}
+
+// Append implements backend.Writer.  A failed append to the replica closes the replica's upload and clears the
+//  partial block from the replica.  With failOnError the primary's upload is left unfinished like after a failed
+//  append to the primary so no truncated object is completed.
+func (rw *readerWriter) Append(ctx context.Context, name string, blockID uuid.UUID, tenantID string, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	var t *appendTracker
+	if tracker == nil {
+		t = &appendTracker{}
+		if rw.isIncomplete(tenantID, blockID) {
+			t.replicaErr = errReplicaIncomplete
+		}
+	} else {
+		t = tracker.(*appendTracker)
+	}
+
+	var err error
+	t.primary, err = rw.primary.Writer.Append(ctx, name, blockID, tenantID, t.primary, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.replicaErr == nil {
+		replica, err := rw.replica.Writer.Append(ctx, name, blockID, tenantID, t.replica, buffer)
+		if err == nil {
+			t.replica = replica
+			return t, nil
+		}
+
+		// backends may return the tracker of the upload together with the error
+		if replica == nil {
+			replica = t.replica
+		}
+		t.replica, t.replicaErr = nil, err
+		_ = rw.replica.Writer.CloseAppend(ctx, replica)
+
+		if rw.failOnError {
+			rw.clearReplica(tenantID, blockID)
+			return nil, err
+		}
+		rw.markIncomplete(tenantID, blockID)
+	}
+
+	return t, nil
+}
+
+// CloseAppend implements backend.Writer
+func (rw *readerWriter) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	if tracker == nil {
+		return nil
+	}
+
+	t := tracker.(*appendTracker)
+	err := rw.primary.Writer.CloseAppend(ctx, t.primary)
+	if err != nil {
+		return err
+	}
+
+	if t.replicaErr == errReplicaIncomplete {
+		return nil
+	}
+	if t.replicaErr != nil {
+		return rw.replicaError("Append", t.replicaErr)
+	}
+	return rw.replicaError("CloseAppend", rw.replica.Writer.CloseAppend(ctx, t.replica))
+}
+
+// MarkBlockCompacted implements backend.Compactor.  Blocks without a meta in the replica, like blocks that were
+//  incomplete in the replica, are only marked compacted in the primary.
+func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error {
+	err := rw.primary.Compactor.MarkBlockCompacted(blockID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	ctx := backend.WithCaller(context.Background(), backend.CallerCompactor)
+	if _, err := rw.replica.Reader.BlockMeta(ctx, blockID, tenantID); err == backend.ErrMetaDoesNotExist {
+		return nil
+	}
+
+	return rw.replicaError("MarkBlockCompacted", rw.replica.Compactor.MarkBlockCompacted(blockID, tenantID))
+}
+
+// ClearBlock implements backend.Compactor
+func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
+	err := rw.primary.Compactor.ClearBlock(blockID, tenantID)
+	if err != nil {
+		return err
+	}
+
+	rw.forgetIncomplete(tenantID, blockID)
+
+	return rw.replicaError("ClearBlock", rw.replica.Compactor.ClearBlock(blockID, tenantID))
+}
+
+// ArchiveObject implements backend.Compactor
+func (rw *readerWriter) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	err := rw.primary.Compactor.ArchiveObject(name, blockID, tenantID, storageClass)
+	if err != nil {
+		return err
+	}
+
+	return rw.replicaError("ArchiveObject", rw.replica.Compactor.ArchiveObject(name, blockID, tenantID, storageClass))
+}
+
+// CompactedBlockMeta implements backend.Compactor
+func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	meta, err := rw.primary.Compactor.CompactedBlockMeta(blockID, tenantID)
+	if err != nil && rw.fallback("CompactedBlockMeta", err) {
+		return rw.replica.Compactor.CompactedBlockMeta(blockID, tenantID)
+	}
+	return meta, err
+}
+
+// fallback returns true if a failed read of the primary should be retried against the replica.  A missing meta
+//  or object is an answer rather than a failure: the block may have been compacted in the primary.
+func (rw *readerWriter) fallback(operation string, err error) bool {
+	if err == backend.ErrMetaDoesNotExist || err == backend.ErrDoesNotExist {
+		return false
+	}
+
+	level.Warn(rw.logger).Log("msg", "primary backend failed, reading from replica", "operation", operation, "err", err)
+	metricReadFallbacks.WithLabelValues(operation).Inc()
+	return true
+}
+
+// replicaError records a failed replica write.  It is only returned if failOnError is set.
+func (rw *readerWriter) replicaError(operation string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	level.Error(rw.logger).Log("msg", "failed to write to replica backend", "operation", operation, "err", err)
+	metricReplicaErrors.WithLabelValues(operation).Inc()
+
+	if rw.failOnError {
+		return err
+	}
+	return nil
+}
+
+// replicaBlockError records a failed replica write of an object of a block.  The block is incomplete in the
+//  replica from then on.
+func (rw *readerWriter) replicaBlockError(operation string, tenantID string, blockID uuid.UUID, err error) error {
+	if err != nil && !rw.failOnError {
+		rw.markIncomplete(tenantID, blockID)
+	}
+	return rw.replicaError(operation, err)
+}
+
+// markIncomplete records a block as incomplete in the replica and clears the objects already written to the
+//  replica.  Expired blocks are forgotten.
+func (rw *readerWriter) markIncomplete(tenantID string, blockID uuid.UUID) {
+	key := blockKey{tenantID: tenantID, blockID: blockID}
+	now := time.Now()
+
+	rw.incompleteMtx.Lock()
+	for k, t := range rw.incomplete {
+		if now.Sub(t) > incompleteTTL {
+			delete(rw.incomplete, k)
+		}
+	}
+	_, ok := rw.incomplete[key]
+	rw.incomplete[key] = now
+	rw.incompleteMtx.Unlock()
+
+	if ok {
+		return
+	}
+
+	rw.clearReplica(tenantID, blockID)
+}
+
+// clearReplica removes the partial objects of a block that failed to be written from the replica
+func (rw *readerWriter) clearReplica(tenantID string, blockID uuid.UUID) {
+	err := rw.replica.Compactor.ClearBlock(blockID, tenantID)
+	if err != nil {
+		level.Error(rw.logger).Log("msg", "failed to clear incomplete block from replica backend", "blockID", blockID, "tenantID", tenantID, "err", err)
+		metricReplicaErrors.WithLabelValues("ClearBlock").Inc()
+	}
+}
+
+func (rw *readerWriter) isIncomplete(tenantID string, blockID uuid.UUID) bool {
+	rw.incompleteMtx.Lock()
+	defer rw.incompleteMtx.Unlock()
+
+	t, ok := rw.incomplete[blockKey{tenantID: tenantID, blockID: blockID}]
+	return ok && time.Since(t) <= incompleteTTL
+}
+
+func (rw *readerWriter) forgetIncomplete(tenantID string, blockID uuid.UUID) {
+	rw.incompleteMtx.Lock()
+	defer rw.incompleteMtx.Unlock()
+
+	delete(rw.incomplete, blockKey{tenantID: tenantID, blockID: blockID})
+}
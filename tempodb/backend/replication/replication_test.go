@@ -0,0 +1,356 @@
+package replication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/backend/retry"
+)
+
+const testTenantID = "fake"
+
+var errFailed = errors.New("failed")
+
+// failingBackend fails all operations
+type failingBackend struct {
+	backend.Reader
+	backend.Writer
+	backend.Compactor
+}
+
+func (failingBackend) Read(context.Context, string, uuid.UUID, string) ([]byte, error) {
+	return nil, errFailed
+}
+
+func (failingBackend) BlockMeta(context.Context, uuid.UUID, string) (*backend.BlockMeta, error) {
+	return nil, errFailed
+}
+
+func (failingBackend) Write(context.Context, string, uuid.UUID, string, []byte) error {
+	return errFailed
+}
+
+func (failingBackend) MarkBlockCompacted(uuid.UUID, string) error {
+	return errFailed
+}
+
+func (failingBackend) ClearBlock(uuid.UUID, string) error {
+	return errFailed
+}
+
+func (failingBackend) Append(context.Context, string, uuid.UUID, string, backend.AppendTracker, []byte) (backend.AppendTracker, error) {
+	return nil, errFailed
+}
+
+func (failingBackend) CloseAppend(context.Context, backend.AppendTracker) error {
+	return errFailed
+}
+
+func newLocalBackend(t *testing.T) Backend {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	r, w, c, err := local.New(&local.Config{Path: tempDir})
+	require.NoError(t, err)
+
+	return Backend{Reader: r, Writer: w, Compactor: c}
+}
+
+func newFailingBackend() Backend {
+	f := failingBackend{}
+	return Backend{Reader: f, Writer: f, Compactor: f}
+}
+
+func TestReplicatesWrites(t *testing.T) {
+	primary := newLocalBackend(t)
+	replica := newLocalBackend(t)
+	_, w, c := New(primary, replica, false, log.NewNopLogger())
+
+	ctx := context.Background()
+	blockID := uuid.New()
+	meta := backend.NewBlockMeta(testTenantID, blockID, "v2", backend.EncNone)
+
+	require.NoError(t, w.Write(ctx, "index", blockID, testTenantID, []byte("index")))
+	require.NoError(t, w.WriteReader(ctx, "bloom", blockID, testTenantID, bytes.NewBufferString("bloom"), 5))
+
+	tracker, err := w.Append(ctx, "data", blockID, testTenantID, nil, []byte("da"))
+	require.NoError(t, err)
+	tracker, err = w.Append(ctx, "data", blockID, testTenantID, tracker, []byte("ta"))
+	require.NoError(t, err)
+	require.NoError(t, w.CloseAppend(ctx, tracker))
+
+	require.NoError(t, w.WriteBlockMeta(ctx, meta))
+
+	for _, b := range []Backend{primary, replica} {
+		for name, expected := range map[string]string{"index": "index", "bloom": "bloom", "data": "data"} {
+			actual, err := b.Reader.Read(ctx, name, blockID, testTenantID)
+			require.NoError(t, err)
+			assert.Equal(t, []byte(expected), actual)
+		}
+
+		_, err = b.Reader.BlockMeta(ctx, blockID, testTenantID)
+		assert.NoError(t, err)
+	}
+
+	require.NoError(t, c.MarkBlockCompacted(blockID, testTenantID))
+	for _, b := range []Backend{primary, replica} {
+		_, err = b.Compactor.CompactedBlockMeta(blockID, testTenantID)
+		assert.NoError(t, err)
+	}
+
+	require.NoError(t, c.ClearBlock(blockID, testTenantID))
+	for _, b := range []Backend{primary, replica} {
+		_, err = b.Compactor.CompactedBlockMeta(blockID, testTenantID)
+		assert.Equal(t, backend.ErrMetaDoesNotExist, err)
+	}
+}
+
+func TestReadFallback(t *testing.T) {
+	replica := newLocalBackend(t)
+	r, _, _ := New(newFailingBackend(), replica, false, log.NewNopLogger())
+
+	ctx := context.Background()
+	blockID := uuid.New()
+	require.NoError(t, replica.Writer.Write(ctx, "index", blockID, testTenantID, []byte("index")))
+	require.NoError(t, replica.Writer.WriteBlockMeta(ctx, backend.NewBlockMeta(testTenantID, blockID, "v2", backend.EncNone)))
+
+	actual, err := r.Read(ctx, "index", blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("index"), actual)
+
+	_, err = r.BlockMeta(ctx, blockID, testTenantID)
+	assert.NoError(t, err)
+
+	// a missing meta in the primary is not a failure
+	primary := newLocalBackend(t)
+	r, _, _ = New(primary, replica, false, log.NewNopLogger())
+	_, err = r.BlockMeta(ctx, blockID, testTenantID)
+	assert.Equal(t, backend.ErrMetaDoesNotExist, err)
+
+	// neither is a missing object
+	_, err = r.Read(ctx, "index", blockID, testTenantID)
+	assert.Equal(t, backend.ErrDoesNotExist, err)
+}
+
+func TestReplicaErrors(t *testing.T) {
+	ctx := context.Background()
+	blockID := uuid.New()
+
+	// replica errors are ignored by default
+	primary := newLocalBackend(t)
+	_, w, _ := New(primary, newFailingBackend(), false, log.NewNopLogger())
+
+	assert.NoError(t, w.Write(ctx, "index", blockID, testTenantID, []byte("index")))
+
+	tracker, err := w.Append(ctx, "data", blockID, testTenantID, nil, []byte("da"))
+	require.NoError(t, err)
+	tracker, err = w.Append(ctx, "data", blockID, testTenantID, tracker, []byte("ta"))
+	require.NoError(t, err)
+	assert.NoError(t, w.CloseAppend(ctx, tracker))
+
+	actual, err := primary.Reader.Read(ctx, "data", blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("data"), actual)
+
+	// and returned if configured
+	_, w, _ = New(newLocalBackend(t), newFailingBackend(), true, log.NewNopLogger())
+
+	assert.Equal(t, errFailed, w.Write(ctx, "index", blockID, testTenantID, []byte("index")))
+	_, err = w.Append(ctx, "data", blockID, testTenantID, nil, []byte("da"))
+	assert.Equal(t, errFailed, err)
+}
+
+// appendFailingWriter fails appends
+type appendFailingWriter struct {
+	backend.Writer
+}
+
+func (appendFailingWriter) Append(context.Context, string, uuid.UUID, string, backend.AppendTracker, []byte) (backend.AppendTracker, error) {
+	return nil, errFailed
+}
+
+func TestReplicaAppendFails(t *testing.T) {
+	ctx := context.Background()
+	blockID := uuid.New()
+	meta := backend.NewBlockMeta(testTenantID, blockID, "v2", backend.EncNone)
+
+	primary := newLocalBackend(t)
+	replica := newLocalBackend(t)
+	_, w, c := New(primary, Backend{
+		Reader:    replica.Reader,
+		Writer:    appendFailingWriter{Writer: replica.Writer},
+		Compactor: replica.Compactor,
+	}, false, log.NewNopLogger())
+
+	// objects written before the failure are cleared from the replica
+	require.NoError(t, w.Write(ctx, "index", blockID, testTenantID, []byte("index")))
+
+	tracker, err := w.Append(ctx, "data", blockID, testTenantID, nil, []byte("data"))
+	require.NoError(t, err)
+	require.NoError(t, w.CloseAppend(ctx, tracker))
+
+	require.NoError(t, w.WriteReader(ctx, "bloom", blockID, testTenantID, bytes.NewBufferString("bloom"), 5))
+	require.NoError(t, w.WriteBlockMeta(ctx, meta))
+
+	// the block is complete in the primary
+	for _, name := range []string{"data", "index", "bloom"} {
+		_, err = primary.Reader.Read(ctx, name, blockID, testTenantID)
+		assert.NoError(t, err)
+	}
+	_, err = primary.Reader.BlockMeta(ctx, blockID, testTenantID)
+	assert.NoError(t, err)
+
+	// and is never listed by the replica
+	for _, name := range []string{"index", "bloom"} {
+		_, err = replica.Reader.Read(ctx, name, blockID, testTenantID)
+		assert.Equal(t, backend.ErrDoesNotExist, err)
+	}
+	_, err = replica.Reader.BlockMeta(ctx, blockID, testTenantID)
+	assert.Equal(t, backend.ErrMetaDoesNotExist, err)
+
+	// and is only marked compacted in the primary
+	require.NoError(t, c.MarkBlockCompacted(blockID, testTenantID))
+	_, err = primary.Compactor.CompactedBlockMeta(blockID, testTenantID)
+	assert.NoError(t, err)
+
+	// other blocks are still replicated
+	otherID := uuid.New()
+	require.NoError(t, w.Write(ctx, "index", otherID, testTenantID, []byte("index")))
+	require.NoError(t, w.WriteBlockMeta(ctx, backend.NewBlockMeta(testTenantID, otherID, "v2", backend.EncNone)))
+	_, err = replica.Reader.BlockMeta(ctx, otherID, testTenantID)
+	assert.NoError(t, err)
+}
+
+func TestIncompleteExpires(t *testing.T) {
+	rw := &readerWriter{
+		replica:    newLocalBackend(t),
+		logger:     log.NewNopLogger(),
+		incomplete: map[blockKey]time.Time{},
+	}
+
+	expired := uuid.New()
+	rw.markIncomplete(testTenantID, expired)
+	assert.True(t, rw.isIncomplete(testTenantID, expired))
+
+	rw.incomplete[blockKey{tenantID: testTenantID, blockID: expired}] = time.Now().Add(-incompleteTTL - time.Minute)
+	assert.False(t, rw.isIncomplete(testTenantID, expired))
+
+	// expired blocks are forgotten once another block fails
+	blockID := uuid.New()
+	rw.markIncomplete(testTenantID, blockID)
+	assert.True(t, rw.isIncomplete(testTenantID, blockID))
+	assert.Len(t, rw.incomplete, 1)
+}
+
+// flakyWriter fails appends after failAfter appends and the first write of every object after reading part of it.
+//  It records the appends it closes.
+type flakyWriter struct {
+	backend.Writer
+
+	failAfter int
+	appends   int
+	closed    int
+	failed    map[string]bool
+}
+
+func (w *flakyWriter) WriteReader(ctx context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, size int64) error {
+	if !w.failed[name] {
+		w.failed[name] = true
+		_, _ = data.Read(make([]byte, 2))
+		return io.ErrUnexpectedEOF
+	}
+	return w.Writer.WriteReader(ctx, name, blockID, tenantID, data, size)
+}
+
+func (w *flakyWriter) Append(ctx context.Context, name string, blockID uuid.UUID, tenantID string, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	w.appends++
+	if w.failAfter > 0 && w.appends > w.failAfter {
+		return nil, errFailed
+	}
+	return w.Writer.Append(ctx, name, blockID, tenantID, tracker, buffer)
+}
+
+func (w *flakyWriter) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	w.closed++
+	return w.Writer.CloseAppend(ctx, tracker)
+}
+
+func newFlakyBackend(t *testing.T, failAfter int) (Backend, *flakyWriter) {
+	b := newLocalBackend(t)
+	w := &flakyWriter{Writer: b.Writer, failAfter: failAfter, failed: map[string]bool{}}
+	return Backend{Reader: b.Reader, Writer: w, Compactor: b.Compactor}, w
+}
+
+func TestReplicationRetriesWrites(t *testing.T) {
+	ctx := context.Background()
+	blockID := uuid.New()
+	cfg := &retry.Config{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	withRetries := func(b Backend) Backend {
+		r, w, c := retry.New(b.Reader, b.Writer, b.Compactor, cfg)
+		return Backend{Reader: r, Writer: w, Compactor: c}
+	}
+
+	primary, _ := newFlakyBackend(t, 0)
+	replica, _ := newFlakyBackend(t, 0)
+	_, w, _ := New(withRetries(primary), withRetries(replica), true, log.NewNopLogger())
+
+	// seekable data is rewound for the retries of both backends
+	require.NoError(t, w.WriteReader(ctx, "bloom", blockID, testTenantID, bytes.NewReader([]byte("bloom")), 5))
+
+	for _, b := range []Backend{primary, replica} {
+		actual, err := b.Reader.Read(ctx, "bloom", blockID, testTenantID)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("bloom"), actual)
+	}
+}
+
+func TestReplicaAppendFailureClosesUploads(t *testing.T) {
+	ctx := context.Background()
+
+	for _, failOnError := range []bool{false, true} {
+		blockID := uuid.New()
+		primary, primaryW := newFlakyBackend(t, 0)
+		replica, replicaW := newFlakyBackend(t, 1)
+		_, w, _ := New(primary, replica, failOnError, log.NewNopLogger())
+
+		tracker, err := w.Append(ctx, "data", blockID, testTenantID, nil, []byte("da"))
+		require.NoError(t, err)
+		tracker, err = w.Append(ctx, "data", blockID, testTenantID, tracker, []byte("ta"))
+
+		// the replica's upload is closed and its partial object cleared
+		assert.Equal(t, 1, replicaW.closed)
+		_, readErr := replica.Reader.Read(ctx, "data", blockID, testTenantID)
+		assert.Equal(t, backend.ErrDoesNotExist, readErr)
+
+		if failOnError {
+			// the truncated object is not completed in the primary
+			assert.Equal(t, errFailed, err)
+			assert.Equal(t, 0, primaryW.closed)
+			continue
+		}
+
+		require.NoError(t, err)
+		require.NoError(t, w.CloseAppend(ctx, tracker))
+		assert.Equal(t, 1, primaryW.closed)
+		assert.Equal(t, 1, replicaW.closed)
+
+		actual, err := primary.Reader.Read(ctx, "data", blockID, testTenantID)
+		require.NoError(t, err)
+		assert.Equal(t, []byte("data"), actual)
+	}
+}
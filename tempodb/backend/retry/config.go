@@ -0,0 +1,12 @@
+package retry
+
+import "time"
+
+type Config struct {
+	MaxRetries int           `yaml:"max_retries"`
+	MinBackoff time.Duration `yaml:"min_backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// MaxRetryDuration is the budget of a single operation including all retries.  Retries that would start after
+	//  the budget is spent are not attempted.  0 disables the budget.
+	MaxRetryDuration time.Duration `yaml:"max_retry_duration"`
+}
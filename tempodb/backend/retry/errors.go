@@ -0,0 +1,82 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/ncw/swift"
+	"google.golang.org/api/googleapi"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// responseError is implemented by azure storage errors
+type responseError interface {
+	Response() *http.Response
+}
+
+// IsRetryable returns true if the error is likely transient: a throttled or failed request or a network error.
+//  Errors describing the request itself, like missing objects or denied access, are not retried.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, backend.ErrMetaDoesNotExist) ||
+		errors.Is(err, backend.ErrDoesNotExist) ||
+		errors.Is(err, backend.ErrEmptyTenantID) ||
+		errors.Is(err, backend.ErrEmptyBlockID) ||
+		errors.Is(err, context.Canceled) ||
+		os.IsNotExist(err) {
+		return false
+	}
+
+	if code, ok := statusCode(err); ok {
+		return code == http.StatusTooManyRequests || code == http.StatusRequestTimeout || code >= 500
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return netErr.Timeout() || netErr.Temporary()
+	}
+
+	return false
+}
+
+// statusCode extracts the http status code from the error types of the supported backends
+func statusCode(err error) (int, bool) {
+	var minioErr minio.ErrorResponse
+	if errors.As(err, &minioErr) && minioErr.StatusCode != 0 {
+		return minioErr.StatusCode, true
+	}
+
+	var googleErr *googleapi.Error
+	if errors.As(err, &googleErr) {
+		return googleErr.Code, true
+	}
+
+	var swiftErr *swift.Error
+	if errors.As(err, &swiftErr) && swiftErr.StatusCode != 0 {
+		return swiftErr.StatusCode, true
+	}
+
+	var respErr responseError
+	if errors.As(err, &respErr) && respErr.Response() != nil {
+		return respErr.Response().StatusCode, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,207 @@
+package retry
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/util"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var (
+	metricRetries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_retries_total",
+		Help:      "Total number of retried backend operations.",
+	}, []string{"operation"})
+)
+
+type readerWriter struct {
+	cfg        *Config
+	nextReader backend.Reader
+	nextWriter backend.Writer
+	nextComp   backend.Compactor
+}
+
+// New wraps the passed backend and retries operations that fail with a retryable error.  Appends and marking
+//  blocks compacted are not idempotent and are passed through unchanged.
+func New(nextReader backend.Reader, nextWriter backend.Writer, nextComp backend.Compactor, cfg *Config) (backend.Reader, backend.Writer, backend.Compactor) {
+	rw := &readerWriter{
+		cfg:        cfg,
+		nextReader: nextReader,
+		nextWriter: nextWriter,
+		nextComp:   nextComp,
+	}
+
+	return rw, rw, rw
+}
+
+// Tenants implements backend.Reader
+func (rw *readerWriter) Tenants(ctx context.Context) ([]string, error) {
+	var tenants []string
+	err := rw.retry(ctx, "Tenants", func() error {
+		var err error
+		tenants, err = rw.nextReader.Tenants(ctx)
+		return err
+	})
+	return tenants, err
+}
+
+// Blocks implements backend.Reader
+func (rw *readerWriter) Blocks(ctx context.Context, tenantID string) ([]uuid.UUID, error) {
+	var blocks []uuid.UUID
+	err := rw.retry(ctx, "Blocks", func() error {
+		var err error
+		blocks, err = rw.nextReader.Blocks(ctx, tenantID)
+		return err
+	})
+	return blocks, err
+}
+
+// BlockMeta implements backend.Reader
+func (rw *readerWriter) BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*backend.BlockMeta, error) {
+	var meta *backend.BlockMeta
+	err := rw.retry(ctx, "BlockMeta", func() error {
+		var err error
+		meta, err = rw.nextReader.BlockMeta(ctx, blockID, tenantID)
+		return err
+	})
+	return meta, err
+}
+
+// Read implements backend.Reader
+func (rw *readerWriter) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error) {
+	var bytes []byte
+	err := rw.retry(ctx, "Read", func() error {
+		var err error
+		bytes, err = rw.nextReader.Read(ctx, name, blockID, tenantID)
+		return err
+	})
+	return bytes, err
+}
+
+// ReadRange implements backend.Reader
+func (rw *readerWriter) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte) error {
+	return rw.retry(ctx, "ReadRange", func() error {
+		return rw.nextReader.ReadRange(ctx, name, blockID, tenantID, offset, buffer)
+	})
+}
+
+// Shutdown implements backend.Reader
+func (rw *readerWriter) Shutdown() {
+	rw.nextReader.Shutdown()
+}
+
+// Write implements backend.Writer
+func (rw *readerWriter) Write(ctx context.Context, name string, blockID uuid.UUID, tenantID string, buffer []byte) error {
+	return rw.retry(ctx, "Write", func() error {
+		return rw.nextWriter.Write(ctx, name, blockID, tenantID, buffer)
+	})
+}
+
+// WriteReader implements backend.Writer.  The write is only retried if the reader can be rewound.
+func (rw *readerWriter) WriteReader(ctx context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, size int64) error {
+	seeker, ok := data.(io.Seeker)
+	if !ok {
+		return rw.nextWriter.WriteReader(ctx, name, blockID, tenantID, data, size)
+	}
+
+	start, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return rw.nextWriter.WriteReader(ctx, name, blockID, tenantID, data, size)
+	}
+
+	attempt := 0
+	return rw.retry(ctx, "WriteReader", func() error {
+		if attempt > 0 {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		attempt++
+		return rw.nextWriter.WriteReader(ctx, name, blockID, tenantID, data, size)
+	})
+}
+
+// WriteBlockMeta implements backend.Writer
+func (rw *readerWriter) WriteBlockMeta(ctx context.Context, meta *backend.BlockMeta) error {
+	return rw.retry(ctx, "WriteBlockMeta", func() error {
+		return rw.nextWriter.WriteBlockMeta(ctx, meta)
+	})
+}
+
+// Append implements backend.Writer
+func (rw *readerWriter) Append(ctx context.Context, name string, blockID uuid.UUID, tenantID string, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	return rw.nextWriter.Append(ctx, name, blockID, tenantID, tracker, buffer)
+}
+
+// CloseAppend implements backend.Writer
+func (rw *readerWriter) CloseAppend(ctx context.Context, tracker backend.AppendTracker) error {
+	return rw.nextWriter.CloseAppend(ctx, tracker)
+}
+
+// MarkBlockCompacted implements backend.Compactor
+func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error {
+	return rw.nextComp.MarkBlockCompacted(blockID, tenantID)
+}
+
+// ClearBlock implements backend.Compactor
+func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
+	return rw.retry(context.Background(), "ClearBlock", func() error {
+		return rw.nextComp.ClearBlock(blockID, tenantID)
+	})
+}
+
+// CompactedBlockMeta implements backend.Compactor
+func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	var meta *backend.CompactedBlockMeta
+	err := rw.retry(context.Background(), "CompactedBlockMeta", func() error {
+		var err error
+		meta, err = rw.nextComp.CompactedBlockMeta(blockID, tenantID)
+		return err
+	})
+	return meta, err
+}
+
+// retry calls fn until it succeeds, fails with an error that is not retryable or the retries or budget of the
+//  operation are exhausted.  The last error of fn is returned.
+func (rw *readerWriter) retry(ctx context.Context, operation string, fn func() error) error {
+	start := time.Now()
+	backoff := util.NewBackoff(ctx, util.BackoffConfig{
+		MinBackoff: rw.cfg.MinBackoff,
+		MaxBackoff: rw.cfg.MaxBackoff,
+		MaxRetries: rw.cfg.MaxRetries,
+	})
+
+	for {
+		err := fn()
+		if !IsRetryable(err) || !backoff.Ongoing() {
+			return err
+		}
+
+		delay := backoff.NextDelay()
+		if rw.cfg.MaxRetryDuration > 0 && time.Since(start)+delay > rw.cfg.MaxRetryDuration {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+
+		metricRetries.WithLabelValues(operation).Inc()
+	}
+}
+
+// ArchiveObject implements backend.Compactor
+func (rw *readerWriter) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	return rw.retry(context.Background(), "ArchiveObject", func() error {
+		return rw.nextComp.ArchiveObject(name, blockID, tenantID, storageClass)
+	})
+}
@@ -0,0 +1,211 @@
+package retry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/minio/minio-go/v7"
+	"github.com/ncw/swift"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/googleapi"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var errUnavailable = minio.ErrorResponse{StatusCode: http.StatusServiceUnavailable}
+
+// failingBackend fails the first failures calls with err
+type failingBackend struct {
+	backend.Reader
+	backend.Writer
+	backend.Compactor
+
+	failures int
+	err      error
+	calls    int
+	written  [][]byte
+}
+
+func (f *failingBackend) call() error {
+	f.calls++
+	if f.calls <= f.failures {
+		return f.err
+	}
+	return nil
+}
+
+func (f *failingBackend) Read(context.Context, string, uuid.UUID, string) ([]byte, error) {
+	if err := f.call(); err != nil {
+		return nil, err
+	}
+	return []byte("data"), nil
+}
+
+func (f *failingBackend) WriteReader(_ context.Context, _ string, _ uuid.UUID, _ string, data io.Reader, _ int64) error {
+	b, err := ioutil.ReadAll(data)
+	if err != nil {
+		return err
+	}
+	f.written = append(f.written, b)
+	return f.call()
+}
+
+func (f *failingBackend) Append(context.Context, string, uuid.UUID, string, backend.AppendTracker, []byte) (backend.AppendTracker, error) {
+	return nil, f.call()
+}
+
+func newTestRetry(next *failingBackend, cfg *Config) *readerWriter {
+	r, _, _ := New(next, next, next, cfg)
+	return r.(*readerWriter)
+}
+
+func testConfig() *Config {
+	return &Config{
+		MaxRetries: 3,
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 2 * time.Millisecond,
+	}
+}
+
+func TestRetry(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int
+		err           error
+		expectedCalls int
+		expectedErr   bool
+	}{
+		{
+			name:          "success",
+			expectedCalls: 1,
+		},
+		{
+			name:          "recovers",
+			failures:      2,
+			err:           errUnavailable,
+			expectedCalls: 3,
+		},
+		{
+			name:          "exhausts retries",
+			failures:      10,
+			err:           errUnavailable,
+			expectedCalls: 4,
+			expectedErr:   true,
+		},
+		{
+			name:          "not retryable",
+			failures:      10,
+			err:           backend.ErrMetaDoesNotExist,
+			expectedCalls: 1,
+			expectedErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := &failingBackend{failures: tt.failures, err: tt.err}
+			rw := newTestRetry(next, testConfig())
+
+			b, err := rw.Read(context.Background(), "name", uuid.New(), "tenant")
+			assert.Equal(t, tt.expectedCalls, next.calls)
+			if tt.expectedErr {
+				assert.Equal(t, tt.err, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("data"), b)
+		})
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	cfg := testConfig()
+	cfg.MaxRetries = 100
+	cfg.MinBackoff = 20 * time.Millisecond
+	cfg.MaxBackoff = 20 * time.Millisecond
+	cfg.MaxRetryDuration = 50 * time.Millisecond
+
+	next := &failingBackend{failures: 100, err: errUnavailable}
+	rw := newTestRetry(next, cfg)
+
+	_, err := rw.Read(context.Background(), "name", uuid.New(), "tenant")
+	assert.Equal(t, errUnavailable, err)
+	assert.GreaterOrEqual(t, next.calls, 2)
+	assert.LessOrEqual(t, next.calls, 3)
+}
+
+func TestRetryCanceled(t *testing.T) {
+	cfg := testConfig()
+	cfg.MinBackoff = time.Hour
+	cfg.MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	next := &failingBackend{failures: 100, err: errUnavailable}
+	rw := newTestRetry(next, cfg)
+
+	_, err := rw.Read(ctx, "name", uuid.New(), "tenant")
+	assert.Equal(t, errUnavailable, err)
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestRetryWriteReader(t *testing.T) {
+	// seekable readers are rewound before retrying
+	next := &failingBackend{failures: 1, err: errUnavailable}
+	rw := newTestRetry(next, testConfig())
+
+	err := rw.WriteReader(context.Background(), "name", uuid.New(), "tenant", bytes.NewReader([]byte("data")), 4)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("data"), []byte("data")}, next.written)
+
+	// other readers are not retried
+	next = &failingBackend{failures: 1, err: errUnavailable}
+	rw = newTestRetry(next, testConfig())
+
+	err = rw.WriteReader(context.Background(), "name", uuid.New(), "tenant", bytes.NewBufferString("data"), 4)
+	assert.Equal(t, errUnavailable, err)
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestRetryAppendPassthrough(t *testing.T) {
+	next := &failingBackend{failures: 1, err: errUnavailable}
+	rw := newTestRetry(next, testConfig())
+
+	_, err := rw.Append(context.Background(), "name", uuid.New(), "tenant", nil, []byte("data"))
+	assert.Equal(t, errUnavailable, err)
+	assert.Equal(t, 1, next.calls)
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		err       error
+		retryable bool
+	}{
+		{nil, false},
+		{backend.ErrMetaDoesNotExist, false},
+		{context.Canceled, false},
+		{fmt.Errorf("unknown"), false},
+		{io.ErrUnexpectedEOF, true},
+		{minio.ErrorResponse{StatusCode: http.StatusNotFound}, false},
+		{minio.ErrorResponse{StatusCode: http.StatusInternalServerError}, true},
+		{errors.Wrap(minio.ErrorResponse{StatusCode: http.StatusTooManyRequests}, "wrapped"), true},
+		{&googleapi.Error{Code: http.StatusForbidden}, false},
+		{&googleapi.Error{Code: http.StatusBadGateway}, true},
+		{swift.ObjectNotFound, false},
+		{&swift.Error{StatusCode: http.StatusServiceUnavailable}, true},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.retryable, IsRetryable(tt.err), "%v", tt.err)
+	}
+}
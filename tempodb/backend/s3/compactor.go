@@ -9,10 +9,17 @@ import (
 	"github.com/go-kit/kit/log/level"
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/tempodb/backend"
-	"github.com/grafana/tempo/tempodb/backend/util"
 	"github.com/pkg/errors"
 )
 
+const (
+	headerStorageClass = "X-Amz-Storage-Class"
+
+	// maxCopyObjectSize is the largest object s3 copies in a single request
+	maxCopyObjectSize int64 = 5 * 1024 * 1024 * 1024
+	copyPartSize      int64 = 1024 * 1024 * 1024
+)
+
 func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error {
 	if len(tenantID) == 0 {
 		return backend.ErrEmptyTenantID
@@ -21,15 +28,15 @@ func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) e
 		return backend.ErrEmptyBlockID
 	}
 
-	metaFileName := util.MetaFileName(blockID, tenantID)
+	metaFileName := rw.metaFileName(blockID, tenantID)
 	// copy meta.json to meta.compacted.json
 	_, err := rw.core.CopyObject(
 		context.TODO(),
 		rw.cfg.Bucket,
 		metaFileName,
 		rw.cfg.Bucket,
-		util.CompactedMetaFileName(blockID, tenantID),
-		nil,
+		rw.compactedMetaFileName(blockID, tenantID),
+		sseHeaders(rw.cfg, tenantID),
 	)
 	if err != nil {
 		return errors.Wrap(err, "error copying obj meta to compacted obj meta")
@@ -47,7 +54,7 @@ func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
 		return backend.ErrEmptyBlockID
 	}
 
-	path := util.RootPath(blockID, tenantID) + "/"
+	path := rw.rootPath(blockID, tenantID) + "/"
 	level.Debug(rw.logger).Log("msg", "deleting block", "block path", path)
 
 	// ListObjects(bucket, prefix, marker, delimiter string, maxKeys int)
@@ -64,9 +71,68 @@ func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
 		}
 	}
 
+	rw.clearLayout(blockID, tenantID)
 	return nil
 }
 
+// ArchiveObject implements backend.Compactor.  The object is copied onto itself with the new storage class.  Objects
+//  larger than the single copy limit of s3 are copied in parts.
+func (rw *readerWriter) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	if len(tenantID) == 0 {
+		return backend.ErrEmptyTenantID
+	}
+	if blockID == uuid.Nil {
+		return backend.ErrEmptyBlockID
+	}
+
+	ctx := context.TODO()
+	objectName := rw.objectFileName(blockID, tenantID, name)
+
+	info, err := rw.core.StatObject(ctx, rw.cfg.Bucket, objectName, minio.StatObjectOptions{})
+	if err != nil {
+		return errors.Wrapf(err, "error reading object info %s", objectName)
+	}
+	if info.StorageClass == storageClass {
+		return nil
+	}
+
+	if info.Size <= maxCopyObjectSize {
+		headers := map[string]string{headerStorageClass: storageClass}
+		for k, v := range sseHeaders(rw.cfg, tenantID) {
+			headers[k] = v
+		}
+
+		_, err = rw.core.CopyObject(ctx, rw.cfg.Bucket, objectName, rw.cfg.Bucket, objectName, headers)
+		return errors.Wrapf(err, "error archiving object %s", objectName)
+	}
+
+	uploadID, err := rw.core.NewMultipartUpload(ctx, rw.cfg.Bucket, objectName, minio.PutObjectOptions{
+		StorageClass:         storageClass,
+		ServerSideEncryption: serverSideEncryption(rw.cfg, tenantID),
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error starting multipart copy of object %s", objectName)
+	}
+
+	var parts []minio.CompletePart
+	for offset, partID := int64(0), 1; offset < info.Size; offset, partID = offset+copyPartSize, partID+1 {
+		length := copyPartSize
+		if info.Size-offset < length {
+			length = info.Size - offset
+		}
+
+		part, err := rw.core.CopyObjectPart(ctx, rw.cfg.Bucket, objectName, rw.cfg.Bucket, objectName, uploadID, partID, offset, length, nil)
+		if err != nil {
+			_ = rw.core.AbortMultipartUpload(ctx, rw.cfg.Bucket, objectName, uploadID)
+			return errors.Wrapf(err, "error copying part %d of object %s", partID, objectName)
+		}
+		parts = append(parts, part)
+	}
+
+	_, err = rw.core.CompleteMultipartUpload(ctx, rw.cfg.Bucket, objectName, uploadID, parts)
+	return errors.Wrapf(err, "error completing multipart copy of object %s", objectName)
+}
+
 func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
 	if len(tenantID) == 0 {
 		return nil, backend.ErrEmptyTenantID
@@ -75,7 +141,7 @@ func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (
 		return nil, backend.ErrEmptyBlockID
 	}
 
-	compactedMetaFileName := util.CompactedMetaFileName(blockID, tenantID)
+	compactedMetaFileName := rw.compactedMetaFileName(blockID, tenantID)
 	bytes, info, err := rw.readAllWithObjInfo(context.TODO(), compactedMetaFileName)
 	if err != nil && err == backend.ErrMetaDoesNotExist {
 		return nil, backend.ErrMetaDoesNotExist
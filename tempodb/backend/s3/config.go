@@ -13,4 +13,18 @@ type Config struct {
 	// SignatureV2 configures the object storage to use V2 signing instead of V4
 	SignatureV2    bool `yaml:"signature_v2"`
 	ForcePathStyle bool `yaml:"forcepathstyle"`
+	// SSEKMSKeyID enables SSE-KMS encryption with the given key.  SSEKMSTenantKeyIDs overrides the key per tenant.
+	SSEKMSKeyID         string            `yaml:"sse_kms_key_id"`
+	SSEKMSTenantKeyIDs  map[string]string `yaml:"sse_kms_tenant_key_ids"`
+	SSEBucketKeyEnabled bool              `yaml:"sse_bucket_key_enabled"`
+	// PrefixSharding writes new blocks under a hashed prefix of the block id to spread requests over more key
+	//  prefixes.  Blocks in either layout are always read.
+	PrefixSharding bool `yaml:"prefix_sharding"`
+	// RequesterPays bills requests to the requester for buckets with requester pays enabled
+	RequesterPays bool `yaml:"requester_pays"`
+	// ObjectTags are added to all written objects.  TenantObjectTags adds or overrides tags per tenant.
+	ObjectTags       map[string]string            `yaml:"object_tags"`
+	TenantObjectTags map[string]map[string]string `yaml:"tenant_object_tags"`
+	// ObjectLock sends the checksums required to write to buckets with object lock enabled
+	ObjectLock bool `yaml:"object_lock"`
 }
@@ -0,0 +1,119 @@
+package s3
+
+import (
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/tempo/tempodb/backend/util"
+)
+
+const (
+	metaFileName          = "meta.json"
+	compactedMetaFileName = "meta.compacted.json"
+)
+
+// rootPath returns the root path of a block.  Blocks are either stored at <tenant>/<blockID> or, with prefix
+//  sharding, at <tenant>/<shard>/<blockID>.  The layout of blocks seen while listing is remembered so both
+//  layouts can be read regardless of the configured one.  New blocks are written in the configured layout.
+func (rw *readerWriter) rootPath(blockID uuid.UUID, tenantID string) string {
+	rw.layoutsMtx.Lock()
+	sharded, ok := rw.layouts[tenantID][blockID]
+	rw.layoutsMtx.Unlock()
+
+	if !ok {
+		sharded = rw.cfg.PrefixSharding
+	}
+	return rootPathForLayout(blockID, tenantID, sharded)
+}
+
+func (rw *readerWriter) objectFileName(blockID uuid.UUID, tenantID string, name string) string {
+	return path.Join(rw.rootPath(blockID, tenantID), name)
+}
+
+func (rw *readerWriter) metaFileName(blockID uuid.UUID, tenantID string) string {
+	return rw.objectFileName(blockID, tenantID, metaFileName)
+}
+
+func (rw *readerWriter) compactedMetaFileName(blockID uuid.UUID, tenantID string) string {
+	return rw.objectFileName(blockID, tenantID, compactedMetaFileName)
+}
+
+func (rw *readerWriter) setLayout(blockID uuid.UUID, tenantID string, sharded bool) {
+	rw.layoutsMtx.Lock()
+	defer rw.layoutsMtx.Unlock()
+
+	if rw.layouts == nil {
+		rw.layouts = map[string]map[uuid.UUID]bool{}
+	}
+	if rw.layouts[tenantID] == nil {
+		rw.layouts[tenantID] = map[uuid.UUID]bool{}
+	}
+	rw.layouts[tenantID][blockID] = sharded
+}
+
+// setLayouts replaces the layouts of a tenant with the layouts of all its listed blocks so the layouts of deleted
+//  blocks are not kept forever
+func (rw *readerWriter) setLayouts(tenantID string, layouts map[uuid.UUID]bool) {
+	rw.layoutsMtx.Lock()
+	defer rw.layoutsMtx.Unlock()
+
+	if rw.layouts == nil {
+		rw.layouts = map[string]map[uuid.UUID]bool{}
+	}
+	if len(layouts) == 0 {
+		delete(rw.layouts, tenantID)
+		return
+	}
+	rw.layouts[tenantID] = layouts
+}
+
+func (rw *readerWriter) clearLayout(blockID uuid.UUID, tenantID string) {
+	rw.layoutsMtx.Lock()
+	defer rw.layoutsMtx.Unlock()
+
+	delete(rw.layouts[tenantID], blockID)
+	if len(rw.layouts[tenantID]) == 0 {
+		delete(rw.layouts, tenantID)
+	}
+}
+
+func (rw *readerWriter) layoutKnown(blockID uuid.UUID, tenantID string) bool {
+	rw.layoutsMtx.Lock()
+	defer rw.layoutsMtx.Unlock()
+
+	_, ok := rw.layouts[tenantID][blockID]
+	return ok
+}
+
+func rootPathForLayout(blockID uuid.UUID, tenantID string, sharded bool) string {
+	if sharded {
+		return util.ShardedRootPath(blockID, tenantID)
+	}
+	return util.RootPath(blockID, tenantID)
+}
+
+// blocksFromPrefixes returns the ids of the blocks in the common prefixes listed under prefix and the names of
+//  the shard prefixes that have to be listed to find the blocks in the prefix sharded layout
+func blocksFromPrefixes(prefix string, prefixes []string) ([]uuid.UUID, []string, error) {
+	var blockIDs []uuid.UUID
+	var shards []string
+
+	for _, p := range prefixes {
+		name := strings.Split(strings.TrimPrefix(p, prefix), "/")[0]
+		if util.IsBlockShard(name) {
+			shards = append(shards, name)
+			continue
+		}
+
+		blockID, err := uuid.Parse(name)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "error parsing uuid of obj, objectName: %s", p)
+		}
+		blockIDs = append(blockIDs, blockID)
+	}
+
+	return blockIDs, shards, nil
+}
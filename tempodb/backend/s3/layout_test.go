@@ -0,0 +1,91 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/tempo/tempodb/backend/util"
+)
+
+func TestRootPath(t *testing.T) {
+	blockID := uuid.MustParse("b51766bd-a395-448b-9684-7a4fdbd053aa")
+	flat := "test/" + blockID.String()
+	sharded := "test/" + util.BlockShard(blockID) + "/" + blockID.String()
+
+	rw := &readerWriter{cfg: &Config{}}
+	assert.Equal(t, flat, rw.rootPath(blockID, "test"))
+	assert.Equal(t, flat+"/meta.json", rw.metaFileName(blockID, "test"))
+
+	rw = &readerWriter{cfg: &Config{PrefixSharding: true}}
+	assert.Equal(t, sharded, rw.rootPath(blockID, "test"))
+	assert.Equal(t, sharded+"/meta.compacted.json", rw.compactedMetaFileName(blockID, "test"))
+
+	// listed blocks are read in their own layout
+	rw.setLayout(blockID, "test", false)
+	assert.True(t, rw.layoutKnown(blockID, "test"))
+	assert.False(t, rw.layoutKnown(blockID, "other"))
+	assert.Equal(t, flat+"/data", rw.objectFileName(blockID, "test", "data"))
+
+	// cleared blocks are forgotten
+	rw.clearLayout(blockID, "test")
+	assert.False(t, rw.layoutKnown(blockID, "test"))
+	assert.Equal(t, sharded, rw.rootPath(blockID, "test"))
+	assert.Len(t, rw.layouts, 0)
+}
+
+func TestSetLayouts(t *testing.T) {
+	listed := uuid.New()
+	deleted := uuid.New()
+
+	rw := &readerWriter{cfg: &Config{}}
+	rw.setLayout(listed, "test", true)
+	rw.setLayout(deleted, "test", true)
+	rw.setLayout(deleted, "other", true)
+
+	// blocks that are no longer listed are forgotten
+	rw.setLayouts("test", map[uuid.UUID]bool{listed: true})
+	assert.True(t, rw.layoutKnown(listed, "test"))
+	assert.False(t, rw.layoutKnown(deleted, "test"))
+	assert.True(t, rw.layoutKnown(deleted, "other"))
+
+	rw.setLayouts("other", map[uuid.UUID]bool{})
+	assert.Len(t, rw.layouts, 1)
+}
+
+func TestBlockShard(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		shard := util.BlockShard(uuid.New())
+		assert.True(t, util.IsBlockShard(shard), shard)
+	}
+
+	assert.False(t, util.IsBlockShard("0"))
+	assert.False(t, util.IsBlockShard("AB"))
+	assert.False(t, util.IsBlockShard("g0"))
+	assert.False(t, util.IsBlockShard(uuid.New().String()))
+}
+
+func TestBlocksFromPrefixes(t *testing.T) {
+	flatID := uuid.New()
+	shardedID := uuid.New()
+	shard := util.BlockShard(shardedID)
+
+	blockIDs, shards, err := blocksFromPrefixes("test/", []string{
+		"test/" + flatID.String() + "/",
+		"test/" + shard + "/",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{flatID}, blockIDs)
+	assert.Equal(t, []string{shard}, shards)
+
+	blockIDs, shards, err = blocksFromPrefixes("test/"+shard+"/", []string{
+		"test/" + shard + "/" + shardedID.String() + "/",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{shardedID}, blockIDs)
+	assert.Len(t, shards, 0)
+
+	_, _, err = blocksFromPrefixes("test/", []string{"test/not-a-block/"})
+	assert.Error(t, err)
+}
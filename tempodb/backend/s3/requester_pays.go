@@ -0,0 +1,54 @@
+package s3
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
+)
+
+const (
+	headerRequestPayer = "X-Amz-Request-Payer"
+
+	signV4CredentialPrefix = "AWS4-HMAC-SHA256 Credential="
+)
+
+// requesterPaysTransport adds the requester pays header to every request.  minio-go can not add headers to all
+//  requests and s3 requires x-amz-* headers to be signed, so requests are signed again once the header is set.
+type requesterPaysTransport struct {
+	next  http.RoundTripper
+	creds *credentials.Credentials
+}
+
+func (t *requesterPaysTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set(headerRequestPayer, "requester")
+
+	// anonymous requests are not signed
+	if region, ok := signatureRegion(req.Header.Get("Authorization")); ok {
+		v, err := t.creds.Get()
+		if err != nil {
+			return nil, err
+		}
+		req = signer.SignV4(*req, v.AccessKeyID, v.SecretAccessKey, v.SessionToken, region)
+	}
+
+	return t.next.RoundTrip(req)
+}
+
+// signatureRegion returns the region of the credential scope of a v4 authorization header.  The scope has the
+//  form <access key>/<date>/<region>/s3/aws4_request.
+func signatureRegion(auth string) (string, bool) {
+	if !strings.HasPrefix(auth, signV4CredentialPrefix) {
+		return "", false
+	}
+
+	scope := strings.SplitN(strings.TrimPrefix(auth, signV4CredentialPrefix), ",", 2)[0]
+	parts := strings.Split(scope, "/")
+	if len(parts) != 5 {
+		return "", false
+	}
+
+	return parts[2], true
+}
@@ -0,0 +1,64 @@
+package s3
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/minio/minio-go/v7/pkg/signer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequesterPaysTransport(t *testing.T) {
+	var received http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+	}))
+	defer server.Close()
+
+	transport := &requesterPaysTransport{
+		next:  http.DefaultTransport,
+		creds: credentials.NewStaticV4("key", "secret", ""),
+	}
+
+	// signed requests are signed again including the header
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/bucket/object", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	req = signer.SignV4(*req, "key", "secret", "", "eu-west-1")
+	original := req.Header.Get("Authorization")
+
+	resp, err := transport.RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "requester", received.Get(headerRequestPayer))
+	assert.Contains(t, received.Get("Authorization"), "x-amz-request-payer")
+	assert.Contains(t, received.Get("Authorization"), "/eu-west-1/s3/aws4_request")
+	assert.Equal(t, original, req.Header.Get("Authorization"), "the passed request should not be modified")
+
+	// anonymous requests only get the header
+	req, err = http.NewRequest(http.MethodGet, server.URL+"/bucket/object", nil)
+	require.NoError(t, err)
+
+	resp, err = transport.RoundTrip(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	assert.Equal(t, "requester", received.Get(headerRequestPayer))
+	assert.Empty(t, received.Get("Authorization"))
+}
+
+func TestSignatureRegion(t *testing.T) {
+	region, ok := signatureRegion("AWS4-HMAC-SHA256 Credential=key/20210301/us-east-2/s3/aws4_request, SignedHeaders=host, Signature=abc")
+	assert.True(t, ok)
+	assert.Equal(t, "us-east-2", region)
+
+	_, ok = signatureRegion("AWS key:signature")
+	assert.False(t, ok)
+
+	_, ok = signatureRegion("AWS4-HMAC-SHA256 Credential=key/us-east-2, SignedHeaders=host, Signature=abc")
+	assert.False(t, ok)
+}
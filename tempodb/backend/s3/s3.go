@@ -3,19 +3,22 @@ package s3
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"path"
 	"strings"
+	"sync"
 
 	log_util "github.com/cortexproject/cortex/pkg/util/log"
 	"github.com/go-kit/kit/log"
 	"github.com/go-kit/kit/log/level"
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/tempodb/backend"
-	"github.com/grafana/tempo/tempodb/backend/util"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/opentracing/opentracing-go"
@@ -31,6 +34,10 @@ type readerWriter struct {
 	logger log.Logger
 	cfg    *Config
 	core   *minio.Core
+
+	// layouts records per tenant if a block is stored in the prefix sharded layout
+	layouts    map[string]map[uuid.UUID]bool
+	layoutsMtx sync.Mutex
 }
 
 // appendTracker is a struct used to track multipart uploads
@@ -66,6 +73,16 @@ func (s *overrideSignatureVersion) IsExpired() bool {
 func New(cfg *Config) (backend.Reader, backend.Writer, backend.Compactor, error) {
 	l := log_util.Logger
 
+	err := validateObjectTags(cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// requests are signed again by the requester pays transport which only supports v4 non streaming signatures
+	if cfg.RequesterPays && (cfg.SignatureV2 || cfg.Insecure) {
+		return nil, nil, nil, fmt.Errorf("requester_pays can not be used with signature_v2 or insecure")
+	}
+
 	wrapCredentialsProvider := func(p credentials.Provider) credentials.Provider {
 		if cfg.SignatureV2 {
 			return &overrideSignatureVersion{useV2: cfg.SignatureV2, upstream: p}
@@ -101,6 +118,17 @@ func New(cfg *Config) (backend.Reader, backend.Writer, backend.Compactor, error)
 		opts.BucketLookup = minio.BucketLookupPath
 	}
 
+	if cfg.RequesterPays {
+		transport, err := minio.DefaultTransport(!cfg.Insecure)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		opts.Transport = &requesterPaysTransport{
+			next:  transport,
+			creds: creds,
+		}
+	}
+
 	core, err := minio.NewCore(cfg.Endpoint, opts)
 	if err != nil {
 		return nil, nil, nil, err
@@ -130,7 +158,7 @@ func (rw *readerWriter) Write(ctx context.Context, name string, blockID uuid.UUI
 
 // WriteReader implements backend.Writer
 func (rw *readerWriter) WriteReader(ctx context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, size int64) error {
-	objName := util.ObjectFileName(blockID, tenantID, name)
+	objName := rw.objectFileName(blockID, tenantID, name)
 
 	info, err := rw.core.Client.PutObject(
 		ctx,
@@ -138,7 +166,12 @@ func (rw *readerWriter) WriteReader(ctx context.Context, name string, blockID uu
 		objName,
 		data,
 		size,
-		minio.PutObjectOptions{PartSize: rw.cfg.PartSize},
+		minio.PutObjectOptions{
+			PartSize:             rw.cfg.PartSize,
+			ServerSideEncryption: serverSideEncryption(rw.cfg, tenantID),
+			SendContentMd5:       rw.cfg.ObjectLock,
+			UserTags:             objectTags(rw.cfg, tenantID, backend.BlockMetaFromContext(ctx)),
+		},
 	)
 	if err != nil {
 		return errors.Wrapf(err, "error writing object to s3 backend, object %s", objName)
@@ -153,7 +186,10 @@ func (rw *readerWriter) WriteBlockMeta(ctx context.Context, meta *backend.BlockM
 	blockID := meta.BlockID
 	tenantID := meta.TenantID
 	options := minio.PutObjectOptions{
-		PartSize: rw.cfg.PartSize,
+		PartSize:             rw.cfg.PartSize,
+		ServerSideEncryption: serverSideEncryption(rw.cfg, tenantID),
+		SendContentMd5:       rw.cfg.ObjectLock,
+		UserTags:             objectTags(rw.cfg, tenantID, meta),
 	}
 
 	bMeta, err := json.Marshal(meta)
@@ -164,7 +200,7 @@ func (rw *readerWriter) WriteBlockMeta(ctx context.Context, meta *backend.BlockM
 	info, err := rw.core.Client.PutObject(
 		ctx,
 		rw.cfg.Bucket,
-		util.MetaFileName(blockID, tenantID),
+		rw.metaFileName(blockID, tenantID),
 		bytes.NewReader(bMeta),
 		int64(len(bMeta)),
 		options,
@@ -180,10 +216,13 @@ func (rw *readerWriter) WriteBlockMeta(ctx context.Context, meta *backend.BlockM
 // AppendObject implements backend.Writer
 func (rw *readerWriter) Append(ctx context.Context, name string, blockID uuid.UUID, tenantID string, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
 	var a appendTracker
-	objectName := util.ObjectFileName(blockID, tenantID, name)
+	objectName := rw.objectFileName(blockID, tenantID, name)
 
 	options := minio.PutObjectOptions{
-		PartSize: rw.cfg.PartSize,
+		PartSize:             rw.cfg.PartSize,
+		ServerSideEncryption: serverSideEncryption(rw.cfg, tenantID),
+		SendContentMd5:       rw.cfg.ObjectLock,
+		UserTags:             objectTags(rw.cfg, tenantID, backend.BlockMetaFromContext(ctx)),
 	}
 	if tracker != nil {
 		a = tracker.(appendTracker)
@@ -203,6 +242,13 @@ func (rw *readerWriter) Append(ctx context.Context, name string, blockID uuid.UU
 
 	level.Debug(rw.logger).Log("msg", "appending object to s3", "objectName", objectName)
 
+	// buckets with object lock enabled require the md5 of every part
+	var md5Base64 string
+	if rw.cfg.ObjectLock {
+		sum := md5.Sum(buffer)
+		md5Base64 = base64.StdEncoding.EncodeToString(sum[:])
+	}
+
 	a.partNum++
 	objPart, err := rw.core.PutObjectPart(
 		ctx,
@@ -212,7 +258,7 @@ func (rw *readerWriter) Append(ctx context.Context, name string, blockID uuid.UU
 		a.partNum,
 		bytes.NewReader(buffer),
 		int64(len(buffer)),
-		"",
+		md5Base64,
 		"",
 		nil,
 	)
@@ -269,44 +315,67 @@ func (rw *readerWriter) Tenants(ctx context.Context) ([]string, error) {
 	return tenants, nil
 }
 
-// Blocks implements backend.Reader
+// Blocks implements backend.Reader.  Blocks in both the flat and the prefix sharded layout are listed.  Shard
+//  prefixes are only listed if the tenant has any.
 func (rw *readerWriter) Blocks(ctx context.Context, tenantID string) ([]uuid.UUID, error) {
 	prefix := tenantID + "/"
-	var blockIDs []uuid.UUID
+	layouts := map[uuid.UUID]bool{}
 
-	nextMarker := ""
-	isTruncated := true
-	for isTruncated {
-		// ListObjects(bucket, prefix, nextMarker, delimiter string, maxKeys int)
-		res, err := rw.core.ListObjects(rw.cfg.Bucket, prefix, nextMarker, "/", 0)
+	prefixes, err := rw.listPrefixes(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	blockIDs, shards, err := blocksFromPrefixes(prefix, prefixes)
+	if err != nil {
+		return nil, err
+	}
+	for _, blockID := range blockIDs {
+		layouts[blockID] = false
+	}
+
+	for _, shard := range shards {
+		shardPrefix := prefix + shard + "/"
+		prefixes, err := rw.listPrefixes(shardPrefix)
 		if err != nil {
-			return nil, errors.Wrapf(err, "error listing blocks in s3 bucket, bucket: %s", rw.cfg.Bucket)
+			return nil, err
 		}
-		isTruncated = res.IsTruncated
-		nextMarker = res.NextMarker
 
-		level.Debug(rw.logger).Log("msg", "listing blocks", "tenantID", tenantID,
-			"found", len(res.CommonPrefixes), "IsTruncated", res.IsTruncated, "NextMarker", res.NextMarker)
-
-		for _, cp := range res.CommonPrefixes {
-			blockID, err := uuid.Parse(strings.Split(strings.TrimPrefix(cp.Prefix, prefix), "/")[0])
-			if err != nil {
-				return nil, errors.Wrapf(err, "error parsing uuid of obj, objectName: %s", cp.Prefix)
-			}
-			blockIDs = append(blockIDs, blockID)
+		shardBlockIDs, _, err := blocksFromPrefixes(shardPrefix, prefixes)
+		if err != nil {
+			return nil, err
 		}
+		for _, blockID := range shardBlockIDs {
+			layouts[blockID] = true
+		}
+		blockIDs = append(blockIDs, shardBlockIDs...)
 	}
 
+	level.Debug(rw.logger).Log("msg", "listing blocks", "tenantID", tenantID, "found", len(blockIDs), "shards", len(shards))
+
+	// blocks that are no longer listed are forgotten
+	rw.setLayouts(tenantID, layouts)
 	return blockIDs, nil
 }
 
 // BlockMeta implements backend.Reader
 func (rw *readerWriter) BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*backend.BlockMeta, error) {
-	blockMetaFileName := util.MetaFileName(blockID, tenantID)
-	body, err := rw.readAll(ctx, blockMetaFileName)
+	body, err := rw.readAll(ctx, rw.metaFileName(blockID, tenantID))
+	if err != nil && err.Error() == s3KeyDoesNotExist && !rw.layoutKnown(blockID, tenantID) {
+		// a block that has not been listed may be stored in the other layout
+		sharded := !rw.cfg.PrefixSharding
+		body, err = rw.readAll(ctx, path.Join(rootPathForLayout(blockID, tenantID, sharded), metaFileName))
+		if err == nil {
+			rw.setLayout(blockID, tenantID, sharded)
+		}
+	}
 	if err != nil && err.Error() == s3KeyDoesNotExist {
 		return nil, backend.ErrMetaDoesNotExist
 	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading block meta from s3")
+	}
+
 	out := &backend.BlockMeta{}
 	err = json.Unmarshal(body, out)
 	if err != nil {
@@ -321,7 +390,11 @@ func (rw *readerWriter) Read(ctx context.Context, name string, blockID uuid.UUID
 	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "Read")
 	defer span.Finish()
 
-	return rw.readAll(derivedCtx, util.ObjectFileName(blockID, tenantID, name))
+	bytes, err := rw.readAll(derivedCtx, rw.objectFileName(blockID, tenantID, name))
+	if err != nil && err.Error() == s3KeyDoesNotExist {
+		return nil, backend.ErrDoesNotExist
+	}
+	return bytes, err
 }
 
 // ReadRange implements backend.Reader
@@ -329,13 +402,40 @@ func (rw *readerWriter) ReadRange(ctx context.Context, name string, blockID uuid
 	span, derivedCtx := opentracing.StartSpanFromContext(ctx, "ReadRange")
 	defer span.Finish()
 
-	return rw.readRange(derivedCtx, util.ObjectFileName(blockID, tenantID, name), int64(offset), buffer)
+	err := rw.readRange(derivedCtx, rw.objectFileName(blockID, tenantID, name), int64(offset), buffer)
+	if err != nil && errors.Cause(err).Error() == s3KeyDoesNotExist {
+		return backend.ErrDoesNotExist
+	}
+	return err
 }
 
 // Shutdown implements backend.Reader
 func (rw *readerWriter) Shutdown() {
 }
 
+// listPrefixes returns all common prefixes under the passed prefix
+func (rw *readerWriter) listPrefixes(prefix string) ([]string, error) {
+	var prefixes []string
+
+	nextMarker := ""
+	isTruncated := true
+	for isTruncated {
+		// ListObjects(bucket, prefix, nextMarker, delimiter string, maxKeys int)
+		res, err := rw.core.ListObjects(rw.cfg.Bucket, prefix, nextMarker, "/", 0)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error listing blocks in s3 bucket, bucket: %s", rw.cfg.Bucket)
+		}
+		isTruncated = res.IsTruncated
+		nextMarker = res.NextMarker
+
+		for _, cp := range res.CommonPrefixes {
+			prefixes = append(prefixes, cp.Prefix)
+		}
+	}
+
+	return prefixes, nil
+}
+
 func (rw *readerWriter) readAll(ctx context.Context, name string) ([]byte, error) {
 	reader, _, _, err := rw.core.GetObject(ctx, rw.cfg.Bucket, name, minio.GetObjectOptions{})
 	if err != nil {
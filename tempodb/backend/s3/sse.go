@@ -0,0 +1,69 @@
+package s3
+
+import (
+	"net/http"
+
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+const (
+	headerSSE                 = "X-Amz-Server-Side-Encryption"
+	headerSSEKMSKeyID         = "X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"
+	headerSSEBucketKeyEnabled = "X-Amz-Server-Side-Encryption-Bucket-Key-Enabled"
+)
+
+// sseKMS is SSE-KMS server side encryption.  It is used instead of encrypt.NewSSEKMS so the bucket key header
+//  can be set.  minio-go would send it as user metadata if passed through PutObjectOptions.
+type sseKMS struct {
+	keyID            string
+	bucketKeyEnabled bool
+}
+
+func (s sseKMS) Type() encrypt.Type {
+	return encrypt.KMS
+}
+
+func (s sseKMS) Marshal(h http.Header) {
+	h.Set(headerSSE, "aws:kms")
+	if s.keyID != "" {
+		h.Set(headerSSEKMSKeyID, s.keyID)
+	}
+	if s.bucketKeyEnabled {
+		h.Set(headerSSEBucketKeyEnabled, "true")
+	}
+}
+
+// serverSideEncryption returns the encryption to use for objects of the passed tenant or nil if
+//  objects should be written with the bucket default
+func serverSideEncryption(cfg *Config, tenantID string) encrypt.ServerSide {
+	keyID := cfg.SSEKMSKeyID
+	if tenantKeyID, ok := cfg.SSEKMSTenantKeyIDs[tenantID]; ok {
+		keyID = tenantKeyID
+	}
+
+	if keyID == "" {
+		return nil
+	}
+
+	return sseKMS{
+		keyID:            keyID,
+		bucketKeyEnabled: cfg.SSEBucketKeyEnabled,
+	}
+}
+
+// sseHeaders returns the encryption headers for the passed tenant as a map for calls that take raw headers
+func sseHeaders(cfg *Config, tenantID string) map[string]string {
+	sse := serverSideEncryption(cfg, tenantID)
+	if sse == nil {
+		return nil
+	}
+
+	h := http.Header{}
+	sse.Marshal(h)
+
+	headers := map[string]string{}
+	for k := range h {
+		headers[k] = h.Get(k)
+	}
+	return headers
+}
@@ -0,0 +1,61 @@
+package s3
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSEHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      *Config
+		tenantID string
+		expected map[string]string
+	}{
+		{
+			name:     "disabled",
+			cfg:      &Config{},
+			tenantID: "test",
+		},
+		{
+			name:     "key",
+			cfg:      &Config{SSEKMSKeyID: "key"},
+			tenantID: "test",
+			expected: map[string]string{
+				headerSSE:         "aws:kms",
+				headerSSEKMSKeyID: "key",
+			},
+		},
+		{
+			name:     "bucket key",
+			cfg:      &Config{SSEKMSKeyID: "key", SSEBucketKeyEnabled: true},
+			tenantID: "test",
+			expected: map[string]string{
+				headerSSE:                 "aws:kms",
+				headerSSEKMSKeyID:         "key",
+				headerSSEBucketKeyEnabled: "true",
+			},
+		},
+		{
+			name:     "tenant key",
+			cfg:      &Config{SSEKMSKeyID: "key", SSEKMSTenantKeyIDs: map[string]string{"test": "tenant-key"}},
+			tenantID: "test",
+			expected: map[string]string{
+				headerSSE:         "aws:kms",
+				headerSSEKMSKeyID: "tenant-key",
+			},
+		},
+		{
+			name:     "tenant key only",
+			cfg:      &Config{SSEKMSTenantKeyIDs: map[string]string{"test": "tenant-key"}},
+			tenantID: "other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, sseHeaders(tt.cfg, tt.tenantID))
+		})
+	}
+}
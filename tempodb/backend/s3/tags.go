@@ -0,0 +1,100 @@
+package s3
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const (
+	// maxObjectTags is the maximum number of tags s3 allows per object
+	maxObjectTags = 10
+	// maxTagKeyLength and maxTagValueLength are the maximum lengths of tag keys and values s3 allows
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// invalidTagCharRegexp matches the characters s3 does not allow in tag keys and values
+var invalidTagCharRegexp = regexp.MustCompile(`[^A-Za-z0-9 +\-=._:/@]`)
+
+// objectTags returns the tags of objects written for the passed tenant and block.  ${tenant} and
+//  ${compaction_level} in tag values are replaced with the tenant and the compaction level of the block.  Characters
+//  of the tenant that s3 does not allow in tags are replaced with _ and values are cut to the length s3 allows.
+func objectTags(cfg *Config, tenantID string, meta *backend.BlockMeta) map[string]string {
+	tenantTags := cfg.TenantObjectTags[tenantID]
+	if len(cfg.ObjectTags) == 0 && len(tenantTags) == 0 {
+		return nil
+	}
+
+	level := ""
+	if meta != nil {
+		level = strconv.Itoa(int(meta.CompactionLevel))
+	}
+	replacer := strings.NewReplacer("${tenant}", invalidTagCharRegexp.ReplaceAllString(tenantID, "_"), "${compaction_level}", level)
+
+	tags := make(map[string]string, len(cfg.ObjectTags)+len(tenantTags))
+	for k, v := range cfg.ObjectTags {
+		tags[k] = truncateTagValue(replacer.Replace(v))
+	}
+	for k, v := range tenantTags {
+		tags[k] = truncateTagValue(replacer.Replace(v))
+	}
+	return tags
+}
+
+func truncateTagValue(v string) string {
+	if len(v) > maxTagValueLength {
+		return v[:maxTagValueLength]
+	}
+	return v
+}
+
+// validateObjectTags confirms all configured tags are valid s3 tags and no tenant ends up with more tags than s3
+//  allows
+func validateObjectTags(cfg *Config) error {
+	if len(cfg.ObjectTags) > maxObjectTags {
+		return fmt.Errorf("object_tags has %d tags, s3 allows at most %d", len(cfg.ObjectTags), maxObjectTags)
+	}
+	if err := validateTags(cfg.ObjectTags); err != nil {
+		return fmt.Errorf("invalid object_tags: %w", err)
+	}
+
+	for tenantID, tags := range cfg.TenantObjectTags {
+		if err := validateTags(tags); err != nil {
+			return fmt.Errorf("invalid object tags of tenant %s: %w", tenantID, err)
+		}
+		if n := len(objectTags(cfg, tenantID, nil)); n > maxObjectTags {
+			return fmt.Errorf("tenant %s has %d object tags, s3 allows at most %d", tenantID, n, maxObjectTags)
+		}
+	}
+
+	return nil
+}
+
+// validateTags checks keys and values against the characters and lengths s3 allows.  Placeholders are checked as
+//  if they were empty since their values are made valid when they are replaced.
+func validateTags(tags map[string]string) error {
+	placeholders := strings.NewReplacer("${tenant}", "", "${compaction_level}", "")
+
+	for k, v := range tags {
+		if len(k) == 0 || len(k) > maxTagKeyLength {
+			return fmt.Errorf("tag key %q must be 1 to %d characters long", k, maxTagKeyLength)
+		}
+		if invalidTagCharRegexp.MatchString(k) {
+			return fmt.Errorf("tag key %q contains characters s3 does not allow", k)
+		}
+
+		v = placeholders.Replace(v)
+		if len(v) > maxTagValueLength {
+			return fmt.Errorf("value of tag %q must be at most %d characters long", k, maxTagValueLength)
+		}
+		if invalidTagCharRegexp.MatchString(v) {
+			return fmt.Errorf("value of tag %q contains characters s3 does not allow", k)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package s3
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+func TestObjectTags(t *testing.T) {
+	cfg := &Config{
+		ObjectTags: map[string]string{
+			"tenant": "${tenant}",
+			"level":  "l${compaction_level}",
+			"team":   "tracing",
+		},
+		TenantObjectTags: map[string]map[string]string{
+			"audit": {
+				"retention": "long",
+				"team":      "compliance",
+			},
+		},
+	}
+	meta := &backend.BlockMeta{CompactionLevel: 2}
+
+	assert.Nil(t, objectTags(&Config{}, "test", meta))
+	assert.Equal(t, map[string]string{
+		"tenant": "test",
+		"level":  "l2",
+		"team":   "tracing",
+	}, objectTags(cfg, "test", meta))
+
+	// tenant tags override and extend the defaults, the compaction level is empty without a block
+	assert.Equal(t, map[string]string{
+		"tenant":    "audit",
+		"level":     "l",
+		"team":      "compliance",
+		"retention": "long",
+	}, objectTags(cfg, "audit", nil))
+
+	// characters s3 does not allow are replaced and long values are cut
+	assert.Equal(t, "a_b_c", objectTags(cfg, "a|b#c", meta)["tenant"])
+	assert.Len(t, objectTags(cfg, strings.Repeat("t", 300), meta)["tenant"], maxTagValueLength)
+}
+
+func TestValidateObjectTags(t *testing.T) {
+	tags := map[string]string{}
+	for i := 0; i < maxObjectTags; i++ {
+		tags[fmt.Sprintf("tag-%d", i)] = "value"
+	}
+
+	cfg := &Config{ObjectTags: tags}
+	assert.NoError(t, validateObjectTags(cfg))
+
+	cfg.TenantObjectTags = map[string]map[string]string{"test": {"tag-0": "override"}}
+	assert.NoError(t, validateObjectTags(cfg))
+
+	cfg.TenantObjectTags["test"]["one-too-many"] = "value"
+	assert.Error(t, validateObjectTags(cfg))
+}
+
+func TestValidateObjectTagsContent(t *testing.T) {
+	tests := []struct {
+		name  string
+		tags  map[string]string
+		valid bool
+	}{
+		{
+			name:  "valid",
+			tags:  map[string]string{"team": "tracing", "path": "a/b:c@d+e=f g_h.i-j"},
+			valid: true,
+		},
+		{
+			name:  "placeholders",
+			tags:  map[string]string{"tenant": "${tenant}", "level": "l${compaction_level}"},
+			valid: true,
+		},
+		{
+			name: "empty key",
+			tags: map[string]string{"": "value"},
+		},
+		{
+			name: "long key",
+			tags: map[string]string{strings.Repeat("k", maxTagKeyLength+1): "value"},
+		},
+		{
+			name: "invalid key",
+			tags: map[string]string{"team!": "value"},
+		},
+		{
+			name: "long value",
+			tags: map[string]string{"team": strings.Repeat("v", maxTagValueLength+1)},
+		},
+		{
+			name: "invalid value",
+			tags: map[string]string{"team": "a,b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateObjectTags(&Config{ObjectTags: tt.tags})
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+
+			err = validateObjectTags(&Config{TenantObjectTags: map[string]map[string]string{"test": tt.tags}})
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package swift
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/ncw/swift"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/util"
+)
+
+func (rw *readerWriter) MarkBlockCompacted(blockID uuid.UUID, tenantID string) error {
+	if len(tenantID) == 0 {
+		return backend.ErrEmptyTenantID
+	}
+	if blockID == uuid.Nil {
+		return backend.ErrEmptyBlockID
+	}
+
+	// copy meta.json to meta.compacted.json
+	metaFileName := util.MetaFileName(blockID, tenantID)
+	_, err := rw.conn.ObjectCopy(rw.cfg.ContainerName, metaFileName, rw.cfg.ContainerName, util.CompactedMetaFileName(blockID, tenantID), nil)
+	if err != nil {
+		return errors.Wrap(err, "error copying obj meta to compacted obj meta")
+	}
+
+	// delete meta.json
+	return rw.conn.ObjectDelete(rw.cfg.ContainerName, metaFileName)
+}
+
+func (rw *readerWriter) ClearBlock(blockID uuid.UUID, tenantID string) error {
+	if len(tenantID) == 0 {
+		return backend.ErrEmptyTenantID
+	}
+	if blockID == uuid.Nil {
+		return backend.ErrEmptyBlockID
+	}
+
+	names, err := rw.conn.ObjectNamesAll(rw.cfg.ContainerName, &swift.ObjectsOpts{
+		Prefix: util.RootPath(blockID, tenantID) + "/",
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error listing objects in container %s", rw.cfg.ContainerName)
+	}
+
+	// large object delete also removes the segments of large objects
+	for _, name := range names {
+		err = rw.conn.LargeObjectDelete(rw.cfg.ContainerName, name)
+		if err != nil && err != swift.ObjectNotFound {
+			return errors.Wrapf(err, "error deleting obj from swift: %s", name)
+		}
+	}
+
+	return nil
+}
+
+func (rw *readerWriter) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	if len(tenantID) == 0 {
+		return nil, backend.ErrEmptyTenantID
+	}
+	if blockID == uuid.Nil {
+		return nil, backend.ErrEmptyBlockID
+	}
+
+	compactedMetaFileName := util.CompactedMetaFileName(blockID, tenantID)
+	bytes, modTime, err := rw.readAllWithModTime(compactedMetaFileName)
+	if err == swift.ObjectNotFound {
+		return nil, backend.ErrMetaDoesNotExist
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error fetching compacted meta file %s", compactedMetaFileName)
+	}
+
+	out := &backend.CompactedBlockMeta{}
+	err = json.Unmarshal(bytes, out)
+	if err != nil {
+		return nil, err
+	}
+	out.CompactedTime = modTime
+
+	return out, nil
+}
+
+// ArchiveObject implements backend.Compactor
+func (rw *readerWriter) ArchiveObject(name string, blockID uuid.UUID, tenantID string, storageClass string) error {
+	return backend.ErrArchiveNotSupported
+}
@@ -0,0 +1,30 @@
+package swift
+
+import (
+	"time"
+
+	"github.com/cortexproject/cortex/pkg/util/flagext"
+)
+
+type Config struct {
+	AuthVersion          int            `yaml:"auth_version"`
+	AuthURL              string         `yaml:"auth_url"`
+	Username             string         `yaml:"username"`
+	UserDomainName       string         `yaml:"user_domain_name"`
+	UserDomainID         string         `yaml:"user_domain_id"`
+	UserID               string         `yaml:"user_id"`
+	Password             flagext.Secret `yaml:"password"`
+	DomainID             string         `yaml:"domain_id"`
+	DomainName           string         `yaml:"domain_name"`
+	ProjectID            string         `yaml:"project_id"`
+	ProjectName          string         `yaml:"project_name"`
+	ProjectDomainID      string         `yaml:"project_domain_id"`
+	ProjectDomainName    string         `yaml:"project_domain_name"`
+	RegionName           string         `yaml:"region_name"`
+	ContainerName        string         `yaml:"container_name"`
+	SegmentContainerName string         `yaml:"segment_container_name"`
+	SegmentSizeBytes     int64          `yaml:"segment_size_bytes"`
+	MaxRetries           int            `yaml:"max_retries"`
+	ConnectTimeout       time.Duration  `yaml:"connect_timeout"`
+	RequestTimeout       time.Duration  `yaml:"request_timeout"`
+}
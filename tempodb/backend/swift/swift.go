@@ -0,0 +1,324 @@
+package swift
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ncw/swift"
+	"github.com/opentracing/opentracing-go"
+	"github.com/pkg/errors"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/util"
+)
+
+const defaultSegmentSizeBytes = 100 * 1024 * 1024
+
+type readerWriter struct {
+	cfg              *Config
+	conn             *swift.Connection
+	segmentContainer string
+	segmentSize      int64
+}
+
+// New creates a swift backend.  Objects larger than the configured segment size are uploaded as static large
+//  objects whose segments are stored in a separate container.
+func New(cfg *Config) (backend.Reader, backend.Writer, backend.Compactor, error) {
+	if cfg.ContainerName == "" {
+		return nil, nil, nil, fmt.Errorf("swift container name must be set")
+	}
+
+	conn := &swift.Connection{
+		AuthVersion:    cfg.AuthVersion,
+		AuthUrl:        cfg.AuthURL,
+		UserName:       cfg.Username,
+		Domain:         cfg.UserDomainName,
+		DomainId:       cfg.UserDomainID,
+		UserId:         cfg.UserID,
+		ApiKey:         cfg.Password.String(),
+		Tenant:         cfg.ProjectName,
+		TenantId:       cfg.ProjectID,
+		TenantDomain:   cfg.ProjectDomainName,
+		TenantDomainId: cfg.ProjectDomainID,
+		Region:         cfg.RegionName,
+		Retries:        cfg.MaxRetries,
+		ConnectTimeout: cfg.ConnectTimeout,
+		Timeout:        cfg.RequestTimeout,
+		Transport:      newInstrumentedTransport(http.DefaultTransport),
+	}
+
+	// domain name/id are a fallback for the user domain
+	if conn.Domain == "" {
+		conn.Domain = cfg.DomainName
+	}
+	if conn.DomainId == "" {
+		conn.DomainId = cfg.DomainID
+	}
+
+	err := conn.Authenticate()
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "authenticating with swift")
+	}
+
+	segmentContainer := cfg.SegmentContainerName
+	if segmentContainer == "" {
+		segmentContainer = cfg.ContainerName + "_segments"
+	}
+
+	// segments are kept out of the block container so they are not listed as tenants or block objects
+	if segmentContainer == cfg.ContainerName {
+		return nil, nil, nil, fmt.Errorf("swift segment container must differ from the container %s", cfg.ContainerName)
+	}
+
+	for _, c := range []string{cfg.ContainerName, segmentContainer} {
+		if _, _, err = conn.Container(c); err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "getting swift container %s", c)
+		}
+	}
+
+	segmentSize := cfg.SegmentSizeBytes
+	if segmentSize <= 0 {
+		segmentSize = defaultSegmentSizeBytes
+	}
+
+	rw := &readerWriter{
+		cfg:              cfg,
+		conn:             conn,
+		segmentContainer: segmentContainer,
+		segmentSize:      segmentSize,
+	}
+
+	return rw, rw, rw, nil
+}
+
+// Write implements backend.Writer
+func (rw *readerWriter) Write(ctx context.Context, name string, blockID uuid.UUID, tenantID string, buffer []byte) error {
+	return rw.WriteReader(ctx, name, blockID, tenantID, bytes.NewBuffer(buffer), int64(len(buffer)))
+}
+
+// WriteReader implements backend.Writer
+func (rw *readerWriter) WriteReader(_ context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, size int64) error {
+	objectName := util.ObjectFileName(blockID, tenantID, name)
+
+	if size >= 0 && size <= rw.segmentSize {
+		_, err := rw.conn.ObjectPut(rw.cfg.ContainerName, objectName, data, false, "", "", nil)
+		return err
+	}
+
+	w, err := rw.largeObjectWriter(objectName)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(w, data)
+	if err != nil {
+		w.Close()
+		return err
+	}
+
+	return w.Close()
+}
+
+// WriteBlockMeta implements backend.Writer
+func (rw *readerWriter) WriteBlockMeta(_ context.Context, meta *backend.BlockMeta) error {
+	bMeta, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+
+	return rw.conn.ObjectPutBytes(rw.cfg.ContainerName, util.MetaFileName(meta.BlockID, meta.TenantID), bMeta, "")
+}
+
+// Append implements backend.Writer
+func (rw *readerWriter) Append(_ context.Context, name string, blockID uuid.UUID, tenantID string, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	var w swift.LargeObjectFile
+	if tracker == nil {
+		var err error
+		w, err = rw.largeObjectWriter(util.ObjectFileName(blockID, tenantID, name))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		w = tracker.(swift.LargeObjectFile)
+	}
+
+	_, err := w.Write(buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// CloseAppend implements backend.Writer
+func (rw *readerWriter) CloseAppend(_ context.Context, tracker backend.AppendTracker) error {
+	if tracker == nil {
+		return nil
+	}
+
+	w := tracker.(swift.LargeObjectFile)
+	return w.Close()
+}
+
+// Tenants implements backend.Reader
+func (rw *readerWriter) Tenants(_ context.Context) ([]string, error) {
+	names, err := rw.conn.ObjectNamesAll(rw.cfg.ContainerName, &swift.ObjectsOpts{
+		Delimiter: '/',
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing tenants")
+	}
+
+	tenants := make([]string, 0, len(names))
+	for _, name := range names {
+		if !strings.HasSuffix(name, "/") {
+			continue
+		}
+		tenants = append(tenants, strings.TrimSuffix(name, "/"))
+	}
+
+	return tenants, nil
+}
+
+// Blocks implements backend.Reader
+func (rw *readerWriter) Blocks(_ context.Context, tenantID string) ([]uuid.UUID, error) {
+	var warning error
+
+	names, err := rw.conn.ObjectNamesAll(rw.cfg.ContainerName, &swift.ObjectsOpts{
+		Prefix:    tenantID + "/",
+		Delimiter: '/',
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "listing blocks")
+	}
+
+	blocks := make([]uuid.UUID, 0, len(names))
+	for _, name := range names {
+		if !strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		idString := strings.TrimSuffix(strings.TrimPrefix(name, tenantID+"/"), "/")
+		blockID, err := uuid.Parse(idString)
+		if err != nil {
+			warning = fmt.Errorf("failed parse on blockID %s: %v", idString, err)
+			continue
+		}
+
+		blocks = append(blocks, blockID)
+	}
+
+	return blocks, warning
+}
+
+// BlockMeta implements backend.Reader
+func (rw *readerWriter) BlockMeta(_ context.Context, blockID uuid.UUID, tenantID string) (*backend.BlockMeta, error) {
+	bytes, err := rw.conn.ObjectGetBytes(rw.cfg.ContainerName, util.MetaFileName(blockID, tenantID))
+	if err == swift.ObjectNotFound {
+		return nil, backend.ErrMetaDoesNotExist
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "read block meta from swift")
+	}
+
+	out := &backend.BlockMeta{}
+	err = json.Unmarshal(bytes, out)
+	if err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// Read implements backend.Reader
+func (rw *readerWriter) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "swift.Read")
+	defer span.Finish()
+
+	span.SetTag("object", name)
+
+	bytes, err := rw.conn.ObjectGetBytes(rw.cfg.ContainerName, util.ObjectFileName(blockID, tenantID, name))
+	if err == swift.ObjectNotFound {
+		return nil, backend.ErrDoesNotExist
+	}
+	if err != nil {
+		span.SetTag("error", true)
+	}
+	return bytes, err
+}
+
+// ReadRange implements backend.Reader
+func (rw *readerWriter) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte) error {
+	span, _ := opentracing.StartSpanFromContext(ctx, "swift.ReadRange")
+	defer span.Finish()
+
+	err := rw.readRange(util.ObjectFileName(blockID, tenantID, name), offset, buffer)
+	if err == swift.ObjectNotFound {
+		return backend.ErrDoesNotExist
+	}
+	if err != nil {
+		span.SetTag("error", true)
+	}
+	return err
+}
+
+// Shutdown implements backend.Reader
+func (rw *readerWriter) Shutdown() {
+}
+
+// largeObjectWriter creates a static large object.  Clusters without the slo middleware fall back to dynamic
+//  large objects.
+func (rw *readerWriter) largeObjectWriter(objectName string) (swift.LargeObjectFile, error) {
+	opts := &swift.LargeObjectOpts{
+		Container:        rw.cfg.ContainerName,
+		ObjectName:       objectName,
+		SegmentContainer: rw.segmentContainer,
+		ChunkSize:        rw.segmentSize,
+	}
+
+	w, err := rw.conn.StaticLargeObjectCreateFile(opts)
+	if err == swift.SLONotSupported {
+		return rw.conn.DynamicLargeObjectCreateFile(opts)
+	}
+	return w, err
+}
+
+func (rw *readerWriter) readRange(objectName string, offset uint64, buffer []byte) error {
+	if len(buffer) == 0 {
+		return nil
+	}
+
+	headers := swift.Headers{
+		"Range": fmt.Sprintf("bytes=%d-%d", offset, offset+uint64(len(buffer))-1),
+	}
+	r, _, err := rw.conn.ObjectOpen(rw.cfg.ContainerName, objectName, false, headers)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	_, err = io.ReadFull(r, buffer)
+	return err
+}
+
+func (rw *readerWriter) readAllWithModTime(objectName string) ([]byte, time.Time, error) {
+	var buffer bytes.Buffer
+	headers, err := rw.conn.ObjectGet(rw.cfg.ContainerName, objectName, &buffer, false, nil)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	modTime, err := time.Parse(http.TimeFormat, headers["Last-Modified"])
+	if err != nil {
+		return nil, time.Time{}, errors.Wrapf(err, "parsing last modified time of %s", objectName)
+	}
+
+	return buffer.Bytes(), modTime, nil
+}
@@ -0,0 +1,147 @@
+package swift
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ncw/swift"
+	"github.com/ncw/swift/swifttest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/util"
+)
+
+const (
+	testContainer = "traces"
+	testTenantID  = "fake"
+)
+
+func newTestReaderWriter(t *testing.T) (*readerWriter, *swift.Connection) {
+	srv, err := swifttest.NewSwiftServer("localhost")
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+
+	cfg := &Config{
+		AuthURL:          srv.AuthURL,
+		Username:         swifttest.TEST_ACCOUNT,
+		ContainerName:    testContainer,
+		SegmentSizeBytes: 10,
+	}
+	cfg.Password.Value = swifttest.TEST_ACCOUNT
+
+	conn := &swift.Connection{
+		AuthUrl:  srv.AuthURL,
+		UserName: swifttest.TEST_ACCOUNT,
+		ApiKey:   swifttest.TEST_ACCOUNT,
+	}
+	require.NoError(t, conn.Authenticate())
+
+	// the backend requires the containers to exist
+	_, _, _, err = New(cfg)
+	require.Error(t, err)
+
+	require.NoError(t, conn.ContainerCreate(testContainer, nil))
+	require.NoError(t, conn.ContainerCreate(testContainer+"_segments", nil))
+
+	r, _, _, err := New(cfg)
+	require.NoError(t, err)
+
+	return r.(*readerWriter), conn
+}
+
+func TestReadWrite(t *testing.T) {
+	rw, conn := newTestReaderWriter(t)
+	ctx := context.Background()
+	blockID := uuid.New()
+
+	meta := backend.NewBlockMeta(testTenantID, blockID, "v2", backend.EncNone)
+	require.NoError(t, rw.WriteBlockMeta(ctx, meta))
+
+	// small objects are written in a single request
+	require.NoError(t, rw.Write(ctx, "index", blockID, testTenantID, []byte("index")))
+	actual, err := rw.Read(ctx, "index", blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("index"), actual)
+
+	// appended objects are uploaded in segments
+	data := bytes.Repeat([]byte("0123456789"), 10)
+	var tracker backend.AppendTracker
+	for i := 0; i < len(data); i += 25 {
+		tracker, err = rw.Append(ctx, "data", blockID, testTenantID, tracker, data[i:i+25])
+		require.NoError(t, err)
+	}
+	require.NoError(t, rw.CloseAppend(ctx, tracker))
+
+	segments, err := conn.ObjectNamesAll(testContainer+"_segments", nil)
+	require.NoError(t, err)
+	assert.Greater(t, len(segments), 1)
+
+	actual, err = rw.Read(ctx, "data", blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, data, actual)
+
+	// as are objects written through a reader that exceed the segment size
+	require.NoError(t, rw.WriteReader(ctx, "bloom", blockID, testTenantID, bytes.NewReader(data), int64(len(data))))
+	actual, err = rw.Read(ctx, "bloom", blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, data, actual)
+
+	buffer := make([]byte, 30)
+	require.NoError(t, rw.ReadRange(ctx, "data", blockID, testTenantID, 5, buffer))
+	assert.Equal(t, data[5:35], buffer)
+
+	tenants, err := rw.Tenants(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{testTenantID}, tenants)
+
+	blocks, err := rw.Blocks(ctx, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{blockID}, blocks)
+
+	actualMeta, err := rw.BlockMeta(ctx, blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, meta.BlockID, actualMeta.BlockID)
+	assert.Equal(t, meta.TenantID, actualMeta.TenantID)
+	assert.Equal(t, meta.Version, actualMeta.Version)
+
+	_, err = rw.BlockMeta(ctx, uuid.New(), testTenantID)
+	assert.Equal(t, backend.ErrMetaDoesNotExist, err)
+}
+
+func TestCompaction(t *testing.T) {
+	rw, conn := newTestReaderWriter(t)
+	ctx := context.Background()
+	blockID := uuid.New()
+
+	meta := backend.NewBlockMeta(testTenantID, blockID, "v2", backend.EncNone)
+	require.NoError(t, rw.WriteBlockMeta(ctx, meta))
+	require.NoError(t, rw.Write(ctx, "data", blockID, testTenantID, bytes.Repeat([]byte("a"), 100)))
+
+	_, err := rw.CompactedBlockMeta(blockID, testTenantID)
+	assert.Equal(t, backend.ErrMetaDoesNotExist, err)
+
+	require.NoError(t, rw.MarkBlockCompacted(blockID, testTenantID))
+
+	_, err = rw.BlockMeta(ctx, blockID, testTenantID)
+	assert.Equal(t, backend.ErrMetaDoesNotExist, err)
+
+	compactedMeta, err := rw.CompactedBlockMeta(blockID, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, meta.BlockID, compactedMeta.BlockID)
+	assert.False(t, compactedMeta.CompactedTime.IsZero())
+
+	// clearing the block removes the segments of large objects as well
+	require.NoError(t, rw.ClearBlock(blockID, testTenantID))
+
+	names, err := conn.ObjectNamesAll(testContainer, &swift.ObjectsOpts{Prefix: util.RootPath(blockID, testTenantID)})
+	require.NoError(t, err)
+	assert.Len(t, names, 0)
+
+	segments, err := conn.ObjectNamesAll(testContainer+"_segments", nil)
+	require.NoError(t, err)
+	assert.Len(t, segments, 0)
+}
@@ -0,0 +1,17 @@
+package throttle
+
+type Config struct {
+	// BytesPerSecond is the rate at which a single tenant may read from the backend.  0 disables the limit.
+	BytesPerSecond float64 `yaml:"bytes_per_second"`
+	// RequestsPerSecond is the rate of backend read requests of a single tenant.  0 disables the limit.
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+
+	// Limits overrides the limits per tenant.  It is set by the app and not configurable.
+	Limits Limits `yaml:"-"`
+}
+
+// Limits returns the read limits of a tenant.  A limit of 0 uses the limit of the config.
+type Limits interface {
+	BackendReadBytesPerSecond(tenantID string) float64
+	BackendReadRequestsPerSecond(tenantID string) float64
+}
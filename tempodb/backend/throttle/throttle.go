@@ -0,0 +1,245 @@
+package throttle
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var (
+	metricThrottledSeconds = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "backend_read_throttled_seconds_total",
+		Help:      "Total time backend reads waited on the per tenant read limits.",
+	}, []string{"tenant"})
+)
+
+// idleTimeout is how long the limiters of a tenant are kept after its last read.  Idle limiters have refilled
+//  their burst long before and are created again on the next read.
+const idleTimeout = 10 * time.Minute
+
+// tenantLimiters are replaced instead of changed when the limits of the tenant change.  lastUsed is guarded by
+//  the mutex of the reader.
+type tenantLimiters struct {
+	bytesPerSecond    float64
+	requestsPerSecond float64
+	bytes             *rate.Limiter
+	requests          *rate.Limiter
+	lastUsed          time.Time
+}
+
+type reader struct {
+	cfg  *Config
+	next backend.Reader
+
+	mtx       sync.Mutex
+	limiters  map[string]*tenantLimiters
+	lastEvict time.Time
+}
+
+// New wraps the passed reader and limits the rate of read requests and bytes read per tenant.  Listing tenants is
+//  not attributed to a tenant and is not limited.  Requests of the blocklist poller are never limited so polling
+//  does not use up the query budget of a tenant or fall behind.
+func New(next backend.Reader, cfg *Config) backend.Reader {
+	return &reader{
+		cfg:       cfg,
+		next:      next,
+		limiters:  map[string]*tenantLimiters{},
+		lastEvict: time.Now(),
+	}
+}
+
+// Tenants implements backend.Reader
+func (r *reader) Tenants(ctx context.Context) ([]string, error) {
+	return r.next.Tenants(ctx)
+}
+
+// Blocks implements backend.Reader
+func (r *reader) Blocks(ctx context.Context, tenantID string) ([]uuid.UUID, error) {
+	if err := r.waitRequest(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	return r.next.Blocks(ctx, tenantID)
+}
+
+// BlockMeta implements backend.Reader
+func (r *reader) BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*backend.BlockMeta, error) {
+	if err := r.waitRequest(ctx, tenantID); err != nil {
+		return nil, err
+	}
+	return r.next.BlockMeta(ctx, blockID, tenantID)
+}
+
+// Read implements backend.Reader.  The size of the object is not known up front so the bytes are accounted for
+//  after the read and delay the following reads of the tenant.
+func (r *reader) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error) {
+	if err := r.waitRequest(ctx, tenantID); err != nil {
+		return nil, err
+	}
+
+	bytes, err := r.next.Read(ctx, name, blockID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !exempt(ctx) {
+		r.reserveBytes(tenantID, len(bytes))
+	}
+	return bytes, nil
+}
+
+// ReadRange implements backend.Reader
+func (r *reader) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte) error {
+	if err := r.waitRequest(ctx, tenantID); err != nil {
+		return err
+	}
+	if err := r.waitBytes(ctx, tenantID, len(buffer)); err != nil {
+		return err
+	}
+
+	return r.next.ReadRange(ctx, name, blockID, tenantID, offset, buffer)
+}
+
+// Shutdown implements backend.Reader
+func (r *reader) Shutdown() {
+	r.next.Shutdown()
+}
+
+func (r *reader) waitRequest(ctx context.Context, tenantID string) error {
+	if exempt(ctx) {
+		return nil
+	}
+
+	l := r.tenantLimiters(tenantID).requests
+	if l == nil {
+		return nil
+	}
+
+	return r.wait(ctx, tenantID, func() error {
+		return l.Wait(ctx)
+	})
+}
+
+// waitBytes waits until n bytes may be read.  Reads larger than the burst are waited for in burst sized steps.
+func (r *reader) waitBytes(ctx context.Context, tenantID string, n int) error {
+	if exempt(ctx) {
+		return nil
+	}
+
+	l := r.tenantLimiters(tenantID).bytes
+	if l == nil {
+		return nil
+	}
+
+	return r.wait(ctx, tenantID, func() error {
+		for n > 0 {
+			step := n
+			if step > l.Burst() {
+				step = l.Burst()
+			}
+			if err := l.WaitN(ctx, step); err != nil {
+				return err
+			}
+			n -= step
+		}
+		return nil
+	})
+}
+
+// reserveBytes accounts for bytes that have already been read
+func (r *reader) reserveBytes(tenantID string, n int) {
+	l := r.tenantLimiters(tenantID).bytes
+	if l == nil {
+		return
+	}
+
+	now := time.Now()
+	for n > 0 {
+		step := n
+		if step > l.Burst() {
+			step = l.Burst()
+		}
+		l.ReserveN(now, step)
+		n -= step
+	}
+}
+
+func (r *reader) wait(ctx context.Context, tenantID string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	metricThrottledSeconds.WithLabelValues(tenantID).Add(time.Since(start).Seconds())
+	return err
+}
+
+// tenantLimiters returns the limiters of the passed tenant.  They are created again if the limits of the tenant
+//  changed.  Limiters of idle tenants are evicted.
+func (r *reader) tenantLimiters(tenantID string) *tenantLimiters {
+	bytesPerSecond, requestsPerSecond := r.limits(tenantID)
+	now := time.Now()
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if now.Sub(r.lastEvict) > idleTimeout {
+		for id, l := range r.limiters {
+			if now.Sub(l.lastUsed) > idleTimeout {
+				delete(r.limiters, id)
+			}
+		}
+		r.lastEvict = now
+	}
+
+	l, ok := r.limiters[tenantID]
+	if !ok || l.bytesPerSecond != bytesPerSecond || l.requestsPerSecond != requestsPerSecond {
+		l = &tenantLimiters{
+			bytesPerSecond:    bytesPerSecond,
+			requestsPerSecond: requestsPerSecond,
+		}
+		// the burst allows one second worth of reads
+		if bytesPerSecond > 0 {
+			l.bytes = rate.NewLimiter(rate.Limit(bytesPerSecond), burst(bytesPerSecond))
+		}
+		if requestsPerSecond > 0 {
+			l.requests = rate.NewLimiter(rate.Limit(requestsPerSecond), burst(requestsPerSecond))
+		}
+		r.limiters[tenantID] = l
+	}
+	l.lastUsed = now
+
+	return l
+}
+
+// limits returns the read limits of the passed tenant.  Overrides take precedence over the config.
+func (r *reader) limits(tenantID string) (bytesPerSecond float64, requestsPerSecond float64) {
+	bytesPerSecond, requestsPerSecond = r.cfg.BytesPerSecond, r.cfg.RequestsPerSecond
+	if r.cfg.Limits == nil {
+		return
+	}
+
+	if b := r.cfg.Limits.BackendReadBytesPerSecond(tenantID); b > 0 {
+		bytesPerSecond = b
+	}
+	if q := r.cfg.Limits.BackendReadRequestsPerSecond(tenantID); q > 0 {
+		requestsPerSecond = q
+	}
+	return
+}
+
+// exempt returns true if requests made with the passed context are not limited
+func exempt(ctx context.Context) bool {
+	return backend.CallerFromContext(ctx) == backend.CallerPoller
+}
+
+func burst(perSecond float64) int {
+	if perSecond < 1 {
+		return 1
+	}
+	return int(perSecond)
+}
@@ -0,0 +1,151 @@
+package throttle
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+type mockReader struct {
+	backend.Reader
+	calls int
+}
+
+func (m *mockReader) Read(context.Context, string, uuid.UUID, string) ([]byte, error) {
+	m.calls++
+	return make([]byte, 100), nil
+}
+
+func (m *mockReader) BlockMeta(context.Context, uuid.UUID, string) (*backend.BlockMeta, error) {
+	m.calls++
+	return &backend.BlockMeta{}, nil
+}
+
+func (m *mockReader) ReadRange(context.Context, string, uuid.UUID, string, uint64, []byte) error {
+	m.calls++
+	return nil
+}
+
+func timeoutContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestRequestsPerSecond(t *testing.T) {
+	next := &mockReader{}
+	r := New(next, &Config{RequestsPerSecond: 1})
+
+	_, err := r.Read(timeoutContext(t), "name", uuid.New(), "a")
+	assert.NoError(t, err)
+
+	// the second read of the tenant would have to wait longer than the context allows
+	_, err = r.Read(timeoutContext(t), "name", uuid.New(), "a")
+	assert.Error(t, err)
+	assert.Equal(t, 1, next.calls)
+
+	// other tenants are not affected
+	_, err = r.Read(timeoutContext(t), "name", uuid.New(), "b")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestBytesPerSecond(t *testing.T) {
+	next := &mockReader{}
+	r := New(next, &Config{BytesPerSecond: 100})
+
+	assert.NoError(t, r.ReadRange(timeoutContext(t), "name", uuid.New(), "a", 0, make([]byte, 60)))
+	assert.Error(t, r.ReadRange(timeoutContext(t), "name", uuid.New(), "a", 0, make([]byte, 60)))
+	assert.Equal(t, 1, next.calls)
+
+	// full object reads are accounted for after the read
+	_, err := r.Read(timeoutContext(t), "name", uuid.New(), "b")
+	assert.NoError(t, err)
+	assert.Error(t, r.ReadRange(timeoutContext(t), "name", uuid.New(), "b", 0, make([]byte, 10)))
+	assert.Equal(t, 2, next.calls)
+}
+
+func TestUnlimited(t *testing.T) {
+	next := &mockReader{}
+	r := New(next, &Config{})
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, r.ReadRange(timeoutContext(t), "name", uuid.New(), "a", 0, make([]byte, 1000)))
+	}
+	assert.Equal(t, 100, next.calls)
+}
+
+func TestPollerNotLimited(t *testing.T) {
+	next := &mockReader{}
+	r := New(next, &Config{RequestsPerSecond: 1})
+
+	for i := 0; i < 10; i++ {
+		_, err := r.BlockMeta(backend.WithCaller(timeoutContext(t), backend.CallerPoller), uuid.New(), "a")
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 10, next.calls)
+
+	// polling does not use up the budget of the tenant
+	_, err := r.Read(timeoutContext(t), "name", uuid.New(), "a")
+	assert.NoError(t, err)
+	assert.Equal(t, 11, next.calls)
+}
+
+type mockLimits struct {
+	requestsPerSecond map[string]float64
+}
+
+func (m *mockLimits) BackendReadBytesPerSecond(string) float64 {
+	return 0
+}
+
+func (m *mockLimits) BackendReadRequestsPerSecond(tenantID string) float64 {
+	return m.requestsPerSecond[tenantID]
+}
+
+func TestTenantLimits(t *testing.T) {
+	next := &mockReader{}
+	limits := &mockLimits{requestsPerSecond: map[string]float64{"b": 1}}
+	r := New(next, &Config{Limits: limits})
+
+	// tenants without overrides use the config
+	for i := 0; i < 10; i++ {
+		_, err := r.BlockMeta(timeoutContext(t), uuid.New(), "a")
+		assert.NoError(t, err)
+	}
+
+	_, err := r.BlockMeta(timeoutContext(t), uuid.New(), "b")
+	assert.NoError(t, err)
+	_, err = r.BlockMeta(timeoutContext(t), uuid.New(), "b")
+	assert.Error(t, err)
+
+	// changed overrides are applied on the next read
+	limits.requestsPerSecond["b"] = 0
+	_, err = r.BlockMeta(timeoutContext(t), uuid.New(), "b")
+	assert.NoError(t, err)
+}
+
+func TestIdleTenantsEvicted(t *testing.T) {
+	r := New(&mockReader{}, &Config{RequestsPerSecond: 1}).(*reader)
+
+	_, err := r.BlockMeta(timeoutContext(t), uuid.New(), "a")
+	assert.NoError(t, err)
+	_, err = r.BlockMeta(timeoutContext(t), uuid.New(), "b")
+	assert.NoError(t, err)
+	assert.Len(t, r.limiters, 2)
+
+	r.mtx.Lock()
+	r.limiters["a"].lastUsed = time.Now().Add(-2 * idleTimeout)
+	r.lastEvict = time.Now().Add(-2 * idleTimeout)
+	r.mtx.Unlock()
+
+	_, err = r.BlockMeta(timeoutContext(t), uuid.New(), "b")
+	assert.Error(t, err)
+	assert.Len(t, r.limiters, 1)
+	assert.Contains(t, r.limiters, "b")
+}
@@ -0,0 +1,27 @@
+package backend
+
+import (
+	"github.com/google/uuid"
+)
+
+// NewTombstoneID returns the id of a new tombstone object.  Tombstones record the trace ids of a tenant that
+//  were marked for deletion.  They are stored like blocks without a meta.  Their ids start with 8 zero bytes,
+//  which a random (version 4) block id never does, so they are told apart from blocks when listing.
+func NewTombstoneID() uuid.UUID {
+	id := uuid.New()
+	for i := 0; i < 8; i++ {
+		id[i] = 0
+	}
+	return id
+}
+
+// IsTombstoneID returns true if the passed id is the id of a tombstone object.  Every consumer of
+//  Reader.Blocks() must skip these ids.
+func IsTombstoneID(id uuid.UUID) bool {
+	for i := 0; i < 8; i++ {
+		if id[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,15 @@
+package backend
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsTombstoneID(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		assert.True(t, IsTombstoneID(NewTombstoneID()))
+		assert.False(t, IsTombstoneID(uuid.New()))
+	}
+}
@@ -1,6 +1,8 @@
 package util
 
 import (
+	"fmt"
+	"hash/fnv"
 	"os"
 	"path"
 
@@ -24,6 +26,32 @@ func RootPath(blockID uuid.UUID, tenantID string) string {
 	return path.Join(tenantID, blockID.String())
 }
 
+// ShardedRootPath is the root path of a block when block paths are distributed over hashed prefixes:
+//  <tenant>/<shard>/<blockID>
+func ShardedRootPath(blockID uuid.UUID, tenantID string) string {
+	return path.Join(tenantID, BlockShard(blockID), blockID.String())
+}
+
+// BlockShard returns the two hex character prefix of a block in the sharded layout
+func BlockShard(blockID uuid.UUID) string {
+	h := fnv.New32a()
+	_, _ = h.Write(blockID[:])
+	return fmt.Sprintf("%02x", h.Sum32()&0xff)
+}
+
+// IsBlockShard returns true if s is a block shard prefix as returned by BlockShard
+func IsBlockShard(s string) bool {
+	if len(s) != 2 {
+		return false
+	}
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
 func FileExists(filename string) error {
 	_, err := os.Stat(filename)
 	return err
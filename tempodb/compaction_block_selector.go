@@ -113,8 +113,6 @@ func newTimeWindowBlockSelector(blocklist []*backend.BlockMeta, maxCompactionRan
 
 			// Within group choose smallest blocks first.
 			entry.order = fmt.Sprintf("%016X", entry.meta.TotalObjects)
-
-			entry.hash = fmt.Sprintf("%v-%v-%v", b.TenantID, b.CompactionLevel, w)
 		} else {
 			// outside active window.
 			// Group by window only.  Choose most recent windows first.
@@ -122,9 +120,13 @@ func newTimeWindowBlockSelector(blocklist []*backend.BlockMeta, maxCompactionRan
 
 			// Within group chose lowest compaction lvl and smallest blocks first.
 			entry.order = fmt.Sprintf("%v-%016X", b.CompactionLevel, entry.meta.TotalObjects)
+		}
 
-			entry.hash = fmt.Sprintf("%v-%v", b.TenantID, w)
+		// split blocks are only compacted with blocks of the same shard
+		if b.ShardCount > 0 {
+			entry.group += fmt.Sprintf("-%v/%v", b.ShardIndex, b.ShardCount)
 		}
+		entry.hash = twbs.hashForBlock(b, activeWindow)
 
 		twbs.entries = append(twbs.entries, entry)
 	}
@@ -143,6 +145,24 @@ func newTimeWindowBlockSelector(blocklist []*backend.BlockMeta, maxCompactionRan
 	return twbs
 }
 
+// hashForBlock returns the hash string used for sharding ownership of the passed block.  Blocks in the active window
+//  are owned per compaction level and window, older blocks per window.  Each shard of split blocks is owned separately.
+func (twbs *timeWindowBlockSelector) hashForBlock(b *backend.BlockMeta, activeWindow int64) string {
+	w := twbs.windowForBlock(b)
+
+	var hash string
+	if activeWindow <= w {
+		hash = fmt.Sprintf("%v-%v-%v", b.TenantID, b.CompactionLevel, w)
+	} else {
+		hash = fmt.Sprintf("%v-%v", b.TenantID, w)
+	}
+
+	if b.ShardCount > 0 {
+		hash += fmt.Sprintf("-%v/%v", b.ShardIndex, b.ShardCount)
+	}
+	return hash
+}
+
 func (twbs *timeWindowBlockSelector) BlocksToCompact() ([]*backend.BlockMeta, string) {
 	for len(twbs.entries) > 0 {
 		var chosen []timeWindowBlockEntry
@@ -0,0 +1,57 @@
+package tempodb
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// ingestVolumePeriod is the period over which the ingest volume of a tenant is measured
+const ingestVolumePeriod = 24 * time.Hour
+
+var (
+	metricCompactionTargetBlockBytes = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "compaction_target_block_bytes",
+		Help:      "Maximum size of compacted blocks of a tenant as of the last compaction cycle.",
+	}, []string{"tenant"})
+)
+
+// targetBlockBytesForTenant returns the maximum size of compacted blocks of the passed tenant.  With
+//  TargetBlocksPerWindow set the size is derived from the bytes the tenant ingested per compaction window over
+//  the last day so a window ends up in about that many blocks.  Small tenants get small blocks and large
+//  tenants large blocks, but never smaller than MinBlockBytes or larger than the tenant's max block bytes.  A
+//  tenant that ingested nothing during the day uses its max block bytes so its remaining blocks are compacted.
+func (rw *readerWriter) targetBlockBytesForTenant(tenantID string, blocklist []*backend.BlockMeta) uint64 {
+	maxBytes := rw.maxBlockBytesForTenant(tenantID)
+	if rw.compactorCfg.TargetBlocksPerWindow <= 0 {
+		return maxBytes
+	}
+
+	// the blocks of the last day hold everything ingested during the day no matter how far they were compacted
+	cutoff := time.Now().Add(-ingestVolumePeriod)
+	var ingested uint64
+	for _, b := range blocklist {
+		if b.EndTime.After(cutoff) {
+			ingested += b.Size
+		}
+	}
+
+	target := maxBytes
+	if ingested > 0 {
+		perWindow := float64(ingested) * rw.compactionWindowForTenant(tenantID).Seconds() / ingestVolumePeriod.Seconds()
+		target = uint64(perWindow / float64(rw.compactorCfg.TargetBlocksPerWindow))
+	}
+
+	if target < rw.compactorCfg.MinBlockBytes {
+		target = rw.compactorCfg.MinBlockBytes
+	}
+	if target > maxBytes {
+		target = maxBytes
+	}
+
+	return target
+}
@@ -0,0 +1,109 @@
+package tempodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+func TestTargetBlockBytesForTenant(t *testing.T) {
+	now := time.Now()
+	// 24 GiB ingested over the last day is 4 GiB per 4h window
+	blocklist := []*backend.BlockMeta{
+		{EndTime: now.Add(-time.Hour), Size: 8 << 30},
+		{EndTime: now.Add(-12 * time.Hour), Size: 16 << 30},
+		// older than a day
+		{EndTime: now.Add(-48 * time.Hour), Size: 100 << 30},
+	}
+
+	tests := []struct {
+		name           string
+		targetBlocks   int
+		minBlockBytes  uint64
+		maxBlockBytes  uint64
+		tenantMaxBytes uint64
+		blocklist      []*backend.BlockMeta
+		expected       uint64
+	}{
+		{
+			name:          "disabled",
+			maxBlockBytes: 100 << 30,
+			blocklist:     blocklist,
+			expected:      100 << 30,
+		},
+		{
+			name:          "one block per window",
+			targetBlocks:  1,
+			maxBlockBytes: 100 << 30,
+			blocklist:     blocklist,
+			expected:      4 << 30,
+		},
+		{
+			name:          "several blocks per window",
+			targetBlocks:  4,
+			maxBlockBytes: 100 << 30,
+			blocklist:     blocklist,
+			expected:      1 << 30,
+		},
+		{
+			name:          "lower bound",
+			targetBlocks:  4,
+			minBlockBytes: 2 << 30,
+			maxBlockBytes: 100 << 30,
+			blocklist:     blocklist,
+			expected:      2 << 30,
+		},
+		{
+			name:          "upper bound",
+			targetBlocks:  1,
+			maxBlockBytes: 2 << 30,
+			blocklist:     blocklist,
+			expected:      2 << 30,
+		},
+		{
+			name:           "tenant upper bound",
+			targetBlocks:   1,
+			maxBlockBytes:  100 << 30,
+			tenantMaxBytes: 3 << 30,
+			blocklist:      blocklist,
+			expected:       3 << 30,
+		},
+		{
+			name:          "idle tenant",
+			targetBlocks:  1,
+			minBlockBytes: 1 << 20,
+			maxBlockBytes: 100 << 30,
+			blocklist: []*backend.BlockMeta{
+				{EndTime: now.Add(-48 * time.Hour), Size: 100 << 30},
+			},
+			expected: 100 << 30,
+		},
+		{
+			name:           "idle tenant with override",
+			targetBlocks:   1,
+			minBlockBytes:  1 << 20,
+			maxBlockBytes:  100 << 30,
+			tenantMaxBytes: 3 << 30,
+			expected:       3 << 30,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := &readerWriter{
+				compactorCfg: &CompactorConfig{
+					MaxCompactionRange:    4 * time.Hour,
+					MaxBlockBytes:         tt.maxBlockBytes,
+					MinBlockBytes:         tt.minBlockBytes,
+					TargetBlocksPerWindow: tt.targetBlocks,
+				},
+				compactorOverrides: &mockOverrides{maxBlockBytes: tt.tenantMaxBytes},
+			}
+
+			assert.Equal(t, tt.expected, rw.targetBlockBytesForTenant(testTenantID, tt.blocklist))
+		})
+	}
+}
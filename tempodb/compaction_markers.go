@@ -0,0 +1,121 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+const compactionMarkerName = "compacted.json"
+
+// compactionMarker lists blocks of a tenant that were marked compacted together
+type compactionMarker struct {
+	BlockIDs      []uuid.UUID `json:"blockIDs"`
+	CompactedTime time.Time   `json:"compactedTime"`
+}
+
+// writeCompactionMarker records that the passed blocks of the tenant were marked compacted.  Pollers reuse the
+//  live metas of blocks within the stale tolerance and only learn about their compaction from markers.  Every
+//  compaction writes its own marker so compactors never overwrite each other's markers.
+func (rw *readerWriter) writeCompactionMarker(ctx context.Context, tenantID string, blockIDs []uuid.UUID, compactedTime time.Time) {
+	if len(blockIDs) == 0 {
+		return
+	}
+
+	b, err := json.Marshal(&compactionMarker{
+		BlockIDs:      blockIDs,
+		CompactedTime: compactedTime,
+	})
+	if err == nil {
+		err = rw.w.WriteReader(ctx, compactionMarkerName, backend.NewCompactionMarkerID(), tenantID, bytes.NewReader(b), int64(len(b)))
+	}
+	if err != nil {
+		// pollers see the compaction once the metas of the blocks are stale
+		level.Error(rw.logger).Log("msg", "failed to write compaction marker", "tenantID", tenantID, "err", err)
+		metricCompactionErrors.Inc()
+	}
+}
+
+// compactionMarkersForTenant returns the compaction markers of the tenant found by the last blocklist poll by id
+func (rw *readerWriter) compactionMarkersForTenant(tenantID string) map[uuid.UUID]*compactionMarker {
+	rw.blockListsMtx.Lock()
+	defer rw.blockListsMtx.Unlock()
+
+	all := make(map[uuid.UUID]*compactionMarker, len(rw.compactionMarkers[tenantID]))
+	for id, m := range rw.compactionMarkers[tenantID] {
+		all[id] = m
+	}
+	return all
+}
+
+// pollCompactionMarkers returns the compaction markers with the passed ids.  Markers are never changed once
+//  written so only those unknown to the previous poll are read.  A marker that fails to read is retried on the
+//  next poll.
+func (rw *readerWriter) pollCompactionMarkers(ctx context.Context, tenantID string, ids []uuid.UUID) map[uuid.UUID]*compactionMarker {
+	previous := rw.compactionMarkersForTenant(tenantID)
+
+	all := make(map[uuid.UUID]*compactionMarker, len(ids))
+	for _, id := range ids {
+		if m, ok := previous[id]; ok {
+			all[id] = m
+			continue
+		}
+
+		b, err := rw.r.Read(ctx, compactionMarkerName, id, tenantID)
+		if err == backend.ErrDoesNotExist {
+			// removed by retention since listing
+			continue
+		}
+		if err != nil {
+			metricBlocklistErrors.WithLabelValues(tenantID).Inc()
+			level.Error(rw.logger).Log("msg", "failed to read compaction marker", "markerID", id, "tenantID", tenantID, "err", err)
+			continue
+		}
+
+		m := &compactionMarker{}
+		err = json.Unmarshal(b, m)
+		if err != nil {
+			metricBlocklistErrors.WithLabelValues(tenantID).Inc()
+			level.Error(rw.logger).Log("msg", "failed to unmarshal compaction marker", "markerID", id, "tenantID", tenantID, "err", err)
+			continue
+		}
+		all[id] = m
+	}
+
+	return all
+}
+
+// clearExpiredCompactionMarkers removes the compaction markers of the tenant whose blocks are cleared.  Blocks
+//  that are no longer listed leave the blocklist without a marker.
+func (rw *readerWriter) clearExpiredCompactionMarkers(tenantID string) {
+	cutoff := time.Now().Add(-rw.compactedBlockRetention())
+	for id, m := range rw.compactionMarkersForTenant(tenantID) {
+		if !m.CompactedTime.Before(cutoff) || !rw.compactorSharder.Owns(id.String()) {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "deleting expired compaction marker", "markerID", id, "tenantID", tenantID)
+		err := rw.c.ClearBlock(id, tenantID)
+		if err != nil && err != backend.ErrObjectLocked {
+			level.Error(rw.logger).Log("msg", "failed to clear expired compaction marker", "markerID", id, "tenantID", tenantID, "err", err)
+			metricRetentionErrors.Inc()
+		}
+	}
+}
+
+// compactedByMarkers returns the compacted time of every block listed in the passed markers
+func compactedByMarkers(markers map[uuid.UUID]*compactionMarker) map[uuid.UUID]time.Time {
+	compacted := map[uuid.UUID]time.Time{}
+	for _, m := range markers {
+		for _, id := range m.BlockIDs {
+			compacted[id] = m.CompactedTime
+		}
+	}
+	return compacted
+}
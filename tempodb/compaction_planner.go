@@ -0,0 +1,186 @@
+package tempodb
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var (
+	metricCompactionOutstandingJobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "compaction_outstanding_jobs",
+		Help:      "Number of compaction jobs owned by this compactor that were outstanding at the start of the last compaction cycle.",
+	}, []string{"tenant", "level"})
+	metricCompactionOutstandingBlocks = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "tempodb",
+		Name:      "compaction_outstanding_blocks",
+		Help:      "Number of blocks in compaction jobs owned by this compactor that were outstanding at the start of the last compaction cycle.",
+	}, []string{"tenant", "level"})
+)
+
+// CompactionPlan describes a compaction job without running it
+type CompactionPlan struct {
+	TenantID string      `json:"tenantID"`
+	Level    uint8       `json:"level"`
+	Hash     string      `json:"hash"`
+	Owned    bool        `json:"owned"` // true if the job is run by this compactor
+	Inputs   []uuid.UUID `json:"inputs"`
+	// InputObjects and EstimatedSize are the totals of the inputs.  Traces that are combined during
+	//  compaction make the output smaller.
+	InputObjects  int    `json:"inputObjects"`
+	EstimatedSize uint64 `json:"estimatedSize"`
+}
+
+// compactionJob is a set of blocks of one tenant that will be compacted together
+type compactionJob struct {
+	tenantID string
+	blocks   []*backend.BlockMeta
+	hash     string
+	level    uint8
+	owned    bool
+
+	round  int // position of the job among the jobs of its tenant
+	tenant int // position of the tenant in the cycle
+}
+
+// planCompaction returns the compaction jobs of the passed tenants that are owned by this compactor in the
+//  order they should be run.  Jobs of lower compaction levels go first so the level 0 backlog created by the
+//  ingesters is worked off before anything else.  Within a level the jobs of all tenants are interleaved so a
+//  single large tenant does not starve the others.  Ties go to the tenant that comes first in tenants.
+func (rw *readerWriter) planCompaction(tenants []string) []*compactionJob {
+	jobs := rw.planJobs(tenants, false)
+	recordOutstandingJobs(jobs)
+
+	return jobs
+}
+
+// PlanCompaction returns the compaction jobs of the passed tenant in priority order, including jobs owned by other
+//  compactors.  Nothing is compacted and no metrics are recorded.
+func (rw *readerWriter) PlanCompaction(tenantID string) []*CompactionPlan {
+	if rw.compactorCfg == nil || tenantID == "" {
+		return nil
+	}
+
+	var plans []*CompactionPlan
+	for _, job := range rw.planJobs([]string{tenantID}, true) {
+		plan := &CompactionPlan{
+			TenantID: job.tenantID,
+			Level:    job.level,
+			Hash:     job.hash,
+			Owned:    job.owned,
+		}
+		for _, b := range job.blocks {
+			plan.Inputs = append(plan.Inputs, b.BlockID)
+			plan.InputObjects += b.TotalObjects
+			plan.EstimatedSize += b.Size
+		}
+		plans = append(plans, plan)
+	}
+
+	return plans
+}
+
+// planJobs returns the compaction jobs of the passed tenants in priority order.  Jobs owned by other compactors
+//  are only included if all is true, which is a dry run that records no metrics.
+func (rw *readerWriter) planJobs(tenants []string, all bool) []*compactionJob {
+	var jobs []*compactionJob
+
+	for i, tenantID := range tenants {
+		blockSelector, targetBlockBytes := rw.blockSelectorForTenant(tenantID)
+		if !all {
+			metricCompactionTargetBlockBytes.WithLabelValues(tenantID).Set(float64(targetBlockBytes))
+		}
+
+		rounds := map[uint8]int{}
+		for {
+			blocks, hashString := blockSelector.BlocksToCompact()
+			if len(blocks) == 0 {
+				break
+			}
+			owned := rw.compactorSharder.Owns(hashString)
+			if !owned && !all {
+				continue
+			}
+
+			lvl := compactionLevelForBlocks(blocks)
+			jobs = append(jobs, &compactionJob{
+				tenantID: tenantID,
+				blocks:   blocks,
+				hash:     hashString,
+				level:    lvl,
+				owned:    owned,
+				round:    rounds[lvl],
+				tenant:   i,
+			})
+			rounds[lvl]++
+		}
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		ji := jobs[i]
+		jj := jobs[j]
+
+		if ji.level != jj.level {
+			return ji.level < jj.level
+		}
+		if ji.round != jj.round {
+			return ji.round < jj.round
+		}
+		return ji.tenant < jj.tenant
+	})
+
+	return jobs
+}
+
+func recordOutstandingJobs(jobs []*compactionJob) {
+	metricCompactionOutstandingJobs.Reset()
+	metricCompactionOutstandingBlocks.Reset()
+
+	for _, job := range jobs {
+		lvl := strconv.Itoa(int(job.level))
+		metricCompactionOutstandingJobs.WithLabelValues(job.tenantID, lvl).Inc()
+		metricCompactionOutstandingBlocks.WithLabelValues(job.tenantID, lvl).Add(float64(len(job.blocks)))
+	}
+}
+
+// blockSelectorForTenant returns a selector over the current blocklist of the passed tenant and the maximum size
+//  of the blocks it compacts
+func (rw *readerWriter) blockSelectorForTenant(tenantID string) (CompactionBlockSelector, uint64) {
+	blocklist := rw.blocklist(tenantID)
+	maxBlockBytes := rw.targetBlockBytesForTenant(tenantID, blocklist)
+	blocklist = rw.withoutCorruptBlocks(tenantID, blocklist)
+
+	if rw.compactorCfg.MixedVersions == MixedVersionsSeparate {
+		return newVersionedBlockSelector(blocklist, func(blocklist []*backend.BlockMeta) CompactionBlockSelector {
+			return rw.newBlockSelector(tenantID, blocklist, maxBlockBytes)
+		}), maxBlockBytes
+	}
+
+	return rw.newBlockSelector(tenantID, blocklist, maxBlockBytes), maxBlockBytes
+}
+
+// newBlockSelector returns the configured selector over the passed blocks of a tenant
+func (rw *readerWriter) newBlockSelector(tenantID string, blocklist []*backend.BlockMeta, maxBlockBytes uint64) CompactionBlockSelector {
+	if rw.compactorCfg.SplitShards > 0 {
+		return newSplitMergeBlockSelector(blocklist,
+			uint32(rw.compactorCfg.SplitShards),
+			rw.compactionWindowForTenant(tenantID),
+			rw.maxCompactionObjectsForTenant(tenantID),
+			maxBlockBytes,
+			defaultMinInputBlocks,
+			defaultMaxInputBlocks)
+	}
+
+	return newTimeWindowBlockSelector(blocklist,
+		rw.compactionWindowForTenant(tenantID),
+		rw.maxCompactionObjectsForTenant(tenantID),
+		maxBlockBytes,
+		defaultMinInputBlocks,
+		defaultMaxInputBlocks)
+}
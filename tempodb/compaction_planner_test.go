@@ -0,0 +1,175 @@
+package tempodb
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/pool"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestPlanCompaction(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_64k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:       10,
+		MaxCompactionRange:   24 * time.Hour,
+		MaxCompactionObjects: 4,
+		MaxBlockBytes:        1024 * 1024 * 1024,
+	}, &mockSharder{}, &mockOverrides{})
+
+	// tenant 1 has one level 0 and one level 1 job.  tenant 2 has two level 0 jobs
+	cutTestBlocks(t, w, testTenantID, 6, 1)
+	cutTestBlocks(t, w, testTenantID2, 4, 2)
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+
+	blocklist := rw.blocklist(testTenantID)
+	require.NoError(t, rw.compact(blocklist[0:2], testTenantID))
+	require.NoError(t, rw.compact(blocklist[2:4], testTenantID))
+
+	jobs := rw.planCompaction([]string{testTenantID, testTenantID2})
+
+	type planned struct {
+		tenantID string
+		level    uint8
+	}
+	var actual []planned
+	for _, job := range jobs {
+		actual = append(actual, planned{job.tenantID, job.level})
+		assert.Len(t, job.blocks, 2)
+	}
+	assert.Equal(t, []planned{
+		{testTenantID, 0},
+		{testTenantID2, 0},
+		{testTenantID2, 0},
+		{testTenantID, 1},
+	}, actual)
+
+	outstanding, err := test.GetGaugeValue(metricCompactionOutstandingJobs.WithLabelValues(testTenantID2, "0"))
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), outstanding)
+
+	outstanding, err = test.GetGaugeValue(metricCompactionOutstandingBlocks.WithLabelValues(testTenantID, "1"))
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), outstanding)
+
+	// tenant order breaks ties
+	jobs = rw.planCompaction([]string{testTenantID2, testTenantID})
+	require.Len(t, jobs, 4)
+	assert.Equal(t, testTenantID2, jobs[0].tenantID)
+	assert.Equal(t, testTenantID, jobs[1].tenantID)
+}
+
+func TestPlanCompactionIncludesUnownedJobs(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	// nothing is planned before compaction is enabled
+	assert.Nil(t, c.PlanCompaction(testTenantID))
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:       10,
+		MaxCompactionRange:   24 * time.Hour,
+		MaxCompactionObjects: 1000,
+		MaxBlockBytes:        1024 * 1024 * 1024,
+	}, &ownsTenantSharder{tenantID: testTenantID}, &mockOverrides{})
+
+	cutTestBlocks(t, w, testTenantID, 2, 1)
+	cutTestBlocks(t, w, testTenantID2, 3, 1)
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+
+	metricCompactionTargetBlockBytes.Reset()
+
+	plans := c.PlanCompaction(testTenantID)
+	require.Len(t, plans, 1)
+	plan := plans[0]
+	assert.Equal(t, testTenantID, plan.TenantID)
+	assert.True(t, plan.Owned)
+	assert.Equal(t, uint8(0), plan.Level)
+	assert.Len(t, plan.Inputs, 2)
+	assert.Equal(t, 2, plan.InputObjects)
+
+	plans = c.PlanCompaction(testTenantID2)
+	require.Len(t, plans, 1)
+	assert.False(t, plans[0].Owned)
+	assert.Len(t, plans[0].Inputs, 3)
+
+	// planning records no metrics
+	targetBytes, err := test.GetGaugeValue(metricCompactionTargetBlockBytes.WithLabelValues(testTenantID2))
+	require.NoError(t, err)
+	assert.Equal(t, float64(0), targetBytes)
+
+	// planning does not compact
+	assert.Len(t, rw.blocklist(testTenantID), 2)
+	assert.Len(t, rw.blocklist(testTenantID2), 3)
+
+	// only owned jobs are run
+	assert.Len(t, rw.planCompaction([]string{testTenantID, testTenantID2}), 1)
+}
+
+type ownsTenantSharder struct {
+	tenantID string
+}
+
+func (s *ownsTenantSharder) Owns(hash string) bool {
+	return strings.HasPrefix(hash, s.tenantID+"-")
+}
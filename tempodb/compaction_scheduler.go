@@ -0,0 +1,101 @@
+package tempodb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+// runCompactionJobs runs the passed jobs in order with up to Concurrency jobs at a time.  A job whose level
+//  is already running LevelConcurrency jobs is passed over for the next job of another level until a slot of
+//  its level frees up.  No new jobs are started once the maintenance cycle that started at start is over.
+//  Returns false if it bailed out before all jobs were run.
+func (rw *readerWriter) runCompactionJobs(jobs []*compactionJob, start time.Time) bool {
+	var (
+		mtx      sync.Mutex
+		cond     = sync.NewCond(&mtx)
+		wg       sync.WaitGroup
+		running  int
+		perLevel = map[uint8]int{}
+		bailed   bool
+	)
+
+	pending := append([]*compactionJob(nil), jobs...)
+
+	mtx.Lock()
+	for len(pending) > 0 && !bailed {
+		i := rw.nextRunnableJob(pending, running, perLevel)
+		if i < 0 {
+			cond.Wait()
+			continue
+		}
+
+		job := pending[i]
+		pending = append(pending[:i], pending[i+1:]...)
+		running++
+		perLevel[job.level]++
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			rw.runCompactionJob(job)
+
+			mtx.Lock()
+			defer mtx.Unlock()
+
+			running--
+			perLevel[job.level]--
+			// after a maintenance cycle bail out
+			if !bailed && start.Add(rw.cfg.BlocklistPoll).Before(time.Now()) {
+				level.Info(rw.logger).Log("msg", "compacted blocks for a maintenance cycle, bailing out")
+				bailed = true
+			}
+			cond.Broadcast()
+		}()
+	}
+	mtx.Unlock()
+
+	wg.Wait()
+	return !bailed
+}
+
+// nextRunnableJob returns the index of the first pending job that can be started or -1 if no job can be
+//  started until a running job is done.
+func (rw *readerWriter) nextRunnableJob(pending []*compactionJob, running int, perLevel map[uint8]int) int {
+	if running >= rw.compactionConcurrency() {
+		return -1
+	}
+
+	for i, job := range pending {
+		limit := rw.compactorCfg.LevelConcurrency[int(job.level)]
+		if limit <= 0 || perLevel[job.level] < limit {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func (rw *readerWriter) runCompactionJob(job *compactionJob) {
+	level.Info(rw.logger).Log("msg", "Compacting hash", "hashString", job.hash, "tenantID", job.tenantID, "level", job.level)
+	err := rw.compact(job.blocks, job.tenantID)
+
+	if err == backend.ErrMetaDoesNotExist {
+		level.Warn(rw.logger).Log("msg", "unable to find meta during compaction.  trying again on this block list", "err", err)
+	} else if err != nil {
+		level.Error(rw.logger).Log("msg", "error during compaction cycle", "err", err)
+		metricCompactionErrors.Inc()
+	}
+}
+
+// compactionConcurrency returns the number of compaction jobs that are run at the same time
+func (rw *readerWriter) compactionConcurrency() int {
+	if rw.compactorCfg.Concurrency <= 0 {
+		return 1
+	}
+	return rw.compactorCfg.Concurrency
+}
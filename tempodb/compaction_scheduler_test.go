@@ -0,0 +1,101 @@
+package tempodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextRunnableJob(t *testing.T) {
+	pending := []*compactionJob{
+		{level: 0},
+		{level: 0},
+		{level: 1},
+	}
+
+	tests := []struct {
+		name             string
+		concurrency      int
+		levelConcurrency map[int]int
+		running          int
+		perLevel         map[uint8]int
+		expected         int
+	}{
+		{
+			name:     "defaults to one job",
+			expected: 0,
+		},
+		{
+			name:     "defaults to one job - busy",
+			running:  1,
+			perLevel: map[uint8]int{0: 1},
+			expected: -1,
+		},
+		{
+			name:        "first job",
+			concurrency: 3,
+			running:     1,
+			perLevel:    map[uint8]int{0: 1},
+			expected:    0,
+		},
+		{
+			name:             "level full",
+			concurrency:      3,
+			levelConcurrency: map[int]int{0: 1},
+			running:          1,
+			perLevel:         map[uint8]int{0: 1},
+			expected:         2,
+		},
+		{
+			name:             "all levels full",
+			concurrency:      3,
+			levelConcurrency: map[int]int{0: 1, 1: 1},
+			running:          2,
+			perLevel:         map[uint8]int{0: 1, 1: 1},
+			expected:         -1,
+		},
+		{
+			name:             "concurrency full",
+			concurrency:      2,
+			levelConcurrency: map[int]int{0: 1},
+			running:          2,
+			perLevel:         map[uint8]int{0: 1, 3: 1},
+			expected:         -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rw := &readerWriter{
+				compactorCfg: &CompactorConfig{
+					Concurrency:      tt.concurrency,
+					LevelConcurrency: tt.levelConcurrency,
+				},
+			}
+
+			perLevel := tt.perLevel
+			if perLevel == nil {
+				perLevel = map[uint8]int{}
+			}
+			assert.Equal(t, tt.expected, rw.nextRunnableJob(pending, tt.running, perLevel))
+		})
+	}
+}
+
+func TestRunCompactionJobs(t *testing.T) {
+	// jobs without blocks return immediately
+	jobs := []*compactionJob{{tenantID: testTenantID}, {tenantID: testTenantID}, {tenantID: testTenantID, level: 1}}
+
+	rw := &readerWriter{
+		cfg:          &Config{BlocklistPoll: time.Hour},
+		logger:       log.NewNopLogger(),
+		compactorCfg: &CompactorConfig{Concurrency: 2},
+	}
+	assert.True(t, rw.runCompactionJobs(jobs, time.Now()))
+
+	// the maintenance cycle is over after the first job
+	rw.cfg.BlocklistPoll = 0
+	assert.False(t, rw.runCompactionJobs(jobs, time.Now().Add(-time.Second)))
+}
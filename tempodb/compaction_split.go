@@ -0,0 +1,169 @@
+package tempodb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"sort"
+	"time"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+/*************************** Split and Merge Block Selector **************************/
+
+// The splitMergeBlockSelector first splits blocks into a fixed number of shards by trace id range and then
+//  merges blocks within each shard.  Every shard of a time window is owned by its own hash so the shards of
+//  a large tenant can be compacted by different compactors at the same time without overlap.
+// Like the timeWindowBlockSelector it can be used ONLY ONCE and needs to be reinitialized with an updated blocklist.
+
+type splitMergeBlockSelector struct {
+	shards uint32
+	twbs   *timeWindowBlockSelector
+
+	splitEntries []timeWindowBlockEntry
+	merge        CompactionBlockSelector
+}
+
+var _ (CompactionBlockSelector) = (*splitMergeBlockSelector)(nil)
+
+func newSplitMergeBlockSelector(blocklist []*backend.BlockMeta, shards uint32, maxCompactionRange time.Duration, maxCompactionObjects int, maxBlockBytes uint64, minInputBlocks int, maxInputBlocks int) CompactionBlockSelector {
+	smbs := &splitMergeBlockSelector{
+		shards: shards,
+		twbs: &timeWindowBlockSelector{
+			MinInputBlocks:       minInputBlocks,
+			MaxInputBlocks:       maxInputBlocks,
+			MaxCompactionRange:   maxCompactionRange,
+			MaxCompactionObjects: maxCompactionObjects,
+			MaxBlockBytes:        maxBlockBytes,
+		},
+	}
+
+	now := time.Now()
+	currWindow := smbs.twbs.windowForTime(now)
+	activeWindow := smbs.twbs.windowForTime(now.Add(-activeWindowDuration))
+
+	var split []*backend.BlockMeta
+	for _, b := range blocklist {
+		if b.ShardCount == shards {
+			split = append(split, b)
+			continue
+		}
+
+		// blocks in the cut-over window are excluded for the same reason as in the timeWindowBlockSelector
+		w := smbs.twbs.windowForBlock(b)
+		if w == activeWindow {
+			continue
+		}
+
+		smbs.splitEntries = append(smbs.splitEntries, timeWindowBlockEntry{
+			meta: b,
+			// Choose most recent windows first
+			group: fmt.Sprintf("%016X", currWindow-w),
+			// Within group choose smallest blocks first.
+			order: fmt.Sprintf("%016X", b.TotalObjects),
+			hash:  smbs.twbs.splitHashForBlock(b),
+		})
+	}
+
+	sort.SliceStable(smbs.splitEntries, func(i, j int) bool {
+		ei := smbs.splitEntries[i]
+		ej := smbs.splitEntries[j]
+
+		if ei.group == ej.group {
+			return ei.order < ej.order
+		}
+		return ei.group < ej.group
+	})
+
+	// blocks that are already split are grouped and hashed by shard by the time window selector
+	smbs.merge = newTimeWindowBlockSelector(split, maxCompactionRange, maxCompactionObjects, maxBlockBytes, minInputBlocks, maxInputBlocks)
+
+	return smbs
+}
+
+// BlocksToCompact returns blocks that still need to be split before any blocks are merged.  A single block
+//  is a valid split job.
+func (smbs *splitMergeBlockSelector) BlocksToCompact() ([]*backend.BlockMeta, string) {
+	if len(smbs.splitEntries) > 0 {
+		chosen := smbs.splitEntries[:1]
+		for j := 1; j < len(smbs.splitEntries); j++ {
+			stripe := smbs.splitEntries[:j+1]
+			if smbs.splitEntries[0].group != smbs.splitEntries[j].group ||
+				len(stripe) > smbs.twbs.MaxInputBlocks ||
+				totalObjects(stripe) > smbs.twbs.MaxCompactionObjects ||
+				totalSize(stripe) > smbs.twbs.MaxBlockBytes {
+				break
+			}
+			chosen = stripe
+		}
+		smbs.splitEntries = smbs.splitEntries[len(chosen):]
+
+		compactBlocks := make([]*backend.BlockMeta, 0, len(chosen))
+		for _, e := range chosen {
+			compactBlocks = append(compactBlocks, e.meta)
+		}
+
+		return compactBlocks, chosen[0].hash
+	}
+
+	return smbs.merge.BlocksToCompact()
+}
+
+// splitHashForBlock returns the hash string used for sharding ownership of the passed block while it still needs
+//  to be split
+func (twbs *timeWindowBlockSelector) splitHashForBlock(b *backend.BlockMeta) string {
+	return fmt.Sprintf("%v-%v-split", b.TenantID, twbs.windowForBlock(b))
+}
+
+// shardForID returns the shard of shardCount equally sized trace id ranges the passed id falls in.  64 bit ids
+//  are stored with 8 leading zero bytes and are sharded by their lower half so they are spread over all shards.
+func shardForID(id []byte, shardCount uint32) uint32 {
+	if shardCount <= 1 {
+		return 0
+	}
+
+	padded := make([]byte, 16)
+	if len(id) > 16 {
+		id = id[:16]
+	}
+	copy(padded[16-len(id):], id)
+
+	v := binary.BigEndian.Uint64(padded[:8])
+	if v == 0 {
+		v = binary.BigEndian.Uint64(padded[8:])
+	}
+
+	shard, _ := bits.Mul64(v, uint64(shardCount))
+	return uint32(shard)
+}
+
+// outputShardCount returns the number of shards the output of a compaction of the passed blocks is split into.
+//  Blocks that were split before keep their shards even if splitting was disabled in the meantime.
+func outputShardCount(blockMetas []*backend.BlockMeta, splitShards int) uint32 {
+	if splitShards > 0 {
+		return uint32(splitShards)
+	}
+
+	shardCount := blockMetas[0].ShardCount
+	for _, m := range blockMetas {
+		if m.ShardCount != shardCount {
+			return 0
+		}
+	}
+	return shardCount
+}
+
+// isSplitJob returns true if the passed blocks are not all in the same one of shardCount shards
+func isSplitJob(blockMetas []*backend.BlockMeta, shardCount uint32) bool {
+	if shardCount <= 1 {
+		return false
+	}
+
+	for _, m := range blockMetas {
+		if m.ShardCount != shardCount || m.ShardIndex != blockMetas[0].ShardIndex {
+			return true
+		}
+	}
+	return false
+}
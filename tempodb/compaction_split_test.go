@@ -0,0 +1,198 @@
+package tempodb
+
+import (
+	"context"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/pool"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestShardForID(t *testing.T) {
+	tests := []struct {
+		name     string
+		id       []byte
+		shards   uint32
+		expected uint32
+	}{
+		{
+			name:     "not sharded",
+			id:       []byte{0xFF, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			shards:   0,
+			expected: 0,
+		},
+		{
+			name:     "first shard",
+			id:       []byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			shards:   4,
+			expected: 0,
+		},
+		{
+			name:     "last shard",
+			id:       []byte{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF},
+			shards:   4,
+			expected: 3,
+		},
+		{
+			name:     "range boundary",
+			id:       []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			shards:   4,
+			expected: 2,
+		},
+		{
+			name:     "64 bit id",
+			id:       []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01},
+			shards:   4,
+			expected: 3,
+		},
+		{
+			name:     "short id",
+			id:       []byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			shards:   4,
+			expected: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, shardForID(tt.id, tt.shards))
+		})
+	}
+}
+
+func TestSplitMergeBlockSelector(t *testing.T) {
+	now := time.Now()
+	timeWindow := 12 * time.Hour
+
+	unsplit1 := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now, TotalObjects: 1}
+	unsplit2 := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now, TotalObjects: 2}
+	shard0a := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now, ShardIndex: 0, ShardCount: 2, CompactionLevel: 1}
+	shard0b := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now, ShardIndex: 0, ShardCount: 2, CompactionLevel: 1}
+	shard1a := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now, ShardIndex: 1, ShardCount: 2, CompactionLevel: 1}
+	shard1b := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now, ShardIndex: 1, ShardCount: 2, CompactionLevel: 1}
+	// split with a different shard count before the config changed
+	resplit := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now.Add(-timeWindow), ShardIndex: 1, ShardCount: 3, CompactionLevel: 1}
+
+	selector := newSplitMergeBlockSelector([]*backend.BlockMeta{shard1a, unsplit2, shard0a, resplit, shard1b, unsplit1, shard0b},
+		2, timeWindow, 100, 1024, defaultMinInputBlocks, defaultMaxInputBlocks)
+
+	// unsplit blocks are split first
+	blocks, hash := selector.BlocksToCompact()
+	assert.Equal(t, []*backend.BlockMeta{unsplit1, unsplit2}, blocks)
+	assert.Equal(t, "-"+windowString(now, timeWindow)+"-split", hash)
+
+	blocks, _ = selector.BlocksToCompact()
+	assert.Equal(t, []*backend.BlockMeta{resplit}, blocks)
+
+	// then shards are merged separately
+	blocks, hash = selector.BlocksToCompact()
+	assert.Equal(t, []*backend.BlockMeta{shard0a, shard0b}, blocks)
+	assert.Equal(t, "-1-"+windowString(now, timeWindow)+"-0/2", hash)
+
+	blocks, hash = selector.BlocksToCompact()
+	assert.Equal(t, []*backend.BlockMeta{shard1a, shard1b}, blocks)
+	assert.Equal(t, "-1-"+windowString(now, timeWindow)+"-1/2", hash)
+
+	blocks, _ = selector.BlocksToCompact()
+	assert.Nil(t, blocks)
+}
+
+func TestSplitMergeCompaction(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_4M,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	shards := 4
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:       10,
+		FlushSizeBytes:       100,
+		MaxCompactionRange:   24 * time.Hour,
+		MaxCompactionObjects: 1000,
+		MaxBlockBytes:        1024 * 1024,
+		SplitShards:          shards,
+	}, &mockSharder{}, &mockOverrides{})
+
+	blockCount := 4
+	recordCount := 50
+	var ids [][]byte
+	for i := 0; i < blockCount; i++ {
+		head, err := w.WAL().NewBlock(uuid.New(), testTenantID)
+		require.NoError(t, err)
+
+		for j := 0; j < recordCount; j++ {
+			id := make([]byte, 16)
+			_, err = rand.Read(id)
+			require.NoError(t, err)
+
+			require.NoError(t, head.Write(id, []byte{0x01, 0x02, 0x03}))
+			ids = append(ids, id)
+		}
+
+		complete, err := w.CompleteBlock(head, &mockSharder{})
+		require.NoError(t, err)
+		require.NoError(t, w.WriteBlock(context.Background(), complete))
+	}
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+
+	// the first cycle splits all blocks and merges the resulting shards
+	rw.doCompaction()
+
+	blocklist := rw.blocklist(testTenantID)
+	require.Len(t, blocklist, shards)
+	seen := map[uint32]bool{}
+	for _, b := range blocklist {
+		assert.Equal(t, uint32(shards), b.ShardCount)
+		assert.Equal(t, b.ShardIndex, shardForID(b.MinID, uint32(shards)))
+		assert.Equal(t, b.ShardIndex, shardForID(b.MaxID, uint32(shards)))
+		seen[b.ShardIndex] = true
+	}
+	assert.Len(t, seen, shards)
+
+	for _, id := range ids {
+		objs, err := rw.Find(context.Background(), testTenantID, id, BlockIDMin, BlockIDMax)
+		require.NoError(t, err)
+		assert.Len(t, objs, 1)
+	}
+}
+
+func windowString(t time.Time, window time.Duration) string {
+	return strconv.FormatInt(t.Unix()/int64(window/time.Second), 10)
+}
@@ -0,0 +1,128 @@
+package tempodb
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var (
+	metricCompactionThrottledSeconds = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "compaction_throttled_seconds_total",
+		Help:      "Total time compaction waited on the compactor throughput limit.",
+	})
+)
+
+// compactionLimiter limits the bytes per second the compactor reads from and writes to the backend.  It is
+//  shared by all compaction jobs of the compactor.
+type compactionLimiter struct {
+	limiter *rate.Limiter
+}
+
+func newCompactionLimiter(bytesPerSecond float64) *compactionLimiter {
+	burst := int(bytesPerSecond)
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &compactionLimiter{
+		limiter: rate.NewLimiter(rate.Limit(bytesPerSecond), burst),
+	}
+}
+
+// wait waits until n bytes may be transferred.  Transfers larger than the burst are waited for in burst
+//  sized steps.
+func (l *compactionLimiter) wait(ctx context.Context, n int) error {
+	start := time.Now()
+	defer func() { metricCompactionThrottledSeconds.Add(time.Since(start).Seconds()) }()
+
+	for n > 0 {
+		step := n
+		if step > l.limiter.Burst() {
+			step = l.limiter.Burst()
+		}
+		if err := l.limiter.WaitN(ctx, step); err != nil {
+			return err
+		}
+		n -= step
+	}
+
+	return nil
+}
+
+// reserve accounts for n bytes that have already been transferred.  This delays the following transfers.
+func (l *compactionLimiter) reserve(n int) {
+	now := time.Now()
+	for n > 0 {
+		step := n
+		if step > l.limiter.Burst() {
+			step = l.limiter.Burst()
+		}
+		l.limiter.ReserveN(now, step)
+		n -= step
+	}
+}
+
+// throttledReader is a backend.Reader that waits on a compactionLimiter for every object read
+type throttledReader struct {
+	backend.Reader
+	limiter *compactionLimiter
+}
+
+// Read implements backend.Reader.  The size of the object is not known up front so the bytes are accounted
+//  for after the read.
+func (r *throttledReader) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error) {
+	bytes, err := r.Reader.Read(ctx, name, blockID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.limiter.reserve(len(bytes))
+	return bytes, nil
+}
+
+// ReadRange implements backend.Reader
+func (r *throttledReader) ReadRange(ctx context.Context, name string, blockID uuid.UUID, tenantID string, offset uint64, buffer []byte) error {
+	if err := r.limiter.wait(ctx, len(buffer)); err != nil {
+		return err
+	}
+	return r.Reader.ReadRange(ctx, name, blockID, tenantID, offset, buffer)
+}
+
+// throttledWriter is a backend.Writer that waits on a compactionLimiter for every object written
+type throttledWriter struct {
+	backend.Writer
+	limiter *compactionLimiter
+}
+
+// Write implements backend.Writer
+func (w *throttledWriter) Write(ctx context.Context, name string, blockID uuid.UUID, tenantID string, buffer []byte) error {
+	if err := w.limiter.wait(ctx, len(buffer)); err != nil {
+		return err
+	}
+	return w.Writer.Write(ctx, name, blockID, tenantID, buffer)
+}
+
+// WriteReader implements backend.Writer.  Writes of unknown size are not throttled.
+func (w *throttledWriter) WriteReader(ctx context.Context, name string, blockID uuid.UUID, tenantID string, data io.Reader, size int64) error {
+	if err := w.limiter.wait(ctx, int(size)); err != nil {
+		return err
+	}
+	return w.Writer.WriteReader(ctx, name, blockID, tenantID, data, size)
+}
+
+// Append implements backend.Writer
+func (w *throttledWriter) Append(ctx context.Context, name string, blockID uuid.UUID, tenantID string, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
+	if err := w.limiter.wait(ctx, len(buffer)); err != nil {
+		return nil, err
+	}
+	return w.Writer.Append(ctx, name, blockID, tenantID, tracker, buffer)
+}
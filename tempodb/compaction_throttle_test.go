@@ -0,0 +1,112 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend/util"
+)
+
+// exhaustedContext returns a context whose deadline is too close to wait for any further bytes of a limiter
+//  of 10 bytes per second
+func exhaustedContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestThrottledWriterEnforcesLimit(t *testing.T) {
+	blockID := uuid.New()
+
+	tests := []struct {
+		name  string
+		write func(ctx context.Context, w *throttledWriter, n int) error
+	}{
+		{
+			name: "Write",
+			write: func(ctx context.Context, w *throttledWriter, n int) error {
+				return w.Write(ctx, "data", blockID, testTenantID, make([]byte, n))
+			},
+		},
+		{
+			name: "WriteReader",
+			write: func(ctx context.Context, w *throttledWriter, n int) error {
+				return w.WriteReader(ctx, "data", blockID, testTenantID, bytes.NewReader(make([]byte, n)), int64(n))
+			},
+		},
+		{
+			name: "Append",
+			write: func(ctx context.Context, w *throttledWriter, n int) error {
+				_, err := w.Append(ctx, "data", blockID, testTenantID, nil, make([]byte, n))
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &throttledWriter{Writer: &util.MockWriter{}, limiter: newCompactionLimiter(10)}
+
+			// the burst is written right away
+			assert.NoError(t, tt.write(exhaustedContext(t), w, 10))
+
+			// and further bytes have to wait
+			assert.Error(t, tt.write(exhaustedContext(t), w, 1))
+		})
+	}
+
+	// writes of unknown size are not throttled
+	w := &throttledWriter{Writer: &util.MockWriter{}, limiter: newCompactionLimiter(10)}
+	assert.NoError(t, w.Write(exhaustedContext(t), "data", blockID, testTenantID, make([]byte, 10)))
+	assert.NoError(t, w.WriteReader(exhaustedContext(t), "data", blockID, testTenantID, bytes.NewReader(make([]byte, 10)), -1))
+}
+
+func TestThrottledReaderEnforcesLimit(t *testing.T) {
+	blockID := uuid.New()
+
+	// range reads wait before reading
+	r := &throttledReader{Reader: &util.MockReader{}, limiter: newCompactionLimiter(10)}
+	assert.NoError(t, r.ReadRange(exhaustedContext(t), "data", blockID, testTenantID, 0, make([]byte, 10)))
+	assert.Error(t, r.ReadRange(exhaustedContext(t), "data", blockID, testTenantID, 0, make([]byte, 1)))
+
+	// reads of whole objects are accounted for afterwards, even beyond the burst, and delay the next transfer
+	r = &throttledReader{Reader: &util.MockReader{R: make([]byte, 25)}, limiter: newCompactionLimiter(10)}
+	actual, err := r.Read(exhaustedContext(t), "data", blockID, testTenantID)
+	assert.NoError(t, err)
+	assert.Len(t, actual, 25)
+	assert.Error(t, r.ReadRange(exhaustedContext(t), "data", blockID, testTenantID, 0, make([]byte, 1)))
+}
+
+func TestCompactionLimiter(t *testing.T) {
+	l := newCompactionLimiter(100_000)
+
+	// the burst is available immediately
+	start := time.Now()
+	require.NoError(t, l.wait(context.Background(), 100_000))
+	assert.Less(t, time.Since(start).Seconds(), 0.2)
+
+	// bytes read without waiting delay the next transfer
+	l.reserve(50_000)
+	start = time.Now()
+	require.NoError(t, l.wait(context.Background(), 1))
+	assert.Greater(t, time.Since(start).Seconds(), 0.3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, l.wait(ctx, 200_000))
+
+	// transfers larger than the burst are waited for in steps
+	l = newCompactionLimiter(10000)
+	assert.NoError(t, l.wait(context.Background(), 10010))
+
+	// limits below one byte per second still allow single bytes
+	l = newCompactionLimiter(0.5)
+	assert.Equal(t, 1, l.limiter.Burst())
+	assert.NoError(t, l.wait(context.Background(), 1))
+}
@@ -0,0 +1,76 @@
+package tempodb
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+)
+
+const (
+	// MixedVersionsConvert compacts blocks of different versions together into a block of the tenant's version
+	MixedVersionsConvert = "convert"
+	// MixedVersionsSeparate only compacts blocks of the same version together and keeps their version
+	MixedVersionsSeparate = "separate"
+)
+
+// versionedBlockSelector selects blocks of each block version separately so blocks of different versions are
+//  never compacted together.  Like the selectors it wraps it can be used ONLY ONCE.
+type versionedBlockSelector struct {
+	selectors []CompactionBlockSelector
+}
+
+var _ (CompactionBlockSelector) = (*versionedBlockSelector)(nil)
+
+func newVersionedBlockSelector(blocklist []*backend.BlockMeta, newSelector func([]*backend.BlockMeta) CompactionBlockSelector) CompactionBlockSelector {
+	byVersion := map[string][]*backend.BlockMeta{}
+	for _, b := range blocklist {
+		byVersion[b.Version] = append(byVersion[b.Version], b)
+	}
+
+	versions := make([]string, 0, len(byVersion))
+	for v := range byVersion {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+
+	vbs := &versionedBlockSelector{}
+	for _, v := range versions {
+		vbs.selectors = append(vbs.selectors, newSelector(byVersion[v]))
+	}
+
+	return vbs
+}
+
+// BlocksToCompact returns the jobs of one version after the other.  The planner orders the jobs of all
+//  versions by level.
+func (vbs *versionedBlockSelector) BlocksToCompact() ([]*backend.BlockMeta, string) {
+	for len(vbs.selectors) > 0 {
+		blocks, hash := vbs.selectors[0].BlocksToCompact()
+		if len(blocks) > 0 {
+			return blocks, hash
+		}
+		vbs.selectors = vbs.selectors[1:]
+	}
+
+	return nil, ""
+}
+
+// compactionBlockConfig returns the block config of the blocks written when compacting the passed blocks.  By
+//  default the output is written in the tenant's version.  With MixedVersionsSeparate the output keeps the
+//  version of the inputs and inputs of different versions are refused.
+func (rw *readerWriter) compactionBlockConfig(tenantID string, blockMetas []*backend.BlockMeta) (*encoding.BlockConfig, error) {
+	if rw.compactorCfg.MixedVersions != MixedVersionsSeparate {
+		return rw.blockConfigForTenant(tenantID), nil
+	}
+
+	version := blockMetas[0].Version
+	for _, m := range blockMetas {
+		if m.Version != version {
+			return nil, fmt.Errorf("refusing to compact blocks of versions %s and %s together", version, m.Version)
+		}
+	}
+
+	return blockConfigWithVersion(rw.cfg.Block, version), nil
+}
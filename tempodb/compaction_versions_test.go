@@ -0,0 +1,85 @@
+package tempodb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+)
+
+func TestVersionedBlockSelector(t *testing.T) {
+	now := time.Now()
+	blocklist := []*backend.BlockMeta{
+		{BlockID: uuid.New(), TenantID: testTenantID, Version: "v1", EndTime: now},
+		{BlockID: uuid.New(), TenantID: testTenantID, Version: "v2", EndTime: now},
+		{BlockID: uuid.New(), TenantID: testTenantID, Version: "v1", EndTime: now},
+		{BlockID: uuid.New(), TenantID: testTenantID, Version: "v2", EndTime: now},
+		{BlockID: uuid.New(), TenantID: testTenantID, Version: "v0", EndTime: now},
+	}
+
+	newSelector := func(blocklist []*backend.BlockMeta) CompactionBlockSelector {
+		return newTimeWindowBlockSelector(blocklist, time.Hour, 100, 1024, defaultMinInputBlocks, defaultMaxInputBlocks)
+	}
+
+	// without separating versions all blocks are compacted together
+	blocks, _ := newSelector(blocklist).BlocksToCompact()
+	assert.Len(t, blocks, 5)
+
+	selector := newVersionedBlockSelector(blocklist, newSelector)
+
+	var versions []string
+	for {
+		blocks, hash := selector.BlocksToCompact()
+		if len(blocks) == 0 {
+			break
+		}
+		require.Len(t, blocks, 2)
+		assert.Equal(t, blocks[0].Version, blocks[1].Version)
+		assert.NotEmpty(t, hash)
+		versions = append(versions, blocks[0].Version)
+	}
+
+	// the single v0 block has nothing to be compacted with
+	assert.Equal(t, []string{"v1", "v2"}, versions)
+}
+
+func TestCompactionBlockConfig(t *testing.T) {
+	rw := &readerWriter{
+		cfg: &Config{
+			Block: &encoding.BlockConfig{Version: "v2"},
+		},
+		compactorCfg:       &CompactorConfig{},
+		compactorOverrides: &mockOverrides{},
+	}
+
+	v1 := &backend.BlockMeta{Version: "v1"}
+	v2 := &backend.BlockMeta{Version: "v2"}
+
+	// mixed blocks are converted to the tenant's version by default
+	cfg, err := rw.compactionBlockConfig(testTenantID, []*backend.BlockMeta{v1, v2})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", cfg.Version)
+
+	rw.compactorCfg.MixedVersions = MixedVersionsSeparate
+	cfg, err = rw.compactionBlockConfig(testTenantID, []*backend.BlockMeta{v1, v1})
+	require.NoError(t, err)
+	assert.Equal(t, "v1", cfg.Version)
+
+	_, err = rw.compactionBlockConfig(testTenantID, []*backend.BlockMeta{v1, v2})
+	assert.Error(t, err)
+}
+
+func TestValidateCompactorConfig(t *testing.T) {
+	assert.NoError(t, ValidateCompactorConfig(&CompactorConfig{MaxCompactionRange: time.Hour}))
+	assert.NoError(t, ValidateCompactorConfig(&CompactorConfig{MaxCompactionRange: time.Hour, MixedVersions: MixedVersionsConvert}))
+	assert.NoError(t, ValidateCompactorConfig(&CompactorConfig{MaxCompactionRange: time.Hour, MixedVersions: MixedVersionsSeparate}))
+	assert.Error(t, ValidateCompactorConfig(&CompactorConfig{MaxCompactionRange: time.Hour, MixedVersions: "refuse"}))
+	assert.Error(t, ValidateCompactorConfig(&CompactorConfig{MaxCompactionRange: 500 * time.Millisecond}))
+	assert.NoError(t, ValidateCompactorConfig(&CompactorConfig{MaxCompactionRange: time.Hour, TargetBlocksPerWindow: 4, MinBlockBytes: 1 << 20}))
+	assert.Error(t, ValidateCompactorConfig(&CompactorConfig{MaxCompactionRange: time.Hour, TargetBlocksPerWindow: 4}))
+}
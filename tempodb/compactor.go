@@ -46,6 +46,11 @@ var (
 		Name:      "compaction_objects_combined_total",
 		Help:      "Total number of objects combined during compaction.",
 	}, []string{"level"})
+	metricCompactionChecksumErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "compaction_checksum_errors_total",
+		Help:      "Total number of blocks that failed checksum verification before compaction.",
+	})
 )
 
 const (
@@ -53,6 +58,9 @@ const (
 	outputBlocks = 1
 
 	compactionCycle = 30 * time.Second
+
+	// deletionCycleShare is the share of a maintenance cycle spent removing deleted traces
+	deletionCycleShare = 0.5
 )
 
 // todo: pass a context/chan in to cancel this cleanly
@@ -69,48 +77,46 @@ func (rw *readerWriter) doCompaction() {
 		return
 	}
 
-	// Iterate through tenants each cycle
+	// Start with a different tenant each cycle so ties between tenants are broken fairly
 	// Sort tenants for stability (since original map does not guarantee order)
 	sort.Slice(tenants, func(i, j int) bool { return tenants[i].(string) < tenants[j].(string) })
 	rw.compactorTenantOffset = (rw.compactorTenantOffset + 1) % uint(len(tenants))
 
-	tenantID := tenants[rw.compactorTenantOffset].(string)
-	blocklist := rw.blocklist(tenantID)
-
-	blockSelector := newTimeWindowBlockSelector(blocklist,
-		rw.compactorCfg.MaxCompactionRange,
-		rw.compactorCfg.MaxCompactionObjects,
-		rw.compactorCfg.MaxBlockBytes,
-		defaultMinInputBlocks,
-		defaultMaxInputBlocks)
+	tenantIDs := make([]string, 0, len(tenants))
+	for i := range tenants {
+		tenantIDs = append(tenantIDs, tenants[(int(rw.compactorTenantOffset)+i)%len(tenants)].(string))
+	}
 
 	start := time.Now()
 
-	level.Info(rw.logger).Log("msg", "starting compaction cycle", "tenantID", tenantID, "offset", rw.compactorTenantOffset)
-	for {
-		toBeCompacted, hashString := blockSelector.BlocksToCompact()
-		if len(toBeCompacted) == 0 {
-			level.Info(rw.logger).Log("msg", "compaction cycle complete. No more blocks to compact", "tenantID", tenantID)
+	// deleted traces are removed before compaction is planned.  rewritten blocks are swapped in the blocklist.
+	//  removing them is limited to a share of the cycle so a backlog of deletions does not hold up compaction
+	deletionDeadline := start.Add(time.Duration(float64(rw.cfg.BlocklistPoll) * deletionCycleShare))
+	for _, tenantID := range tenantIDs {
+		if !rw.doDeletion(tenantID, deletionDeadline) {
 			break
 		}
-		if !rw.compactorSharder.Owns(hashString) {
-			// continue on this tenant until we find something we own
-			continue
-		}
-		level.Info(rw.logger).Log("msg", "Compacting hash", "hashString", hashString)
-		err := rw.compact(toBeCompacted, tenantID)
+	}
 
-		if err == backend.ErrMetaDoesNotExist {
-			level.Warn(rw.logger).Log("msg", "unable to find meta during compaction.  trying again on this block list", "err", err)
-		} else if err != nil {
-			level.Error(rw.logger).Log("msg", "error during compaction cycle", "err", err)
-			metricCompactionErrors.Inc()
-		}
+	jobs := rw.planCompaction(tenantIDs)
+	level.Info(rw.logger).Log("msg", "starting compaction cycle", "jobs", len(jobs), "offset", rw.compactorTenantOffset)
+	if !rw.runCompactionJobs(jobs, start) {
+		return
+	}
 
-		// after a maintenance cycle bail out
+	level.Info(rw.logger).Log("msg", "compaction cycle complete. No more blocks to compact")
+	for _, tenantID := range tenantIDs {
+		if rw.compactorCfg.SummarizeAfter > 0 {
+			rw.doSummarization(tenantID, start)
+		}
+		if rw.compactorCfg.MigrateBlocks {
+			rw.doMigration(tenantID, start)
+		}
+		if rw.compactorCfg.ArchiveAfter > 0 && rw.compactorCfg.ArchiveStorageClass != "" {
+			rw.doArchiving(tenantID, start)
+		}
 		if start.Add(rw.cfg.BlocklistPoll).Before(time.Now()) {
-			level.Info(rw.logger).Log("msg", "compacted blocks for a maintenance cycle, bailing out", "tenantID", tenantID)
-			break
+			return
 		}
 	}
 }
@@ -121,17 +127,21 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 	level.Debug(rw.logger).Log("msg", "beginning compaction", "num blocks compacting", len(blockMetas))
 
 	// todo - add timeout?
-	ctx := context.Background()
+	ctx := backend.WithCaller(context.Background(), backend.CallerCompactor)
 
 	if len(blockMetas) == 0 {
 		return nil
 	}
 
+	blockCfg, err := rw.compactionBlockConfig(tenantID, blockMetas)
+	if err != nil {
+		return err
+	}
+
 	compactionLevel := compactionLevelForBlocks(blockMetas)
 	compactionLevelLabel := strconv.Itoa(int(compactionLevel))
 	nextCompactionLevel := compactionLevel + 1
 
-	var err error
 	bookmarks := make([]*bookmark, 0, len(blockMetas))
 
 	// cleanup compaction
@@ -142,12 +152,15 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 		}
 	}()
 
+	// traces marked for deletion as of the last poll are dropped while rewriting
+	deleted := rw.deletedTraces(tenantID)
+
 	var totalRecords int
 	for _, blockMeta := range blockMetas {
 		level.Info(rw.logger).Log("msg", "compacting block", "block", fmt.Sprintf("%+v", blockMeta))
 		totalRecords += blockMeta.TotalObjects
 
-		block, err := encoding.NewBackendBlock(blockMeta, rw.r)
+		block, err := rw.openBlock(ctx, blockMeta)
 		if err != nil {
 			return err
 		}
@@ -167,8 +180,14 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 
 	recordsPerBlock := (totalRecords / outputBlocks)
 	var newCompactedBlocks []*backend.BlockMeta
-	var currentBlock *encoding.CompactorBlock
-	var tracker backend.AppendTracker
+
+	// objects are routed to one output block per shard.  without sharding there is only shard 0
+	shardCount := outputShardCount(blockMetas, rw.compactorCfg.SplitShards)
+	estimatedObjects := recordsPerBlock
+	if isSplitJob(blockMetas, shardCount) {
+		estimatedObjects = recordsPerBlock/int(shardCount) + 1
+	}
+	currentBlocks := map[uint32]*compactionOutput{}
 
 	for !allDone(ctx, bookmarks) {
 		var lowestID []byte
@@ -206,46 +225,67 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 			return fmt.Errorf("failed to find a lowest object in compaction")
 		}
 
+		if isDeleted(deleted, lowestID) {
+			metricCompactionObjectsDeleted.WithLabelValues(tenantID).Inc()
+			lowestBookmark.clear()
+			continue
+		}
+
 		// make a new block if necessary
-		if currentBlock == nil {
-			currentBlock, err = encoding.NewCompactorBlock(rw.cfg.Block, uuid.New(), tenantID, blockMetas, recordsPerBlock)
+		shard := shardForID(lowestID, shardCount)
+		current := currentBlocks[shard]
+		if current == nil {
+			block, err := encoding.NewCompactorBlock(blockCfg, uuid.New(), tenantID, blockMetas, estimatedObjects)
 			if err != nil {
 				return errors.Wrap(err, "error making new compacted block")
 			}
-			currentBlock.BlockMeta().CompactionLevel = nextCompactionLevel
-			newCompactedBlocks = append(newCompactedBlocks, currentBlock.BlockMeta())
+			block.BlockMeta().CompactionLevel = nextCompactionLevel
+			block.BlockMeta().Summary = allSummaries(blockMetas)
+			if shardCount > 0 {
+				block.BlockMeta().ShardIndex = shard
+				block.BlockMeta().ShardCount = shardCount
+			}
+			newCompactedBlocks = append(newCompactedBlocks, block.BlockMeta())
+
+			current = &compactionOutput{block: block}
+			currentBlocks[shard] = current
 		}
 
 		// writing to the current block will cause the id to escape the iterator so we need to make a copy of it
 		writeID := append([]byte(nil), lowestID...)
-		err = currentBlock.AddObject(writeID, lowestObject)
+		err = current.block.AddObject(writeID, lowestObject)
 		if err != nil {
 			return err
 		}
 		lowestBookmark.clear()
 
 		// write partial block
-		if currentBlock.CurrentBufferLength() >= int(rw.compactorCfg.FlushSizeBytes) {
-			tracker, err = appendBlock(rw, tracker, currentBlock)
+		if current.block.CurrentBufferLength() >= int(rw.compactorCfg.FlushSizeBytes) {
+			current.tracker, err = appendBlock(ctx, rw, current.tracker, current.block)
 			if err != nil {
 				return errors.Wrap(err, "error writing partial block")
 			}
 		}
 
 		// ship block to backend if done
-		if currentBlock.Length() >= recordsPerBlock {
-			err = finishBlock(rw, tracker, currentBlock)
+		if current.block.Length() >= recordsPerBlock {
+			err = finishBlock(ctx, rw, current.tracker, current.block)
 			if err != nil {
 				return errors.Wrap(err, "error shipping block to backend")
 			}
-			currentBlock = nil
-			tracker = nil
+			delete(currentBlocks, shard)
 		}
 	}
 
-	// ship final block to backend
-	if currentBlock != nil {
-		err = finishBlock(rw, tracker, currentBlock)
+	// ship final blocks to backend
+	shards := make([]uint32, 0, len(currentBlocks))
+	for shard := range currentBlocks {
+		shards = append(shards, shard)
+	}
+	sort.Slice(shards, func(i, j int) bool { return shards[i] < shards[j] })
+	for _, shard := range shards {
+		current := currentBlocks[shard]
+		err = finishBlock(ctx, rw, current.tracker, current.block)
 		if err != nil {
 			return errors.Wrap(err, "error shipping block to backend")
 		}
@@ -259,11 +299,17 @@ func (rw *readerWriter) compact(blockMetas []*backend.BlockMeta, tenantID string
 	return nil
 }
 
-func appendBlock(rw *readerWriter, tracker backend.AppendTracker, block *encoding.CompactorBlock) (backend.AppendTracker, error) {
+// compactionOutput is a block being written by compaction and the tracker of its partial writes
+type compactionOutput struct {
+	block   *encoding.CompactorBlock
+	tracker backend.AppendTracker
+}
+
+func appendBlock(ctx context.Context, rw *readerWriter, tracker backend.AppendTracker, block *encoding.CompactorBlock) (backend.AppendTracker, error) {
 	compactionLevelLabel := strconv.Itoa(int(block.BlockMeta().CompactionLevel - 1))
 	metricCompactionObjectsWritten.WithLabelValues(compactionLevelLabel).Add(float64(block.CurrentBufferedObjects()))
 
-	tracker, bytesFlushed, err := block.FlushBuffer(context.TODO(), tracker, rw.w)
+	tracker, bytesFlushed, err := block.FlushBuffer(ctx, tracker, rw.compactorW)
 	if err != nil {
 		return nil, err
 	}
@@ -272,10 +318,10 @@ func appendBlock(rw *readerWriter, tracker backend.AppendTracker, block *encodin
 	return tracker, nil
 }
 
-func finishBlock(rw *readerWriter, tracker backend.AppendTracker, block *encoding.CompactorBlock) error {
+func finishBlock(ctx context.Context, rw *readerWriter, tracker backend.AppendTracker, block *encoding.CompactorBlock) error {
 	level.Info(rw.logger).Log("msg", "writing compacted block", "block", fmt.Sprintf("%+v", block.BlockMeta()))
 
-	bytesFlushed, err := block.Complete(context.TODO(), tracker, rw.w)
+	bytesFlushed, err := block.Complete(ctx, tracker, rw.compactorW)
 	if err != nil {
 		return err
 	}
@@ -294,6 +340,17 @@ func allDone(ctx context.Context, bookmarks []*bookmark) bool {
 	return true
 }
 
+// allSummaries returns true if all passed blocks are summary blocks.  Compacting summary blocks with full blocks
+//  results in a full block that is summarized again once it is old enough.
+func allSummaries(blockMetas []*backend.BlockMeta) bool {
+	for _, m := range blockMetas {
+		if !m.Summary {
+			return false
+		}
+	}
+	return true
+}
+
 func compactionLevelForBlocks(blockMetas []*backend.BlockMeta) uint8 {
 	level := uint8(0)
 
@@ -307,23 +364,126 @@ func compactionLevelForBlocks(blockMetas []*backend.BlockMeta) uint8 {
 }
 
 func markCompacted(rw *readerWriter, tenantID string, oldBlocks []*backend.BlockMeta, newBlocks []*backend.BlockMeta) {
+	compactedTime := time.Now()
+	marked := make([]uuid.UUID, 0, len(oldBlocks))
 	for _, meta := range oldBlocks {
 		// Mark in the backend
 		if err := rw.c.MarkBlockCompacted(meta.BlockID, tenantID); err != nil {
 			level.Error(rw.logger).Log("msg", "unable to mark block compacted", "blockID", meta.BlockID, "tenantID", tenantID, "err", err)
 			metricCompactionErrors.Inc()
+			continue
 		}
+		marked = append(marked, meta.BlockID)
 	}
+	rw.writeCompactionMarker(backend.WithCaller(context.Background(), backend.CallerCompactor), tenantID, marked, compactedTime)
 
 	// Converted outgoing blocks into compacted entries.
 	newCompactions := make([]*backend.CompactedBlockMeta, 0, len(oldBlocks))
 	for _, newBlock := range oldBlocks {
 		newCompactions = append(newCompactions, &backend.CompactedBlockMeta{
 			BlockMeta:     *newBlock,
-			CompactedTime: time.Now(),
+			CompactedTime: compactedTime,
 		})
 	}
 
 	// Update blocklist in memory
 	rw.updateBlocklist(tenantID, newBlocks, oldBlocks, newCompactions)
 }
+
+// compactionWindowForTenant returns the time window blocks of the passed tenant are compacted in
+func (rw *readerWriter) compactionWindowForTenant(tenantID string) time.Duration {
+	// windows are counted in seconds.  shorter windows are rejected when overrides are loaded
+	if w := rw.compactorOverrides.CompactionWindowForTenant(tenantID); w >= time.Second {
+		return w
+	}
+	return rw.compactorCfg.MaxCompactionRange
+}
+
+// maxBlockBytesForTenant returns the maximum size of compacted blocks of the passed tenant
+func (rw *readerWriter) maxBlockBytesForTenant(tenantID string) uint64 {
+	if b := rw.compactorOverrides.MaxBlockBytesForTenant(tenantID); b > 0 {
+		return b
+	}
+	return rw.compactorCfg.MaxBlockBytes
+}
+
+// maxCompactionObjectsForTenant returns the maximum number of traces in compacted blocks of the passed tenant
+func (rw *readerWriter) maxCompactionObjectsForTenant(tenantID string) int {
+	if o := rw.compactorOverrides.MaxCompactionObjectsForTenant(tenantID); o > 0 {
+		return o
+	}
+	return rw.compactorCfg.MaxCompactionObjects
+}
+
+// blockConfigForTenant returns the block config to use for blocks created by the compactor for the passed tenant
+func (rw *readerWriter) blockConfigForTenant(tenantID string) *encoding.BlockConfig {
+	return blockConfigWithVersion(rw.cfg.Block, rw.compactorOverrides.BlockVersionForTenant(tenantID))
+}
+
+// openBlock opens a block to rewrite it.  With VerifyChecksums set the objects of the block are verified first.  A
+//  block that fails verification is excluded from compaction from then on so the blocks around it are still
+//  compacted.
+func (rw *readerWriter) openBlock(ctx context.Context, meta *backend.BlockMeta) (*encoding.BackendBlock, error) {
+	block, err := encoding.NewBackendBlock(meta, rw.compactorR)
+	if err != nil {
+		return nil, err
+	}
+
+	if !rw.compactorCfg.VerifyChecksums {
+		return block, nil
+	}
+
+	err = block.VerifyChecksums(ctx)
+	if errors.Is(err, encoding.ErrChecksumMismatch) {
+		level.Error(rw.logger).Log("msg", "block failed checksum verification.  excluding it from compaction", "blockID", meta.BlockID, "tenantID", meta.TenantID, "err", err)
+		metricCompactionChecksumErrors.Inc()
+		rw.markCorrupt(meta.TenantID, meta.BlockID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+func (rw *readerWriter) markCorrupt(tenantID string, blockID uuid.UUID) {
+	rw.corruptBlocksMtx.Lock()
+	defer rw.corruptBlocksMtx.Unlock()
+
+	if rw.corruptBlocks == nil {
+		rw.corruptBlocks = map[string]map[uuid.UUID]struct{}{}
+	}
+	if rw.corruptBlocks[tenantID] == nil {
+		rw.corruptBlocks[tenantID] = map[uuid.UUID]struct{}{}
+	}
+	rw.corruptBlocks[tenantID][blockID] = struct{}{}
+}
+
+// withoutCorruptBlocks returns the passed polled blocklist of a tenant except the blocks that failed checksum
+//  verification.  Corrupt blocks that are no longer in the blocklist are forgotten.
+func (rw *readerWriter) withoutCorruptBlocks(tenantID string, blocklist []*backend.BlockMeta) []*backend.BlockMeta {
+	rw.corruptBlocksMtx.Lock()
+	defer rw.corruptBlocksMtx.Unlock()
+
+	corrupt := rw.corruptBlocks[tenantID]
+	if len(corrupt) == 0 {
+		return blocklist
+	}
+
+	blocks := make([]*backend.BlockMeta, 0, len(blocklist))
+	listed := make(map[uuid.UUID]struct{}, len(corrupt))
+	for _, b := range blocklist {
+		if _, ok := corrupt[b.BlockID]; ok {
+			listed[b.BlockID] = struct{}{}
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+
+	if len(listed) == 0 {
+		delete(rw.corruptBlocks, tenantID)
+	} else {
+		rw.corruptBlocks[tenantID] = listed
+	}
+	return blocks
+}
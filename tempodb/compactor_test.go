@@ -2,6 +2,7 @@ package tempodb
 
 import (
 	"context"
+	"errors"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -40,13 +41,33 @@ func (m *mockSharder) Combine(objA []byte, objB []byte) []byte {
 }
 
 type mockOverrides struct {
-	blockRetention time.Duration
+	blockRetention       time.Duration
+	blockVersion         string
+	compactionWindow     time.Duration
+	maxBlockBytes        uint64
+	maxCompactionObjects int
 }
 
 func (m *mockOverrides) BlockRetentionForTenant(_ string) time.Duration {
 	return m.blockRetention
 }
 
+func (m *mockOverrides) BlockVersionForTenant(_ string) string {
+	return m.blockVersion
+}
+
+func (m *mockOverrides) CompactionWindowForTenant(_ string) time.Duration {
+	return m.compactionWindow
+}
+
+func (m *mockOverrides) MaxBlockBytesForTenant(_ string) uint64 {
+	return m.maxBlockBytes
+}
+
+func (m *mockOverrides) MaxCompactionObjectsForTenant(_ string) int {
+	return m.maxCompactionObjects
+}
+
 func TestCompaction(t *testing.T) {
 	tempDir, err := ioutil.TempDir("/tmp", "")
 	defer os.RemoveAll(tempDir)
@@ -253,6 +274,13 @@ func TestSameIDCompaction(t *testing.T) {
 
 	checkBlocklists(t, uuid.Nil, blockCount-blocksPerCompaction, inputBlocks, rw)
 
+	// the compacted blocks are recorded in one compaction marker
+	markers := rw.compactionMarkersForTenant(testTenantID)
+	require.Len(t, markers, 1)
+	for _, m := range markers {
+		assert.Len(t, m.BlockIDs, inputBlocks)
+	}
+
 	// do we have the right number of records
 	var records int
 	for _, meta := range rw.blockLists[testTenantID] {
@@ -484,3 +512,160 @@ func cutTestBlocks(t *testing.T, w Writer, tenantID string, blockCount int, reco
 func makeTraceID(i int, j int) []byte {
 	return []byte{byte(i), byte(j), 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
 }
+
+func TestCompactionHonorsTenantOverrides(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_64k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	assert.NoError(t, err)
+
+	overrides := &mockOverrides{maxCompactionObjects: 2}
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:       10,
+		MaxCompactionRange:   24 * time.Hour,
+		MaxCompactionObjects: 1000,
+		MaxBlockBytes:        1024 * 1024 * 1024,
+	}, &mockSharder{}, overrides)
+
+	cutTestBlocks(t, w, testTenantID, 5, 1)
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+	assert.Equal(t, 5, len(rw.blockLists[testTenantID]))
+
+	// the tenant's limit only allows compacting two blocks at a time
+	rw.doCompaction()
+	assert.Equal(t, 4, len(rw.blockLists[testTenantID]))
+
+	// without the override the remaining level 0 blocks are compacted at once
+	overrides.maxCompactionObjects = 0
+	rw.doCompaction()
+	assert.Equal(t, 2, len(rw.blockLists[testTenantID]))
+
+	// the window falls back to the compactor config
+	assert.Equal(t, 24*time.Hour, rw.compactionWindowForTenant(testTenantID))
+	overrides.compactionWindow = time.Hour
+	assert.Equal(t, time.Hour, rw.compactionWindowForTenant(testTenantID))
+}
+
+func TestInvalidTenantCompactionOverrides(t *testing.T) {
+	rw := &readerWriter{
+		compactorCfg: &CompactorConfig{
+			MaxCompactionRange:   time.Hour,
+			MaxBlockBytes:        100,
+			MaxCompactionObjects: 10,
+		},
+		compactorOverrides: &mockOverrides{
+			compactionWindow:     500 * time.Millisecond,
+			maxCompactionObjects: -1,
+		},
+	}
+
+	// invalid overrides fall back to the compactor config instead of breaking compaction
+	assert.Equal(t, time.Hour, rw.compactionWindowForTenant(testTenantID))
+	assert.Equal(t, int64(time.Now().Unix()/3600), rw.compactionWindowForTime(testTenantID, time.Now()))
+	assert.Equal(t, uint64(100), rw.maxBlockBytesForTenant(testTenantID))
+	assert.Equal(t, 10, rw.maxCompactionObjectsForTenant(testTenantID))
+}
+
+func TestCompactionExcludesCorruptBlocks(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      24 * time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: 0,
+		MaxCompactionObjects:    1000,
+		MaxBlockBytes:           1024 * 1024 * 1024,
+		VerifyChecksums:         true,
+	}, &mockSharder{}, &mockOverrides{})
+
+	cutTestBlocks(t, w, testTenantID, 3, 2)
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+	blocklist := rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 3)
+
+	// flip a byte in the data object of one block
+	corrupt := blocklist[0]
+	dataFile := path.Join(tempDir, "traces", testTenantID, corrupt.BlockID.String(), encoding.NameObjects)
+	data, err := ioutil.ReadFile(dataFile)
+	require.NoError(t, err)
+	data[len(data)/2] ^= 0xFF
+	require.NoError(t, ioutil.WriteFile(dataFile, data, 0644))
+
+	errorsStart, err := test.GetCounterValue(metricCompactionChecksumErrors)
+	require.NoError(t, err)
+
+	err = rw.compact(blocklist, testTenantID)
+	assert.True(t, errors.Is(err, encoding.ErrChecksumMismatch))
+
+	errorsEnd, err := test.GetCounterValue(metricCompactionChecksumErrors)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), errorsEnd-errorsStart)
+
+	// the corrupt block is no longer selected and the others are still compacted
+	blockSelector, _ := rw.blockSelectorForTenant(testTenantID)
+	toCompact, _ := blockSelector.BlocksToCompact()
+	require.Len(t, toCompact, 2)
+	for _, b := range toCompact {
+		assert.NotEqual(t, corrupt.BlockID, b.BlockID)
+	}
+
+	require.NoError(t, rw.compact(toCompact, testTenantID))
+	rw.pollBlocklist()
+	assert.Len(t, rw.blocklist(testTenantID), 2)
+
+	// the corrupt block is forgotten once it is no longer polled
+	require.NoError(t, rw.c.MarkBlockCompacted(corrupt.BlockID, testTenantID))
+	rw.pollBlocklist()
+	assert.Len(t, rw.withoutCorruptBlocks(testTenantID, rw.blocklist(testTenantID)), 1)
+	assert.Len(t, rw.corruptBlocks, 0)
+}
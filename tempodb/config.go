@@ -3,6 +3,7 @@ package tempodb
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/grafana/tempo/tempodb/backend/azure"
@@ -10,7 +11,10 @@ import (
 	"github.com/grafana/tempo/tempodb/backend/cache/redis"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/backend/retry"
 	"github.com/grafana/tempo/tempodb/backend/s3"
+	"github.com/grafana/tempo/tempodb/backend/swift"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/pool"
 	"github.com/grafana/tempo/tempodb/wal"
@@ -19,6 +23,11 @@ import (
 const DefaultBlocklistPollConcurrency = uint(50)
 const DefaultRetentionConcurrency = uint(10)
 
+// PollOverrides returns the blocklist polling settings of a tenant.  0 uses the config.
+type PollOverrides interface {
+	BlocklistPollStaleTolerance(tenantID string) time.Duration
+}
+
 // Config holds the entirety of tempodb configuration
 type Config struct {
 	Pool  *pool.Config          `yaml:"pool,omitempty"`
@@ -27,6 +36,12 @@ type Config struct {
 
 	BlocklistPoll            time.Duration `yaml:"blocklist_poll"`
 	BlocklistPollConcurrency uint          `yaml:"blocklist_poll_concurrency"`
+	// BlocklistPollStaleTolerance is how long the meta of a live block is reused before it is fetched again.  Within
+	//  the tolerance blocks leave the blocklist on the next poll if a compaction marker lists them.  Compacted block
+	//  metas are never fetched again.
+	BlocklistPollStaleTolerance time.Duration `yaml:"blocklist_poll_stale_tolerance"`
+	// PollOverrides overrides the stale tolerance per tenant.  It is set by the app and not configurable.
+	PollOverrides PollOverrides `yaml:"-"`
 
 	// backends
 	Backend string        `yaml:"backend"`
@@ -34,6 +49,19 @@ type Config struct {
 	GCS     *gcs.Config   `yaml:"gcs"`
 	S3      *s3.Config    `yaml:"s3"`
 	Azure   *azure.Config `yaml:"azure"`
+	Swift   *swift.Config `yaml:"swift"`
+
+	// optional backend all blocks are replicated to
+	Replica *ReplicaConfig `yaml:"replica"`
+
+	// optional backend blocks are read from instead of the backend they are written to
+	ReadBackend *BackendConfig `yaml:"read_backend"`
+
+	// retries of failed backend operations
+	Retry *retry.Config `yaml:"retry"`
+
+	// per tenant limits on backend reads
+	ReadLimits *throttle.Config `yaml:"read_limits"`
 
 	// caches
 	Cache     string            `yaml:"cache"`
@@ -41,6 +69,26 @@ type Config struct {
 	Redis     *redis.Config     `yaml:"redis"`
 }
 
+// BackendConfig configures a backend in addition to the main one.  These backends have no flags so their
+//  configuration is nil unless set in the config file.
+type BackendConfig struct {
+	Backend string        `yaml:"backend"`
+	Local   *local.Config `yaml:"local"`
+	GCS     *gcs.Config   `yaml:"gcs"`
+	S3      *s3.Config    `yaml:"s3"`
+	Azure   *azure.Config `yaml:"azure"`
+	Swift   *swift.Config `yaml:"swift"`
+}
+
+// ReplicaConfig configures a second backend that all writes are replicated to.  Reads fall back to the replica
+//  if the primary backend fails.
+type ReplicaConfig struct {
+	BackendConfig `yaml:",inline"`
+
+	// FailOnError fails writes if the replica can not be written.  By default replica errors are only logged.
+	FailOnError bool `yaml:"fail_on_error"`
+}
+
 // CompactorConfig contains compaction configuration options
 type CompactorConfig struct {
 	ChunkSizeBytes          uint32        `yaml:"chunk_size_bytes"` // todo: do we need this?
@@ -51,6 +99,73 @@ type CompactorConfig struct {
 	BlockRetention          time.Duration `yaml:"block_retention"`
 	CompactedBlockRetention time.Duration `yaml:"compacted_block_retention"`
 	RetentionConcurrency    uint          `yaml:"retention_concurrency"`
+	MigrateBlocks           bool          `yaml:"migrate_blocks"`
+	VerifyChecksums         bool          `yaml:"verify_checksums"`
+	ArchiveAfter            time.Duration `yaml:"archive_after"`
+	ArchiveStorageClass     string        `yaml:"archive_storage_class"`
+	SplitShards             int           `yaml:"split_shards"`
+	SummarizeAfter          time.Duration `yaml:"summarize_after"`
+	Concurrency             int           `yaml:"concurrency"`
+	LevelConcurrency        map[int]int   `yaml:"level_concurrency"`
+	MaxBytesPerSecond       float64       `yaml:"max_bytes_per_second"`
+	MixedVersions           string        `yaml:"mixed_versions"`
+	TargetBlocksPerWindow   int           `yaml:"target_blocks_per_window"`
+	MinBlockBytes           uint64        `yaml:"min_block_bytes"`
+}
+
+// ValidateCompactorConfig returns an error if the compactor config is invalid
+func ValidateCompactorConfig(cfg *CompactorConfig) error {
+	if cfg.MaxCompactionRange < time.Second {
+		return fmt.Errorf("compaction_window must be at least 1s, got %s", cfg.MaxCompactionRange)
+	}
+
+	// without a lower bound a tiny target keeps the backlog of small tenants from ever being compacted
+	if cfg.TargetBlocksPerWindow > 0 && cfg.MinBlockBytes == 0 {
+		return errors.New("min_block_bytes must be set when target_blocks_per_window is set")
+	}
+
+	switch cfg.MixedVersions {
+	case "", MixedVersionsConvert, MixedVersionsSeparate:
+	default:
+		return fmt.Errorf("mixed_versions must be one of %s or %s, got %s", MixedVersionsConvert, MixedVersionsSeparate, cfg.MixedVersions)
+	}
+
+	return nil
+}
+
+// offlineStorageClasses are the storage classes of each backend whose objects must be restored before they can be
+//  read.  Archived blocks are searched like any other block so they must be readable at all times.
+var offlineStorageClasses = map[string][]string{
+	"s3":    {"GLACIER", "DEEP_ARCHIVE"},
+	"azure": {"Archive"},
+}
+
+// ValidateArchiveConfig returns an error if blocks are archived in a backend that does not support archiving or
+//  to a storage class that can not be read without restoring it first
+func ValidateArchiveConfig(compactorCfg *CompactorConfig, cfg *Config) error {
+	if compactorCfg.ArchiveAfter <= 0 {
+		return nil
+	}
+
+	backends := []string{cfg.Backend}
+	if cfg.Replica != nil && cfg.Replica.Backend != "" {
+		backends = append(backends, cfg.Replica.Backend)
+	}
+
+	for _, b := range backends {
+		switch b {
+		case "local", "swift":
+			return fmt.Errorf("archive_after is not supported by the %s backend", b)
+		}
+
+		for _, class := range offlineStorageClasses[b] {
+			if strings.EqualFold(compactorCfg.ArchiveStorageClass, class) {
+				return fmt.Errorf("archive_storage_class %s of the %s backend can not be read without restoring objects", compactorCfg.ArchiveStorageClass, b)
+			}
+		}
+	}
+
+	return nil
 }
 
 func validateConfig(cfg *Config) error {
@@ -67,5 +182,44 @@ func validateConfig(cfg *Config) error {
 		return fmt.Errorf("block config validation failed: %w", err)
 	}
 
+	if cfg.Replica != nil && cfg.Replica.Backend != "" {
+		err = validateBackendConfig(&cfg.Replica.BackendConfig)
+		if err != nil {
+			return fmt.Errorf("replica config validation failed: %w", err)
+		}
+	}
+
+	if cfg.ReadBackend != nil && cfg.ReadBackend.Backend != "" {
+		err = validateBackendConfig(cfg.ReadBackend)
+		if err != nil {
+			return fmt.Errorf("read backend config validation failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// validateBackendConfig confirms the selected backend is configured
+func validateBackendConfig(cfg *BackendConfig) error {
+	var configured bool
+	switch cfg.Backend {
+	case "local":
+		configured = cfg.Local != nil
+	case "gcs":
+		configured = cfg.GCS != nil
+	case "s3":
+		configured = cfg.S3 != nil
+	case "azure":
+		configured = cfg.Azure != nil
+	case "swift":
+		configured = cfg.Swift != nil
+	default:
+		return fmt.Errorf("unknown backend %s", cfg.Backend)
+	}
+
+	if !configured {
+		return fmt.Errorf("%s backend config should be non-nil", cfg.Backend)
+	}
+
 	return nil
 }
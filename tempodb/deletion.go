@@ -0,0 +1,146 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+var (
+	metricDeletionBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "deletion_blocks_total",
+		Help:      "Total number of blocks rewritten because they contained deleted traces.",
+	})
+	metricDeletionErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "deletion_errors_total",
+		Help:      "Total number of errors occurring while removing deleted traces from blocks.",
+	})
+)
+
+// doDeletion rewrites the blocks of a tenant that still contain traces marked for deletion.  Blocks are searched
+//  using their bloom filters.  Blocks in the active compaction window are skipped as compaction drops deleted
+//  traces.  A block is searched again only once new tombstones are polled.  It bails out before searching
+//  a block once deadline has passed and then returns false.
+func (rw *readerWriter) doDeletion(tenantID string, deadline time.Time) bool {
+	ctx := backend.WithCaller(context.Background(), backend.CallerCompactor)
+
+	all := rw.tombstonesForTenant(tenantID)
+	blocklist := rw.withoutCorruptBlocks(tenantID, rw.blocklist(tenantID))
+	checked := rw.pruneDeletionChecked(tenantID, blocklist)
+	if len(all) == 0 {
+		return true
+	}
+
+	activeWindow := rw.compactionWindowForTime(tenantID, time.Now().Add(-activeWindowDuration))
+
+	var ids []common.ID
+	var newest time.Time
+	for _, t := range all {
+		for hexID := range t.TraceIDs {
+			id, err := hex.DecodeString(hexID)
+			if err != nil {
+				continue
+			}
+			ids = append(ids, id)
+		}
+		if t.polled.After(newest) {
+			newest = t.polled
+		}
+	}
+
+	for _, b := range blocklist {
+		if t, ok := checked[b.BlockID]; ok && !t.Before(newest) {
+			continue
+		}
+
+		// blocks in the active window are about to be compacted which drops deleted traces as well
+		if rw.compactionWindowForTime(tenantID, b.EndTime) >= activeWindow {
+			continue
+		}
+		if !rw.ownsBlock(tenantID, b) {
+			continue
+		}
+
+		if deadline.Before(time.Now()) {
+			level.Info(rw.logger).Log("msg", "removed deleted traces for a maintenance cycle, bailing out", "tenantID", tenantID)
+			return false
+		}
+
+		contains, err := rw.containsAny(ctx, b, ids)
+		if err != nil {
+			level.Error(rw.logger).Log("msg", "error searching block for deleted traces", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricDeletionErrors.Inc()
+			continue
+		}
+
+		if !contains {
+			checked[b.BlockID] = newest
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "rewriting block to remove deleted traces", "blockID", b.BlockID, "tenantID", tenantID)
+		err = rw.rewriteBlock(ctx, b, false)
+		if err == backend.ErrMetaDoesNotExist {
+			level.Warn(rw.logger).Log("msg", "unable to find meta while removing deleted traces", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+		} else if err != nil {
+			level.Error(rw.logger).Log("msg", "error removing deleted traces", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricDeletionErrors.Inc()
+		} else {
+			metricDeletionBlocks.Inc()
+		}
+	}
+
+	return true
+}
+
+// containsAny returns true if any of the passed ids is in the block.  Ids outside of the id range of the block
+//  are skipped without reading it.
+func (rw *readerWriter) containsAny(ctx context.Context, meta *backend.BlockMeta, ids []common.ID) (bool, error) {
+	inRange := make([]common.ID, 0, len(ids))
+	for _, id := range ids {
+		if bytes.Compare(id, meta.MinID) >= 0 && bytes.Compare(id, meta.MaxID) <= 0 {
+			inRange = append(inRange, id)
+		}
+	}
+	if len(inRange) == 0 {
+		return false, nil
+	}
+
+	// checksums are verified once the block is rewritten
+	block, err := encoding.NewBackendBlock(meta, rw.compactorR)
+	if err != nil {
+		return false, err
+	}
+
+	return block.ContainsAny(ctx, inRange)
+}
+
+// pruneDeletionChecked forgets the blocks of the tenant that are no longer in its blocklist
+func (rw *readerWriter) pruneDeletionChecked(tenantID string, blocklist []*backend.BlockMeta) map[uuid.UUID]time.Time {
+	if rw.deletionChecked == nil {
+		rw.deletionChecked = map[string]map[uuid.UUID]time.Time{}
+	}
+
+	previous := rw.deletionChecked[tenantID]
+	checked := make(map[uuid.UUID]time.Time, len(previous))
+	for _, b := range blocklist {
+		if t, ok := previous[b.BlockID]; ok {
+			checked[b.BlockID] = t
+		}
+	}
+	rw.deletionChecked[tenantID] = checked
+
+	return checked
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/cespare/xxhash"
 	"github.com/opentracing/opentracing-go"
 	willf_bloom "github.com/willf/bloom"
 
@@ -12,6 +13,9 @@ import (
 	"github.com/grafana/tempo/tempodb/encoding/common"
 )
 
+// verifyChunkSizeBytes is the size of the reads used to checksum the data object
+const verifyChunkSizeBytes = 1024 * 1024
+
 // BackendBlock represents a block already in the backend.
 type BackendBlock struct {
 	encoding versionedEncoding
@@ -23,17 +27,13 @@ type BackendBlock struct {
 // NewBackendBlock returns a BackendBlock for the given backend.BlockMeta
 //  It is version aware.
 func NewBackendBlock(meta *backend.BlockMeta, r backend.Reader) (*BackendBlock, error) {
-	var encoding versionedEncoding
+	if meta.Version == "" {
+		return nil, fmt.Errorf("block %s has no version", meta.BlockID)
+	}
 
-	switch meta.Version {
-	case "v0":
-		encoding = v0Encoding{}
-	case "v1":
-		encoding = v1Encoding{}
-	case "v2":
-		encoding = v2Encoding{}
-	default:
-		return nil, fmt.Errorf("%s is not a valid block version", meta.Version)
+	encoding, err := encodingForVersion(meta.Version)
+	if err != nil {
+		return nil, err
 	}
 
 	return &BackendBlock{
@@ -56,11 +56,53 @@ func (b *BackendBlock) Find(ctx context.Context, id common.ID) ([]byte, error) {
 
 	span.SetTag("block", b.meta.BlockID.String())
 
-	shardKey := common.ShardKeyForTraceID(id)
-	blockID := b.meta.BlockID
-	tenantID := b.meta.TenantID
+	filter, err := b.bloom(ctx, common.ShardKeyForTraceID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if !filter.Test(id) {
+		return nil, nil
+	}
 
-	bloomBytes, err := b.reader.Read(ctx, bloomName(shardKey), blockID, tenantID)
+	return b.findInIndex(ctx, id)
+}
+
+// ContainsAny returns true if any of the passed ids is in the block.  Every bloom shard is read at most once and
+//  only ids that pass the bloom filter are searched for.
+func (b *BackendBlock) ContainsAny(ctx context.Context, ids []common.ID) (bool, error) {
+	idsByShard := map[int][]common.ID{}
+	for _, id := range ids {
+		shardKey := common.ShardKeyForTraceID(id)
+		idsByShard[shardKey] = append(idsByShard[shardKey], id)
+	}
+
+	for shardKey, shardIDs := range idsByShard {
+		filter, err := b.bloom(ctx, shardKey)
+		if err != nil {
+			return false, err
+		}
+
+		for _, id := range shardIDs {
+			if !filter.Test(id) {
+				continue
+			}
+
+			obj, err := b.findInIndex(ctx, id)
+			if err != nil {
+				return false, err
+			}
+			if obj != nil {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (b *BackendBlock) bloom(ctx context.Context, shardKey int) (*willf_bloom.BloomFilter, error) {
+	bloomBytes, err := b.reader.Read(ctx, bloomName(shardKey), b.meta.BlockID, b.meta.TenantID)
 	if err != nil {
 		return nil, fmt.Errorf("error retrieving bloom (%s, %s): %w", b.meta.TenantID, b.meta.BlockID, err)
 	}
@@ -71,17 +113,18 @@ func (b *BackendBlock) Find(ctx context.Context, id common.ID) ([]byte, error) {
 		return nil, fmt.Errorf("error parsing bloom (%s, %s): %w", b.meta.TenantID, b.meta.BlockID, err)
 	}
 
-	if !filter.Test(id) {
-		return nil, nil
-	}
+	return filter, nil
+}
 
+// findInIndex searches the index and data of the block for the ID and returns an object if found
+func (b *BackendBlock) findInIndex(ctx context.Context, id common.ID) ([]byte, error) {
 	indexReaderAt := backend.NewContextReader(b.meta, nameIndex, b.reader)
 	indexReader, err := b.encoding.newIndexReader(indexReaderAt, int(b.meta.IndexPageSize), int(b.meta.TotalRecords))
 	if err != nil {
 		return nil, fmt.Errorf("error building index reader (%s, %s): %w", b.meta.TenantID, b.meta.BlockID, err)
 	}
 
-	ra := backend.NewContextReader(b.meta, nameObjects, b.reader)
+	ra := backend.NewContextReader(b.meta, NameObjects, b.reader)
 	dataReader, err := b.encoding.newDataReader(ra, b.meta.Encoding)
 	if err != nil {
 		return nil, fmt.Errorf("error building page reader (%s, %s): %w", b.meta.TenantID, b.meta.BlockID, err)
@@ -102,7 +145,7 @@ func (b *BackendBlock) Find(ctx context.Context, id common.ID) ([]byte, error) {
 // Iterator returns an Iterator that iterates over the objects in the block from the backend
 func (b *BackendBlock) Iterator(chunkSizeBytes uint32) (Iterator, error) {
 	// read index
-	ra := backend.NewContextReader(b.meta, nameObjects, b.reader)
+	ra := backend.NewContextReader(b.meta, NameObjects, b.reader)
 	dataReader, err := b.encoding.newDataReader(ra, b.meta.Encoding)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create dataReader (%s, %s): %w", b.meta.TenantID, b.meta.BlockID, err)
@@ -116,3 +159,47 @@ func (b *BackendBlock) Iterator(chunkSizeBytes uint32) (Iterator, error) {
 
 	return newPagedIterator(chunkSizeBytes, reader, dataReader), nil
 }
+
+// VerifyChecksums reads every object that has a checksum recorded in the block meta and confirms it is
+//  unchanged.  Blocks written before checksums were recorded are not verified.
+func (b *BackendBlock) VerifyChecksums(ctx context.Context) error {
+	for name, expected := range b.meta.Checksums {
+		actual, err := b.checksum(ctx, name)
+		if err != nil {
+			return fmt.Errorf("error reading %s (%s, %s): %w", name, b.meta.TenantID, b.meta.BlockID, err)
+		}
+
+		if actual != expected {
+			return fmt.Errorf("%w: %s (%s, %s) expected %d got %d", ErrChecksumMismatch, name, b.meta.TenantID, b.meta.BlockID, expected, actual)
+		}
+	}
+
+	return nil
+}
+
+func (b *BackendBlock) checksum(ctx context.Context, name string) (uint64, error) {
+	if name != NameObjects {
+		obj, err := b.reader.Read(ctx, name, b.meta.BlockID, b.meta.TenantID)
+		if err != nil {
+			return 0, err
+		}
+		return xxhash.Sum64(obj), nil
+	}
+
+	// the data object can be large.  read it in chunks
+	h := xxhash.New()
+	buffer := make([]byte, verifyChunkSizeBytes)
+	for offset := uint64(0); offset < b.meta.Size; offset += uint64(len(buffer)) {
+		if remaining := b.meta.Size - offset; remaining < uint64(len(buffer)) {
+			buffer = buffer[:remaining]
+		}
+
+		err := b.reader.ReadRange(ctx, NameObjects, b.meta.BlockID, b.meta.TenantID, offset, buffer)
+		if err != nil {
+			return 0, err
+		}
+		_, _ = h.Write(buffer)
+	}
+
+	return h.Sum64(), nil
+}
@@ -1,12 +1,18 @@
 package encoding
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path"
 	"testing"
 
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding/common"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -110,3 +116,86 @@ func testLegacyBlock(t *testing.T, ids [][]byte, objs [][]byte, meta *backend.Bl
 	}
 	assert.Equal(t, len(ids), i)
 }
+
+func TestBackendBlockVerifyChecksums(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	block, _, _ := completeBlock(t, &BlockConfig{
+		IndexDownsampleBytes: 1000,
+		BloomFP:              .01,
+		Encoding:             backend.EncGZIP,
+		IndexPageSizeBytes:   1000,
+	}, tempDir)
+
+	backendTmpDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(backendTmpDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, _, err := local.New(&local.Config{
+		Path: backendTmpDir,
+	})
+	require.NoError(t, err, "error creating backend")
+
+	err = block.Write(context.Background(), w)
+	require.NoError(t, err, "error writing backend")
+
+	meta := block.BlockMeta()
+	backendBlock, err := NewBackendBlock(meta, r)
+	require.NoError(t, err, "error creating block")
+	require.NoError(t, backendBlock.VerifyChecksums(context.Background()))
+
+	// flip a byte in the data object
+	dataFile := path.Join(backendTmpDir, meta.TenantID, meta.BlockID.String(), NameObjects)
+	data, err := ioutil.ReadFile(dataFile)
+	require.NoError(t, err)
+	data[len(data)/2] ^= 0xFF
+	err = ioutil.WriteFile(dataFile, data, 0644)
+	require.NoError(t, err)
+
+	err = backendBlock.VerifyChecksums(context.Background())
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+
+	// blocks written without checksums are not verified
+	meta.Checksums = nil
+	assert.NoError(t, backendBlock.VerifyChecksums(context.Background()))
+}
+
+func TestBackendBlockContainsAny(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	block, ids, _ := completeBlock(t, &BlockConfig{
+		IndexDownsampleBytes: 1000,
+		BloomFP:              .01,
+		Encoding:             backend.EncGZIP,
+		IndexPageSizeBytes:   1000,
+	}, tempDir)
+
+	backendTmpDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(backendTmpDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, _, err := local.New(&local.Config{
+		Path: backendTmpDir,
+	})
+	require.NoError(t, err, "error creating backend")
+
+	err = block.Write(context.Background(), w)
+	require.NoError(t, err, "error writing backend")
+
+	backendBlock, err := NewBackendBlock(block.BlockMeta(), r)
+	require.NoError(t, err, "error creating block")
+
+	missing := []common.ID{make([]byte, 16), bytes.Repeat([]byte{0xFF}, 16)}
+
+	contains, err := backendBlock.ContainsAny(context.Background(), missing)
+	require.NoError(t, err)
+	assert.False(t, contains)
+
+	contains, err = backendBlock.ContainsAny(context.Background(), append(missing, ids[len(ids)/2]))
+	require.NoError(t, err)
+	assert.True(t, contains)
+}
@@ -2,23 +2,29 @@ package encoding
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"strconv"
 
+	"github.com/cespare/xxhash"
+
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 )
 
 const (
-	// nameObjects names the backend data object
-	nameObjects = "data"
+	// NameObjects names the backend data object
+	NameObjects = "data"
 	// nameIndex names the backend index object
 	nameIndex = "index"
 	// nameBloomPrefix is the prefix used to build the bloom shards
 	nameBloomPrefix = "bloom-"
 )
 
+// ErrChecksumMismatch is returned when a backend object does not match the checksum recorded in the block meta
+var ErrChecksumMismatch = errors.New("checksum mismatch")
+
 // bloomName returns the backend bloom name for the given shard
 func bloomName(shard int) string {
 	return nameBloomPrefix + strconv.Itoa(shard)
@@ -31,6 +37,13 @@ func writeBlockMeta(ctx context.Context, w backend.Writer, meta *backend.BlockMe
 		return err
 	}
 
+	ctx = backend.WithBlockMeta(ctx, meta)
+
+	meta.SetChecksum(nameIndex, xxhash.Sum64(indexBytes))
+	for i, bloom := range blooms {
+		meta.SetChecksum(bloomName(i), xxhash.Sum64(bloom))
+	}
+
 	// index
 	err = w.Write(ctx, nameIndex, meta.BlockID, meta.TenantID, indexBytes)
 	if err != nil {
@@ -56,10 +69,10 @@ func writeBlockMeta(ctx context.Context, w backend.Writer, meta *backend.BlockMe
 
 // writeBlockData writes the data object from an io.Reader to the backend.Writer
 func writeBlockData(ctx context.Context, w backend.Writer, meta *backend.BlockMeta, r io.Reader, size int64) error {
-	return w.WriteReader(ctx, nameObjects, meta.BlockID, meta.TenantID, r, size)
+	return w.WriteReader(backend.WithBlockMeta(ctx, meta), NameObjects, meta.BlockID, meta.TenantID, r, size)
 }
 
 // appendBlockData appends the bytes passed to the block data
 func appendBlockData(ctx context.Context, w backend.Writer, meta *backend.BlockMeta, tracker backend.AppendTracker, buffer []byte) (backend.AppendTracker, error) {
-	return w.Append(ctx, nameObjects, meta.BlockID, meta.TenantID, tracker, buffer)
+	return w.Append(backend.WithBlockMeta(ctx, meta), NameObjects, meta.BlockID, meta.TenantID, tracker, buffer)
 }
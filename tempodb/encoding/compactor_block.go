@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"hash"
 
+	"github.com/cespare/xxhash"
 	"github.com/google/uuid"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/encoding/common"
@@ -21,6 +23,7 @@ type CompactorBlock struct {
 	bufferedObjects int
 	appendBuffer    *bytes.Buffer
 	appender        Appender
+	dataHash        hash.Hash64
 
 	cfg *BlockConfig
 }
@@ -35,11 +38,17 @@ func NewCompactorBlock(cfg *BlockConfig, id uuid.UUID, tenantID string, metas []
 		return nil, fmt.Errorf("must have non-zero positive estimated objects for a reliable bloom filter")
 	}
 
+	encoding, err := encodingForVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &CompactorBlock{
-		encoding:      latestEncoding(),
-		compactedMeta: backend.NewBlockMeta(tenantID, id, currentVersion, cfg.Encoding),
+		encoding:      encoding,
+		compactedMeta: backend.NewBlockMeta(tenantID, id, versionOrDefault(cfg.Version), cfg.Encoding),
 		bloom:         common.NewWithEstimates(uint(estimatedObjects), cfg.BloomFP),
 		inMetas:       metas,
+		dataHash:      xxhash.New(),
 		cfg:           cfg,
 	}
 
@@ -91,6 +100,7 @@ func (c *CompactorBlock) FlushBuffer(ctx context.Context, tracker backend.Append
 	if err != nil {
 		return nil, 0, err
 	}
+	_, _ = c.dataHash.Write(c.appendBuffer.Bytes())
 
 	bytesFlushed := c.appendBuffer.Len()
 	c.appendBuffer.Reset()
@@ -123,6 +133,7 @@ func (c *CompactorBlock) Complete(ctx context.Context, tracker backend.AppendTra
 
 	meta.TotalRecords = uint32(len(records)) // casting
 	meta.IndexPageSize = uint32(c.cfg.IndexPageSizeBytes)
+	meta.SetChecksum(NameObjects, c.dataHash.Sum64())
 
 	err = writeBlockMeta(ctx, w, meta, indexBytes, c.bloom)
 	if err != nil {
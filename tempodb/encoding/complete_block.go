@@ -3,10 +3,12 @@ package encoding
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"sync"
 	"time"
 
+	"github.com/cespare/xxhash"
 	"go.uber.org/atomic"
 
 	"github.com/grafana/tempo/tempodb/backend"
@@ -34,9 +36,14 @@ type CompleteBlock struct {
 
 // NewCompleteBlock creates a new block and takes _ALL_ the parameters necessary to build the ordered, deduped file on disk
 func NewCompleteBlock(cfg *BlockConfig, originatingMeta *backend.BlockMeta, iterator Iterator, estimatedObjects int, filepath string) (*CompleteBlock, error) {
+	encoding, err := encodingForVersion(cfg.Version)
+	if err != nil {
+		return nil, err
+	}
+
 	c := &CompleteBlock{
-		encoding: latestEncoding(),
-		meta:     backend.NewBlockMeta(originatingMeta.TenantID, originatingMeta.BlockID, currentVersion, cfg.Encoding),
+		encoding: encoding,
+		meta:     backend.NewBlockMeta(originatingMeta.TenantID, originatingMeta.BlockID, versionOrDefault(cfg.Version), cfg.Encoding),
 		bloom:    common.NewWithEstimates(uint(estimatedObjects), cfg.BloomFP),
 		records:  make([]*common.Record, 0),
 		filepath: filepath,
@@ -112,10 +119,12 @@ func (c *CompleteBlock) Write(ctx context.Context, w backend.Writer) error {
 		return err
 	}
 
-	err = writeBlockData(ctx, w, c.meta, src, fileStat.Size())
+	dataHash := xxhash.New()
+	err = writeBlockData(ctx, w, c.meta, io.TeeReader(src, dataHash), fileStat.Size())
 	if err != nil {
 		return err
 	}
+	c.meta.SetChecksum(NameObjects, dataHash.Sum64())
 
 	indexWriter := c.encoding.newIndexWriter(c.cfg.IndexPageSizeBytes)
 	indexBytes, err := indexWriter.Write(c.records)
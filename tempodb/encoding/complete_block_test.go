@@ -83,6 +83,22 @@ func TestCompleteBlockAll(t *testing.T) {
 	}
 }
 
+func TestCompleteBlockVersions(t *testing.T) {
+	for _, v := range []string{"v0", "v1", "v2"} {
+		t.Run(v, func(t *testing.T) {
+			testCompleteBlockToBackendBlock(t,
+				&BlockConfig{
+					IndexDownsampleBytes: 1000,
+					BloomFP:              .01,
+					Encoding:             backend.EncSnappy,
+					IndexPageSizeBytes:   1000,
+					Version:              v,
+				},
+			)
+		})
+	}
+}
+
 func testCompleteBlockToBackendBlock(t *testing.T, cfg *BlockConfig) {
 	tempDir, err := ioutil.TempDir("/tmp", "")
 	defer os.RemoveAll(tempDir)
@@ -109,10 +125,17 @@ func testCompleteBlockToBackendBlock(t *testing.T, cfg *BlockConfig) {
 
 	meta, err := r.BlockMeta(context.Background(), uuids[0], testTenantID)
 	require.NoError(t, err, "error getting meta")
+	assert.Equal(t, versionOrDefault(cfg.Version), meta.Version)
 
 	backendBlock, err := NewBackendBlock(meta, r)
 	require.NoError(t, err, "error creating block")
 
+	// test checksums
+	assert.Contains(t, meta.Checksums, NameObjects)
+	assert.Contains(t, meta.Checksums, nameIndex)
+	err = backendBlock.VerifyChecksums(context.Background())
+	require.NoError(t, err, "error verifying checksums")
+
 	// test Find
 	for i, id := range ids {
 		foundBytes, err := backendBlock.Find(context.Background(), id)
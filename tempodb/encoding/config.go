@@ -12,6 +12,7 @@ type BlockConfig struct {
 	IndexPageSizeBytes   int              `yaml:"index_page_size_bytes"`
 	BloomFP              float64          `yaml:"bloom_filter_false_positive"`
 	Encoding             backend.Encoding `yaml:"encoding"`
+	Version              string           `yaml:"version"`
 }
 
 // ValidateConfig returns true if the config is valid
@@ -28,5 +29,9 @@ func ValidateConfig(b *BlockConfig) error {
 		return fmt.Errorf("invalid bloom filter fp rate %v", b.BloomFP)
 	}
 
+	if _, err := encodingForVersion(b.Version); err != nil {
+		return err
+	}
+
 	return nil
 }
@@ -1,6 +1,7 @@
 package encoding
 
 import (
+	"fmt"
 	"io"
 
 	"github.com/grafana/tempo/tempodb/backend"
@@ -10,20 +11,70 @@ import (
 	v2 "github.com/grafana/tempo/tempodb/encoding/v2"
 )
 
-const currentVersion = "v2"
+// CurrentVersion is the version of all newly created blocks
+const CurrentVersion = "v2"
 
-// latestEncoding is used by Compactor and Complete block
-func latestEncoding() versionedEncoding {
-	return v2Encoding{}
+// versions are all supported block versions and their encodings ordered from oldest to newest
+var versions = []struct {
+	version  string
+	encoding versionedEncoding
+}{
+	{"v0", v0Encoding{}},
+	{"v1", v1Encoding{}},
+	{"v2", v2Encoding{}},
+}
+
+// encodingForVersion returns the versioned encoding for the passed block version.  An empty
+//  version is treated as CurrentVersion.
+func encodingForVersion(v string) (versionedEncoding, error) {
+	if i := versionIndex(v); i >= 0 {
+		return versions[i].encoding, nil
+	}
+
+	return nil, fmt.Errorf("%s is not a valid block version", v)
+}
+
+// ValidateVersion returns an error if the passed block version is not supported.  An empty version is valid and
+//  treated as CurrentVersion.
+func ValidateVersion(v string) error {
+	_, err := encodingForVersion(v)
+	return err
+}
+
+// IsOlderVersion returns true if block version a was released before block version b.  Empty versions are treated
+//  as CurrentVersion and unknown versions are never older.
+func IsOlderVersion(a, b string) bool {
+	ia := versionIndex(a)
+	ib := versionIndex(b)
+	return ia >= 0 && ib >= 0 && ia < ib
+}
+
+// versionIndex returns the position of the passed version in versions or -1 if it is not supported
+func versionIndex(v string) int {
+	v = versionOrDefault(v)
+	for i, version := range versions {
+		if version.version == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// versionOrDefault returns CurrentVersion if no version is passed
+func versionOrDefault(v string) string {
+	if v == "" {
+		return CurrentVersion
+	}
+	return v
 }
 
 // allEncodings returns all encodings
 func allEncodings() []versionedEncoding {
-	return []versionedEncoding{
-		v0Encoding{},
-		v1Encoding{},
-		v2Encoding{},
+	encodings := make([]versionedEncoding, 0, len(versions))
+	for _, v := range versions {
+		encodings = append(encodings, v.encoding)
 	}
+	return encodings
 }
 
 // versionedEncoding has a whole bunch of versioned functionality.  This is
@@ -68,3 +68,14 @@ func testDataWriterReader(t *testing.T, v versionedEncoding, e backend.Encoding)
 		assert.Equal(t, []byte{0x01}, []byte(id))
 	}
 }
+
+func TestIsOlderVersion(t *testing.T) {
+	assert.True(t, IsOlderVersion("v0", "v1"))
+	assert.True(t, IsOlderVersion("v1", "v2"))
+	assert.True(t, IsOlderVersion("v1", ""))
+	assert.False(t, IsOlderVersion("v2", "v1"))
+	assert.False(t, IsOlderVersion("v2", "v2"))
+	assert.False(t, IsOlderVersion("", "v2"))
+	assert.False(t, IsOlderVersion("v1", "v9"))
+	assert.False(t, IsOlderVersion("v9", "v1"))
+}
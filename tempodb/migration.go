@@ -0,0 +1,209 @@
+package tempodb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding"
+)
+
+var (
+	metricMigrationBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "migration_blocks_total",
+		Help:      "Total number of blocks rewritten to the current block version.",
+	})
+	metricMigrationErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "migration_errors_total",
+		Help:      "Total number of errors occurring while rewriting blocks to the current block version.",
+	})
+)
+
+// doMigration rewrites blocks written with a different block version into the tenant's block version.  It is meant to
+//  use idle compactor time and bails out once the maintenance cycle that started at start is over.
+func (rw *readerWriter) doMigration(tenantID string, start time.Time) {
+	for _, b := range rw.blocksToMigrate(tenantID) {
+		if !rw.ownsBlock(tenantID, b) {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "migrating block", "blockID", b.BlockID, "tenantID", tenantID, "version", b.Version)
+		err := rw.migrate(b)
+		if err == backend.ErrMetaDoesNotExist {
+			level.Warn(rw.logger).Log("msg", "unable to find meta during migration", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+		} else if err != nil {
+			level.Error(rw.logger).Log("msg", "error migrating block", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricMigrationErrors.Inc()
+		}
+
+		if start.Add(rw.cfg.BlocklistPoll).Before(time.Now()) {
+			level.Info(rw.logger).Log("msg", "migrated blocks for a maintenance cycle, bailing out", "tenantID", tenantID)
+			return
+		}
+	}
+}
+
+// blocksToMigrate returns all blocks of a tenant that are written in an older version than the tenant's block
+//  version.  Blocks are never migrated to an older version.  Blocks in the active compaction window are skipped
+//  as they will be rewritten by compaction shortly.
+func (rw *readerWriter) blocksToMigrate(tenantID string) []*backend.BlockMeta {
+	activeWindow := rw.compactionWindowForTime(tenantID, time.Now().Add(-activeWindowDuration))
+	version := rw.blockVersionForTenant(tenantID)
+
+	var blocks []*backend.BlockMeta
+	for _, b := range rw.withoutCorruptBlocks(tenantID, rw.blocklist(tenantID)) {
+		if !encoding.IsOlderVersion(b.Version, version) {
+			continue
+		}
+		if rw.compactionWindowForTime(tenantID, b.EndTime) >= activeWindow {
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks
+}
+
+// migrate copies all objects of the passed block into a new block of the tenant's version with the same
+//  compaction level and time range and then marks the old block compacted.
+func (rw *readerWriter) migrate(meta *backend.BlockMeta) error {
+	ctx := backend.WithCaller(context.Background(), backend.CallerCompactor)
+
+	err := rw.rewriteBlock(ctx, meta, false)
+	if err != nil {
+		return err
+	}
+
+	metricMigrationBlocks.Inc()
+	return nil
+}
+
+// rewriteBlock copies all objects of the passed block into a new block of the tenant's version with the same
+//  compaction level, shard and time range and then marks the old block compacted.  Deleted traces are dropped
+//  and if summarize is true only the summary of every trace is kept.
+func (rw *readerWriter) rewriteBlock(ctx context.Context, meta *backend.BlockMeta, summarize bool) error {
+	if meta.TotalObjects <= 0 {
+		return fmt.Errorf("unable to rewrite block %s with no objects", meta.BlockID)
+	}
+
+	// confirm the block was not compacted since the last poll
+	_, err := rw.r.BlockMeta(ctx, meta.BlockID, meta.TenantID)
+	if err != nil {
+		return err
+	}
+
+	deleted := rw.deletedTraces(meta.TenantID)
+
+	block, err := rw.openBlock(ctx, meta)
+	if err != nil {
+		return err
+	}
+
+	iter, err := block.Iterator(rw.compactorCfg.ChunkSizeBytes)
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	newBlock, err := encoding.NewCompactorBlock(rw.blockConfigForTenant(meta.TenantID), uuid.New(), meta.TenantID, []*backend.BlockMeta{meta}, meta.TotalObjects)
+	if err != nil {
+		return errors.Wrap(err, "error making new block")
+	}
+	newMeta := newBlock.BlockMeta()
+	newMeta.CompactionLevel = meta.CompactionLevel
+	newMeta.ShardIndex = meta.ShardIndex
+	newMeta.ShardCount = meta.ShardCount
+	newMeta.Summary = meta.Summary || summarize
+
+	var tracker backend.AppendTracker
+	for {
+		id, obj, err := iter.Next(ctx)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if isDeleted(deleted, id) {
+			metricCompactionObjectsDeleted.WithLabelValues(meta.TenantID).Inc()
+			continue
+		}
+
+		if summarize {
+			obj, err = summarizeObject(obj)
+			if err != nil {
+				return err
+			}
+		}
+
+		// writing to the block will cause the id to escape the iterator so we need to make a copy of it
+		writeID := append([]byte(nil), id...)
+		err = newBlock.AddObject(writeID, obj)
+		if err != nil {
+			return err
+		}
+
+		if newBlock.CurrentBufferLength() >= int(rw.compactorCfg.FlushSizeBytes) {
+			tracker, _, err = newBlock.FlushBuffer(ctx, tracker, rw.compactorW)
+			if err != nil {
+				return errors.Wrap(err, "error writing partial block")
+			}
+		}
+	}
+
+	// every trace of the block was deleted
+	if newBlock.Length() == 0 {
+		markCompacted(rw, meta.TenantID, []*backend.BlockMeta{meta}, nil)
+		return nil
+	}
+
+	_, err = newBlock.Complete(ctx, tracker, rw.compactorW)
+	if err != nil {
+		return errors.Wrap(err, "error shipping block to backend")
+	}
+
+	markCompacted(rw, meta.TenantID, []*backend.BlockMeta{meta}, []*backend.BlockMeta{newMeta})
+
+	return nil
+}
+
+// blockVersionForTenant returns the version new blocks are written in for the passed tenant.  Tenants without a
+//  version override use the configured version.
+func (rw *readerWriter) blockVersionForTenant(tenantID string) string {
+	if v := rw.compactorOverrides.BlockVersionForTenant(tenantID); v != "" {
+		return v
+	}
+	if rw.cfg.Block.Version != "" {
+		return rw.cfg.Block.Version
+	}
+	return encoding.CurrentVersion
+}
+
+func (rw *readerWriter) compactionWindowForTime(tenantID string, t time.Time) int64 {
+	return t.Unix() / int64(rw.compactionWindowForTenant(tenantID)/time.Second)
+}
+
+// ownsBlock returns true if this compactor owns the passed block of a tenant.  Blocks are owned by the hash the
+//  configured block selector compacts them with so a block is never rewritten by one compactor while another one
+//  compacts it.
+func (rw *readerWriter) ownsBlock(tenantID string, b *backend.BlockMeta) bool {
+	twbs := &timeWindowBlockSelector{MaxCompactionRange: rw.compactionWindowForTenant(tenantID)}
+
+	hashString := twbs.hashForBlock(b, twbs.windowForTime(time.Now().Add(-activeWindowDuration)))
+	if shards := uint32(rw.compactorCfg.SplitShards); shards > 0 && b.ShardCount != shards {
+		hashString = twbs.splitHashForBlock(b)
+	}
+
+	return rw.compactorSharder.Owns(hashString)
+}
@@ -0,0 +1,198 @@
+package tempodb
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+const (
+	v1TestBlockPath = "./encoding/v1test/fake/b51766bd-a395-448b-9684-7a4fdbd053aa"
+	v1TestBlockID   = "b51766bd-a395-448b-9684-7a4fdbd053aa"
+)
+
+func TestMigration(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	// seed the backend with a v1 block
+	copyDir(t, v1TestBlockPath, path.Join(tempDir, "traces", "fake", v1TestBlockID))
+
+	r, _, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_4M,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		FlushSizeBytes:          100,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: 0,
+		MigrateBlocks:           true,
+	}, &mockSharder{}, &mockOverrides{})
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+
+	blocklist := rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 1)
+	oldMeta := blocklist[0]
+	require.Equal(t, "v1", oldMeta.Version)
+	assert.Len(t, rw.blocksToMigrate(testTenantID), 1)
+
+	// collect everything in the old block
+	ids, objs := iterateBlock(t, rw, oldMeta)
+	require.Len(t, ids, oldMeta.TotalObjects)
+
+	rw.doMigration(testTenantID, time.Now())
+
+	rw.pollBlocklist()
+	blocklist = rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 1)
+	newMeta := blocklist[0]
+	assert.Equal(t, encoding.CurrentVersion, newMeta.Version)
+	assert.NotEqual(t, oldMeta.BlockID, newMeta.BlockID)
+	assert.Equal(t, oldMeta.CompactionLevel, newMeta.CompactionLevel)
+	assert.Equal(t, oldMeta.TotalObjects, newMeta.TotalObjects)
+	assert.True(t, oldMeta.StartTime.Equal(newMeta.StartTime))
+	assert.True(t, oldMeta.EndTime.Equal(newMeta.EndTime))
+	assert.Len(t, rw.blocksToMigrate(testTenantID), 0)
+
+	compactedBlocklist := rw.compactedBlocklist(testTenantID)
+	require.Len(t, compactedBlocklist, 1)
+	assert.Equal(t, oldMeta.BlockID, compactedBlocklist[0].BlockID)
+
+	newBlock, err := encoding.NewBackendBlock(newMeta, rw.r)
+	require.NoError(t, err)
+	assert.NoError(t, newBlock.VerifyChecksums(context.Background()))
+
+	newIDs, newObjs := iterateBlock(t, rw, newMeta)
+	assert.Equal(t, ids, newIDs)
+	assert.Equal(t, objs, newObjs)
+}
+
+func iterateBlock(t *testing.T, rw *readerWriter, meta *backend.BlockMeta) ([][]byte, [][]byte) {
+	block, err := encoding.NewBackendBlock(meta, rw.r)
+	require.NoError(t, err)
+
+	iter, err := block.Iterator(10)
+	require.NoError(t, err)
+	defer iter.Close()
+
+	var ids, objs [][]byte
+	for {
+		id, obj, err := iter.Next(context.Background())
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		ids = append(ids, append([]byte(nil), id...))
+		objs = append(objs, append([]byte(nil), obj...))
+	}
+
+	return ids, objs
+}
+
+func copyDir(t *testing.T, src string, dst string) {
+	err := os.MkdirAll(dst, os.ModePerm)
+	require.NoError(t, err)
+
+	files, err := ioutil.ReadDir(src)
+	require.NoError(t, err)
+
+	for _, f := range files {
+		b, err := ioutil.ReadFile(path.Join(src, f.Name()))
+		require.NoError(t, err)
+		err = ioutil.WriteFile(path.Join(dst, f.Name()), b, 0644)
+		require.NoError(t, err)
+	}
+}
+
+func TestMigrationHonorsTenantVersion(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	copyDir(t, v1TestBlockPath, path.Join(tempDir, "traces", "fake", v1TestBlockID))
+
+	r, _, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_4M,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	overrides := &mockOverrides{blockVersion: "v1"}
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:     10,
+		FlushSizeBytes:     100,
+		MaxCompactionRange: time.Hour,
+		MigrateBlocks:      true,
+	}, &mockSharder{}, overrides)
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+
+	// the block is already in the tenant's version
+	assert.Len(t, rw.blocksToMigrate(testTenantID), 0)
+
+	// moving the tenant to v2 migrates the block
+	overrides.blockVersion = "v2"
+	require.Len(t, rw.blocksToMigrate(testTenantID), 1)
+	rw.doMigration(testTenantID, time.Now())
+
+	rw.pollBlocklist()
+	blocklist := rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 1)
+	assert.Equal(t, "v2", blocklist[0].Version)
+
+	// blocks are never migrated to an older version
+	overrides.blockVersion = "v1"
+	assert.Len(t, rw.blocksToMigrate(testTenantID), 0)
+
+	// tenants without a version use the configured version
+	overrides.blockVersion = ""
+	assert.Equal(t, encoding.CurrentVersion, rw.blockVersionForTenant(testTenantID))
+	assert.Equal(t, rw.cfg.Block, rw.blockConfigForTenant(testTenantID))
+	assert.Len(t, rw.blocksToMigrate(testTenantID), 0)
+}
@@ -1,11 +1,14 @@
 package tempodb
 
 import (
+	"context"
 	"time"
 
 	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
 
 	"github.com/grafana/tempo/pkg/boundedwaitgroup"
+	"github.com/grafana/tempo/tempodb/backend"
 )
 
 // todo: pass a context/chan in to cancel this cleanly
@@ -37,16 +40,14 @@ func (rw *readerWriter) retainTenant(tenantID string) {
 	start := time.Now()
 	defer func() { metricRetentionDuration.Observe(time.Since(start).Seconds()) }()
 
-	// Check for overrides
-	retention := rw.compactorCfg.BlockRetention // Default
-	if r := rw.compactorOverrides.BlockRetentionForTenant(tenantID); r != 0 {
-		retention = r
-	}
+	retention := rw.retentionForTenant(tenantID)
 	level.Debug(rw.logger).Log("msg", "Performing block retention", "tenantID", tenantID, "retention", retention)
 
 	// iterate through block list.  make compacted anything that is past retention.
 	cutoff := time.Now().Add(-retention)
 	blocklist := rw.blocklist(tenantID)
+	compactedTime := time.Now()
+	marked := make([]uuid.UUID, 0)
 	for _, b := range blocklist {
 		if b.EndTime.Before(cutoff) && rw.compactorSharder.Owns(b.BlockID.String()) {
 			level.Info(rw.logger).Log("msg", "marking block for deletion", "blockID", b.BlockID, "tenantID", tenantID)
@@ -56,18 +57,26 @@ func (rw *readerWriter) retainTenant(tenantID string) {
 				metricRetentionErrors.Inc()
 			} else {
 				metricMarkedForDeletion.Inc()
+				marked = append(marked, b.BlockID)
 			}
 		}
 	}
+	rw.writeCompactionMarker(backend.WithCaller(context.Background(), backend.CallerCompactor), tenantID, marked, compactedTime)
+
+	rw.clearExpiredTombstones(tenantID)
+	rw.clearExpiredCompactionMarkers(tenantID)
 
 	// iterate through compacted list looking for blocks ready to be cleared
-	cutoff = time.Now().Add(-rw.compactorCfg.CompactedBlockRetention)
+	cutoff = time.Now().Add(-rw.compactedBlockRetention())
 	compactedBlocklist := rw.compactedBlocklist(tenantID)
 	for _, b := range compactedBlocklist {
 		if b.CompactedTime.Before(cutoff) && rw.compactorSharder.Owns(b.BlockID.String()) {
 			level.Info(rw.logger).Log("msg", "deleting block", "blockID", b.BlockID, "tenantID", tenantID)
 			err := rw.c.ClearBlock(b.BlockID, tenantID)
-			if err != nil {
+			if err == backend.ErrObjectLocked {
+				// the block is deleted on a later cycle once its retention policy expires
+				level.Debug(rw.logger).Log("msg", "block is still protected by a retention policy", "blockID", b.BlockID, "tenantID", tenantID)
+			} else if err != nil {
 				level.Error(rw.logger).Log("msg", "failed to clear compacted block during retention", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
 				metricRetentionErrors.Inc()
 			} else {
@@ -76,3 +85,21 @@ func (rw *readerWriter) retainTenant(tenantID string) {
 		}
 	}
 }
+
+// retentionForTenant returns how long blocks of the passed tenant are kept
+func (rw *readerWriter) retentionForTenant(tenantID string) time.Duration {
+	// Check for overrides
+	if r := rw.compactorOverrides.BlockRetentionForTenant(tenantID); r != 0 {
+		return r
+	}
+	return rw.compactorCfg.BlockRetention // Default
+}
+
+// compactedBlockRetention returns how long compacted blocks are kept before they are deleted.  Queriers keep
+//  searching compacted blocks for two polling cycles after they were compacted so they are never deleted sooner.
+func (rw *readerWriter) compactedBlockRetention() time.Duration {
+	if min := 2 * rw.cfg.BlocklistPoll; rw.compactorCfg.CompactedBlockRetention < min {
+		return min
+	}
+	return rw.compactorCfg.CompactedBlockRetention
+}
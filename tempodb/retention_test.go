@@ -11,7 +11,9 @@ import (
 	"github.com/go-kit/kit/log"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/grafana/tempo/pkg/util/test"
 	"github.com/grafana/tempo/tempodb/backend"
 	"github.com/grafana/tempo/tempodb/backend/local"
 	"github.com/grafana/tempo/tempodb/encoding"
@@ -67,13 +69,19 @@ func TestRetention(t *testing.T) {
 	// poll
 	checkBlocklists(t, blockID, 1, 0, rw)
 
-	// retention should mark it compacted
+	// retention should mark it compacted and record it in a compaction marker
 	r.(*readerWriter).doRetention()
 	checkBlocklists(t, blockID, 0, 1, rw)
+	markers := rw.compactionMarkersForTenant(testTenantID)
+	require.Len(t, markers, 1)
+	for _, m := range markers {
+		assert.Equal(t, []uuid.UUID{blockID}, m.BlockIDs)
+	}
 
-	// retention again should clear it
+	// retention again should clear it and the marker
 	r.(*readerWriter).doRetention()
 	checkBlocklists(t, blockID, 0, 0, rw)
+	assert.Len(t, rw.compactionMarkersForTenant(testTenantID), 0)
 }
 
 func TestBlockRetentionOverride(t *testing.T) {
@@ -131,3 +139,81 @@ func TestBlockRetentionOverride(t *testing.T) {
 	rw.pollBlocklist()
 	assert.Equal(t, 0, len(rw.blocklist(testTenantID)))
 }
+
+func TestCompactedBlockRetention(t *testing.T) {
+	rw := &readerWriter{
+		cfg:          &Config{BlocklistPoll: time.Minute},
+		compactorCfg: &CompactorConfig{},
+	}
+
+	// compacted blocks are searched by queriers for two polling cycles
+	assert.Equal(t, 2*time.Minute, rw.compactedBlockRetention())
+
+	rw.compactorCfg.CompactedBlockRetention = time.Hour
+	assert.Equal(t, time.Hour, rw.compactedBlockRetention())
+}
+
+// lockedCompactor fails to clear blocks like a backend with a retention policy that has not expired yet
+type lockedCompactor struct {
+	backend.Compactor
+}
+
+func (lockedCompactor) ClearBlock(uuid.UUID, string) error {
+	return backend.ErrObjectLocked
+}
+
+func TestRetentionSkipsLockedBlocks(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	assert.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:          10,
+		MaxCompactionRange:      time.Hour,
+		BlockRetention:          0,
+		CompactedBlockRetention: 0,
+	}, &mockSharder{}, &mockOverrides{})
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID)
+	assert.NoError(t, err)
+	complete, err := w.CompleteBlock(head, &mockSharder{})
+	assert.NoError(t, err)
+	blockID := complete.BlockMeta().BlockID
+	assert.NoError(t, w.WriteBlock(context.Background(), complete))
+
+	rw := r.(*readerWriter)
+	checkBlocklists(t, blockID, 1, 0, rw)
+
+	rw.doRetention()
+	checkBlocklists(t, blockID, 0, 1, rw)
+
+	// a locked block is kept for a later cycle and is not an error
+	rw.c = lockedCompactor{Compactor: rw.c}
+	errorsStart, err := test.GetCounterValue(metricRetentionErrors)
+	assert.NoError(t, err)
+
+	rw.doRetention()
+	checkBlocklists(t, blockID, 0, 1, rw)
+
+	errorsEnd, err := test.GetCounterValue(metricRetentionErrors)
+	assert.NoError(t, err)
+	assert.Equal(t, errorsStart, errorsEnd)
+}
@@ -0,0 +1,101 @@
+package tempodb
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/gogo/protobuf/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	"github.com/grafana/tempo/pkg/util"
+	"github.com/grafana/tempo/tempodb/backend"
+)
+
+var (
+	metricSummaryBlocks = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "summary_blocks_total",
+		Help:      "Total number of blocks rewritten to summary blocks.",
+	})
+	metricSummaryErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "summary_errors_total",
+		Help:      "Total number of errors occurring while rewriting blocks to summary blocks.",
+	})
+)
+
+// doSummarization rewrites blocks that ended more than summarize_after ago into summary blocks that only keep
+//  the root spans of every trace.  Like migration it uses idle compactor time and bails out once the maintenance
+//  cycle that started at start is over.
+func (rw *readerWriter) doSummarization(tenantID string, start time.Time) {
+	for _, b := range rw.blocksToSummarize(tenantID) {
+		if !rw.ownsBlock(tenantID, b) {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "summarizing block", "blockID", b.BlockID, "tenantID", tenantID)
+		err := rw.summarize(b)
+		if err == backend.ErrMetaDoesNotExist {
+			level.Warn(rw.logger).Log("msg", "unable to find meta during summarizing", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+		} else if err != nil {
+			level.Error(rw.logger).Log("msg", "error summarizing block", "blockID", b.BlockID, "tenantID", tenantID, "err", err)
+			metricSummaryErrors.Inc()
+		}
+
+		if start.Add(rw.cfg.BlocklistPoll).Before(time.Now()) {
+			level.Info(rw.logger).Log("msg", "summarized blocks for a maintenance cycle, bailing out", "tenantID", tenantID)
+			return
+		}
+	}
+}
+
+// blocksToSummarize returns all blocks of a tenant that ended more than summarize_after ago and are not summary
+//  blocks yet.  Blocks past retention are skipped as they are about to be deleted and blocks in the active
+//  compaction window are skipped as they are still being compacted.
+func (rw *readerWriter) blocksToSummarize(tenantID string) []*backend.BlockMeta {
+	now := time.Now()
+	cutoff := now.Add(-rw.compactorCfg.SummarizeAfter)
+	retentionCutoff := now.Add(-rw.retentionForTenant(tenantID))
+	activeWindow := rw.compactionWindowForTime(tenantID, now.Add(-activeWindowDuration))
+
+	var blocks []*backend.BlockMeta
+	for _, b := range rw.withoutCorruptBlocks(tenantID, rw.blocklist(tenantID)) {
+		if b.Summary || !b.EndTime.Before(cutoff) || b.EndTime.Before(retentionCutoff) {
+			continue
+		}
+		if rw.compactionWindowForTime(tenantID, b.EndTime) >= activeWindow {
+			continue
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks
+}
+
+// summarize rewrites the passed block into a summary block with the same compaction level and time range and
+//  then marks the old block compacted.
+func (rw *readerWriter) summarize(meta *backend.BlockMeta) error {
+	ctx := backend.WithCaller(context.Background(), backend.CallerCompactor)
+
+	err := rw.rewriteBlock(ctx, meta, true)
+	if err != nil {
+		return err
+	}
+
+	metricSummaryBlocks.Inc()
+	return nil
+}
+
+// summarizeObject returns the summary of the marshalled trace
+func summarizeObject(obj []byte) ([]byte, error) {
+	trace := &tempopb.Trace{}
+	err := proto.Unmarshal(obj, trace)
+	if err != nil {
+		return nil, err
+	}
+
+	return proto.Marshal(util.SummarizeTrace(trace))
+}
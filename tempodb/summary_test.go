@@ -0,0 +1,121 @@
+package tempodb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/golang/protobuf/proto"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/tempopb"
+	v1 "github.com/grafana/tempo/pkg/tempopb/trace/v1"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestSummarize(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:     10,
+		FlushSizeBytes:     100,
+		MaxCompactionRange: time.Hour,
+		BlockRetention:     48 * time.Hour,
+		SummarizeAfter:     time.Hour,
+	}, &mockSharder{}, &mockOverrides{})
+
+	id := makeTraceID(1, 1)
+	root := &v1.Span{TraceId: id, SpanId: []byte{0x01}, Name: "root"}
+	child := &v1.Span{TraceId: id, SpanId: []byte{0x02}, ParentSpanId: []byte{0x01}, Name: "child"}
+	leaf := &v1.Span{TraceId: id, SpanId: []byte{0x03}, ParentSpanId: []byte{0x02}, Name: "leaf"}
+	trace := &tempopb.Trace{
+		Batches: []*v1.ResourceSpans{
+			{InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: []*v1.Span{root, child}}}},
+			{InstrumentationLibrarySpans: []*v1.InstrumentationLibrarySpans{{Spans: []*v1.Span{leaf}}}},
+		},
+	}
+	obj, err := proto.Marshal(trace)
+	require.NoError(t, err)
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID)
+	require.NoError(t, err)
+	require.NoError(t, head.Write(id, obj))
+	complete, err := w.CompleteBlock(head, &mockSharder{})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBlock(context.Background(), complete))
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+	blocklist := rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 1)
+
+	require.NoError(t, rw.summarize(blocklist[0]))
+
+	blocklist = rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 1)
+	assert.True(t, blocklist[0].Summary)
+	assert.Len(t, rw.compactedBlocklist(testTenantID), 1)
+
+	objs, err := rw.Find(context.Background(), testTenantID, id, BlockIDMin, BlockIDMax)
+	require.NoError(t, err)
+	require.Len(t, objs, 1)
+
+	actual := &tempopb.Trace{}
+	require.NoError(t, proto.Unmarshal(objs[0], actual))
+	require.Len(t, actual.Batches, 1)
+	require.Len(t, actual.Batches[0].InstrumentationLibrarySpans, 1)
+	assert.True(t, proto.Equal(root, actual.Batches[0].InstrumentationLibrarySpans[0].Spans[0]))
+	assert.Len(t, actual.Batches[0].InstrumentationLibrarySpans[0].Spans, 1)
+}
+
+func TestBlocksToSummarize(t *testing.T) {
+	now := time.Now()
+	old := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now.Add(-30 * time.Hour)}
+	summary := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now.Add(-30 * time.Hour), Summary: true}
+	recent := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now.Add(-time.Minute)}
+	active := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now.Add(-2 * time.Hour)}
+	expired := &backend.BlockMeta{BlockID: uuid.New(), EndTime: now.Add(-72 * time.Hour)}
+
+	rw := &readerWriter{
+		blockLists: map[string][]*backend.BlockMeta{
+			testTenantID: {old, summary, recent, active, expired},
+		},
+		compactorCfg: &CompactorConfig{
+			MaxCompactionRange: time.Hour,
+			BlockRetention:     48 * time.Hour,
+			SummarizeAfter:     time.Hour,
+		},
+		compactorOverrides: &mockOverrides{},
+	}
+
+	assert.Equal(t, []*backend.BlockMeta{old}, rw.blocksToSummarize(testTenantID))
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -21,13 +23,18 @@ import (
 
 	"github.com/grafana/tempo/pkg/boundedwaitgroup"
 	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/accounting"
 	"github.com/grafana/tempo/tempodb/backend/azure"
 	"github.com/grafana/tempo/tempodb/backend/cache"
 	"github.com/grafana/tempo/tempodb/backend/cache/memcached"
 	"github.com/grafana/tempo/tempodb/backend/cache/redis"
 	"github.com/grafana/tempo/tempodb/backend/gcs"
 	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/backend/replication"
+	"github.com/grafana/tempo/tempodb/backend/retry"
 	"github.com/grafana/tempo/tempodb/backend/s3"
+	"github.com/grafana/tempo/tempodb/backend/swift"
+	"github.com/grafana/tempo/tempodb/backend/throttle"
 	"github.com/grafana/tempo/tempodb/encoding"
 	"github.com/grafana/tempo/tempodb/encoding/common"
 	"github.com/grafana/tempo/tempodb/pool"
@@ -53,11 +60,27 @@ var (
 		Help:      "Records the amount of time to poll and update the blocklist.",
 		Buckets:   prometheus.ExponentialBuckets(.25, 2, 6),
 	})
+	metricBlocklistPollReused = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "blocklist_poll_metas_reused_total",
+		Help:      "Total number of block metas reused from the previous poll instead of being fetched.",
+	})
 	metricBlocklistLength = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: "tempodb",
 		Name:      "blocklist_length",
 		Help:      "Total number of blocks per tenant.",
 	}, []string{"tenant"})
+	metricFindBlockDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "tempodb",
+		Name:      "find_block_duration_seconds",
+		Help:      "Records the amount of time to search a block for a trace by storage class of the block.",
+		Buckets:   prometheus.ExponentialBuckets(.005, 4, 7),
+	}, []string{"storage_class"})
+	metricFindBlocksDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "tempodb",
+		Name:      "find_blocks_deleted_total",
+		Help:      "Total number of blocks that were deleted from the backend while they were searched for a trace.",
+	}, []string{"compacted"})
 	metricRetentionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
 		Namespace: "tempodb",
 		Name:      "retention_duration_seconds",
@@ -84,6 +107,7 @@ var (
 type Writer interface {
 	WriteBlock(ctx context.Context, block WriteableBlock) error
 	CompleteBlock(block *wal.AppendBlock, combiner common.ObjectCombiner) (*encoding.CompleteBlock, error)
+	CompleteBlockWithVersion(block *wal.AppendBlock, combiner common.ObjectCombiner, version string) (*encoding.CompleteBlock, error)
 	WAL() *wal.WAL
 }
 
@@ -94,6 +118,8 @@ type Reader interface {
 
 type Compactor interface {
 	EnableCompaction(cfg *CompactorConfig, sharder CompactorSharder, overrides CompactorOverrides)
+	DeleteTraces(ctx context.Context, tenantID string, ids []common.ID) error
+	PlanCompaction(tenantID string) []*CompactionPlan
 }
 
 type CompactorSharder interface {
@@ -102,6 +128,10 @@ type CompactorSharder interface {
 
 type CompactorOverrides interface {
 	BlockRetentionForTenant(tenantID string) time.Duration
+	BlockVersionForTenant(tenantID string) string
+	CompactionWindowForTenant(tenantID string) time.Duration
+	MaxBlockBytesForTenant(tenantID string) uint64
+	MaxCompactionObjectsForTenant(tenantID string) int
 }
 
 type WriteableBlock interface {
@@ -120,12 +150,34 @@ type readerWriter struct {
 	cfg           *Config
 	blockLists    map[string][]*backend.BlockMeta
 	blockListsMtx sync.Mutex
+	// blockMetaPolled is the time the meta of each block in blockLists was last fetched from the backend
+	blockMetaPolled map[string]map[uuid.UUID]time.Time
+	// tombstones are the tombstone objects of each tenant found by the last poll.  They are immutable and are
+	//  only read once.
+	tombstones map[string]map[uuid.UUID]*tombstones
+	// compactionMarkers are the compaction markers of each tenant found by the last poll.  Like tombstones they
+	//  are immutable and are only read once.
+	compactionMarkers map[string]map[uuid.UUID]*compactionMarker
 
 	compactorCfg          *CompactorConfig
 	compactedBlockLists   map[string][]*backend.CompactedBlockMeta
 	compactorSharder      CompactorSharder
 	compactorOverrides    CompactorOverrides
 	compactorTenantOffset uint
+
+	// compactorR and compactorW are used to read and write blocks during compaction.  They are throttled to
+	//  the compactor throughput limit.
+	compactorR backend.Reader
+	compactorW backend.Writer
+
+	// corruptBlocks are the blocks of each tenant that failed checksum verification.  They are no longer
+	//  compacted, migrated or summarized by this compactor.
+	corruptBlocks    map[string]map[uuid.UUID]struct{}
+	corruptBlocksMtx sync.Mutex
+
+	// deletionChecked is the time each block of a tenant was last found to contain no deleted traces.  It is only
+	//  used by the compaction loop.
+	deletionChecked map[string]map[uuid.UUID]time.Time
 }
 
 // New creates a new tempodb
@@ -139,23 +191,48 @@ func New(cfg *Config, logger log.Logger) (Reader, Writer, Compactor, error) {
 		return nil, nil, nil, fmt.Errorf("invalid config while creating tempodb: %w", err)
 	}
 
-	switch cfg.Backend {
-	case "local":
-		r, w, c, err = local.New(cfg.Local)
-	case "gcs":
-		r, w, c, err = gcs.New(cfg.GCS)
-	case "s3":
-		r, w, c, err = s3.New(cfg.S3)
-	case "azure":
-		r, w, c, err = azure.New(cfg.Azure)
-	default:
-		err = fmt.Errorf("unknown backend %s", cfg.Backend)
-	}
-
+	r, w, c, err = newBackend(&BackendConfig{
+		Backend: cfg.Backend,
+		Local:   cfg.Local,
+		GCS:     cfg.GCS,
+		S3:      cfg.S3,
+		Azure:   cfg.Azure,
+		Swift:   cfg.Swift,
+	}, cfg.Retry)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
+	if cfg.Replica != nil && cfg.Replica.Backend != "" {
+		replicaR, replicaW, replicaC, err := newBackend(&cfg.Replica.BackendConfig, cfg.Retry)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create replica backend: %w", err)
+		}
+
+		r, w, c = replication.New(
+			replication.Backend{Reader: r, Writer: w, Compactor: c},
+			replication.Backend{Reader: replicaR, Writer: replicaW, Compactor: replicaC},
+			cfg.Replica.FailOnError,
+			logger,
+		)
+	}
+
+	// blocks and metas are read from the read backend.  it is only configured for queriers, which never mark or
+	//  clear blocks
+	if cfg.ReadBackend != nil && cfg.ReadBackend.Backend != "" {
+		readR, _, readC, err := newBackend(cfg.ReadBackend, cfg.Retry)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create read backend: %w", err)
+		}
+
+		r = readR
+		c = &splitCompactor{Compactor: c, read: readC}
+	}
+
+	if cfg.ReadLimits != nil && (cfg.ReadLimits.BytesPerSecond > 0 || cfg.ReadLimits.RequestsPerSecond > 0 || cfg.ReadLimits.Limits != nil) {
+		r = throttle.New(r, cfg.ReadLimits)
+	}
+
 	var cacheBackend cache.Client
 
 	switch cfg.Cache {
@@ -185,6 +262,9 @@ func New(cfg *Config, logger log.Logger) (Reader, Writer, Compactor, error) {
 		logger:              logger,
 		pool:                pool.NewPool(cfg.Pool),
 		blockLists:          make(map[string][]*backend.BlockMeta),
+		blockMetaPolled:     make(map[string]map[uuid.UUID]time.Time),
+		tombstones:          make(map[string]map[uuid.UUID]*tombstones),
+		compactionMarkers:   make(map[string]map[uuid.UUID]*compactionMarker),
 	}
 
 	rw.wal, err = wal.New(rw.cfg.WAL)
@@ -197,14 +277,68 @@ func New(cfg *Config, logger log.Logger) (Reader, Writer, Compactor, error) {
 	return rw, rw, rw, nil
 }
 
+// newBackend creates the named backend and wraps it in retries if configured.  Requests are accounted for below the
+//  retries so every attempt is counted, and every backend of a replicated setup is counted on its own.  Cache hits
+//  never reach the backend and are not counted.
+func newBackend(cfg *BackendConfig, retryCfg *retry.Config) (backend.Reader, backend.Writer, backend.Compactor, error) {
+	var r backend.Reader
+	var w backend.Writer
+	var c backend.Compactor
+	var err error
+
+	switch cfg.Backend {
+	case "local":
+		r, w, c, err = local.New(cfg.Local)
+	case "gcs":
+		r, w, c, err = gcs.New(cfg.GCS)
+	case "s3":
+		r, w, c, err = s3.New(cfg.S3)
+	case "azure":
+		r, w, c, err = azure.New(cfg.Azure)
+	case "swift":
+		r, w, c, err = swift.New(cfg.Swift)
+	default:
+		err = fmt.Errorf("unknown backend %s", cfg.Backend)
+	}
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	r, w, c = accounting.New(r, w, c)
+
+	if retryCfg != nil && retryCfg.MaxRetries > 0 {
+		r, w, c = retry.New(r, w, c, retryCfg)
+	}
+
+	return r, w, c, nil
+}
+
+// splitCompactor reads compacted metas from the read backend.  Marking and clearing blocks still goes to the main
+//  backend, but is never done by queriers.
+type splitCompactor struct {
+	backend.Compactor
+	read backend.Compactor
+}
+
+func (c *splitCompactor) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	return c.read.CompactedBlockMeta(blockID, tenantID)
+}
+
 func (rw *readerWriter) WriteBlock(ctx context.Context, c WriteableBlock) error {
-	return c.Write(ctx, rw.w)
+	return c.Write(backend.WithCaller(ctx, backend.CallerIngester), rw.w)
 }
 
 func (rw *readerWriter) CompleteBlock(block *wal.AppendBlock, combiner common.ObjectCombiner) (*encoding.CompleteBlock, error) {
 	return block.Complete(rw.cfg.Block, rw.wal, combiner)
 }
 
+// CompleteBlockWithVersion completes the block using the passed block version instead of the configured one.  An
+//  empty version uses the configured block version.
+func (rw *readerWriter) CompleteBlockWithVersion(block *wal.AppendBlock, combiner common.ObjectCombiner, version string) (*encoding.CompleteBlock, error) {
+	return block.Complete(blockConfigWithVersion(rw.cfg.Block, version), rw.wal, combiner)
+}
+
 func (rw *readerWriter) WAL() *wal.WAL {
 	return rw.wal
 }
@@ -214,6 +348,7 @@ func (rw *readerWriter) Find(ctx context.Context, tenantID string, id common.ID,
 	logger := log_util.WithContext(ctx, log_util.Logger)
 	span, ctx := opentracing.StartSpanFromContext(ctx, "store.Find")
 	defer span.Finish()
+	ctx = backend.WithCaller(ctx, backend.CallerQuerier)
 
 	blockStartUUID, err := uuid.Parse(blockStart)
 	if err != nil {
@@ -235,6 +370,7 @@ func (rw *readerWriter) Find(ctx context.Context, tenantID string, id common.ID,
 	rw.blockListsMtx.Lock()
 	blocklist, found := rw.blockLists[tenantID]
 	copiedBlocklist := make([]interface{}, 0, len(blocklist))
+	compactedIDs := map[uuid.UUID]struct{}{}
 
 	for _, b := range blocklist {
 		if includeBlock(b, id, blockStartBytes, blockEndBytes) {
@@ -246,6 +382,7 @@ func (rw *readerWriter) Find(ctx context.Context, tenantID string, id common.ID,
 	for _, c := range compactedBlocklist {
 		if includeCompactedBlock(c, id, blockStartBytes, blockEndBytes, rw.cfg.BlocklistPoll) {
 			copiedBlocklist = append(copiedBlocklist, &c.BlockMeta)
+			compactedIDs[c.BlockID] = struct{}{}
 		}
 	}
 	rw.blockListsMtx.Unlock()
@@ -255,6 +392,11 @@ func (rw *readerWriter) Find(ctx context.Context, tenantID string, id common.ID,
 		return nil, nil
 	}
 
+	// blocks keep deleted traces until they are rewritten
+	if rw.isTraceDeleted(tenantID, id) {
+		return nil, nil
+	}
+
 	partialTraces, err := rw.pool.RunJobs(ctx, copiedBlocklist, func(ctx context.Context, payload interface{}) ([]byte, error) {
 		meta := payload.(*backend.BlockMeta)
 		block, err := encoding.NewBackendBlock(meta, rw.r)
@@ -262,7 +404,21 @@ func (rw *readerWriter) Find(ctx context.Context, tenantID string, id common.ID,
 			return nil, err
 		}
 
+		// archived blocks may be slower to read and are tracked separately
+		start := time.Now()
 		foundObject, err := block.Find(ctx, id)
+		metricFindBlockDuration.WithLabelValues(storageClassLabel(meta)).Observe(time.Since(start).Seconds())
+		if errors.Is(err, backend.ErrDoesNotExist) {
+			// the block was deleted after the blocklist was polled.  a compacted block was polled together
+			//  with the blocks that replaced it so its traces are found there.  a block that was polled as
+			//  live means the blocklist is older than the compacted block retention and traces may be missed.
+			_, compacted := compactedIDs[meta.BlockID]
+			metricFindBlocksDeleted.WithLabelValues(strconv.FormatBool(compacted)).Inc()
+			level.Warn(logger).Log("msg", "block was deleted while searching for trace", "block", meta.BlockID, "compacted", compacted)
+			if compacted {
+				return nil, nil
+			}
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -296,6 +452,18 @@ func (rw *readerWriter) EnableCompaction(cfg *CompactorConfig, c CompactorSharde
 	rw.compactorSharder = c
 	rw.compactorOverrides = overrides
 
+	if cfg.CompactedBlockRetention < 2*rw.cfg.BlocklistPoll {
+		level.Warn(rw.logger).Log("msg", "compacted block retention is shorter than two polling cycles.  compacted blocks are kept for two polling cycles instead",
+			"compactedBlockRetention", cfg.CompactedBlockRetention, "blocklistPoll", rw.cfg.BlocklistPoll)
+	}
+
+	rw.compactorR, rw.compactorW = rw.r, rw.w
+	if cfg.MaxBytesPerSecond > 0 {
+		limiter := newCompactionLimiter(cfg.MaxBytesPerSecond)
+		rw.compactorR = &throttledReader{Reader: rw.r, limiter: limiter}
+		rw.compactorW = &throttledWriter{Writer: rw.w, limiter: limiter}
+	}
+
 	if rw.cfg.BlocklistPoll == 0 {
 		level.Info(rw.logger).Log("msg", "maintenance cycle unset.  compaction and retention disabled.")
 		return
@@ -326,7 +494,7 @@ func (rw *readerWriter) pollBlocklist() {
 	start := time.Now()
 	defer func() { metricBlocklistPollDuration.Observe(time.Since(start).Seconds()) }()
 
-	ctx := context.Background()
+	ctx := backend.WithCaller(context.Background(), backend.CallerPoller)
 	tenants, err := rw.r.Tenants(ctx)
 	if err != nil {
 		metricBlocklistErrors.WithLabelValues("").Inc()
@@ -337,38 +505,95 @@ func (rw *readerWriter) pollBlocklist() {
 
 	for _, tenantID := range tenants {
 
-		newBlockList, newCompactedBlockList := rw.pollTenant(ctx, tenantID)
+		newBlockList, newCompactedBlockList, newPolled, newTombstones, newMarkers := rw.pollTenant(ctx, tenantID)
 
 		metricBlocklistLength.WithLabelValues(tenantID).Set(float64(len(newBlockList)))
 
 		rw.blockListsMtx.Lock()
 		rw.blockLists[tenantID] = newBlockList
 		rw.compactedBlockLists[tenantID] = newCompactedBlockList
+		rw.blockMetaPolled[tenantID] = newPolled
+		rw.tombstones[tenantID] = newTombstones
+		rw.compactionMarkers[tenantID] = newMarkers
 		rw.blockListsMtx.Unlock()
 	}
 }
 
-func (rw *readerWriter) pollTenant(ctx context.Context, tenantID string) ([]*backend.BlockMeta, []*backend.CompactedBlockMeta) {
+// pollTenant returns the block and compacted block lists, the tombstones and the compaction markers of a tenant.
+//  Only blocks that changed since the last poll cost a request: compacted metas never change and live metas are
+//  reused until they are older than the stale tolerance of the tenant.  Within the tolerance blocks listed in
+//  a compaction marker are moved to the compacted blocklist without fetching their meta.  If fetching a known
+//  block fails its previous meta is kept.
+func (rw *readerWriter) pollTenant(ctx context.Context, tenantID string) ([]*backend.BlockMeta, []*backend.CompactedBlockMeta, map[uuid.UUID]time.Time, map[uuid.UUID]*tombstones, map[uuid.UUID]*compactionMarker) {
 	blockIDs, err := rw.r.Blocks(ctx, tenantID)
 	if err != nil {
 		metricBlocklistErrors.WithLabelValues(tenantID).Inc()
 		level.Error(rw.logger).Log("msg", "error polling blocklist", "tenantID", tenantID, "err", err)
-		return []*backend.BlockMeta{}, []*backend.CompactedBlockMeta{}
+		return []*backend.BlockMeta{}, []*backend.CompactedBlockMeta{}, map[uuid.UUID]time.Time{}, rw.tombstonesForTenant(tenantID), rw.compactionMarkersForTenant(tenantID)
+	}
+
+	previousMetas, previousCompactedMetas, previousPolled := rw.previousPoll(tenantID)
+	staleTolerance := rw.staleToleranceForTenant(tenantID)
+
+	tombstoneIDs := make([]uuid.UUID, 0)
+	markerIDs := make([]uuid.UUID, 0)
+	for _, blockID := range blockIDs {
+		if backend.IsTombstoneID(blockID) {
+			tombstoneIDs = append(tombstoneIDs, blockID)
+		} else if backend.IsCompactionMarkerID(blockID) {
+			markerIDs = append(markerIDs, blockID)
+		}
+	}
+	markers := rw.pollCompactionMarkers(ctx, tenantID, markerIDs)
+	compactedByMarker := compactedByMarkers(markers)
+
+	type polledMeta struct {
+		meta   *backend.BlockMeta
+		polled time.Time
 	}
 
 	bg := boundedwaitgroup.New(rw.cfg.BlocklistPollConcurrency)
-	chMeta := make(chan *backend.BlockMeta, len(blockIDs))
+	chMeta := make(chan polledMeta, len(blockIDs))
 	chCompactedMeta := make(chan *backend.CompactedBlockMeta, len(blockIDs))
 
+	now := time.Now()
 	for _, blockID := range blockIDs {
+		if backend.IsTombstoneID(blockID) || backend.IsCompactionMarkerID(blockID) {
+			continue
+		}
+
+		if cm, ok := previousCompactedMetas[blockID]; ok {
+			metricBlocklistPollReused.Inc()
+			chCompactedMeta <- cm
+			continue
+		}
+
+		// within the stale tolerance the live meta is reused unless a compaction marker lists the block
+		previous, known := previousMetas[blockID]
+		if known && now.Sub(previousPolled[blockID]) < staleTolerance {
+			metricBlocklistPollReused.Inc()
+			if compactedTime, ok := compactedByMarker[blockID]; ok {
+				chCompactedMeta <- &backend.CompactedBlockMeta{
+					BlockMeta:     *previous,
+					CompactedTime: compactedTime,
+				}
+				continue
+			}
+			chMeta <- polledMeta{meta: previous, polled: previousPolled[blockID]}
+			continue
+		}
+
 		bg.Add(1)
 		go func(b uuid.UUID) {
 			defer bg.Done()
-			m, cm := rw.pollBlock(ctx, tenantID, b)
+			m, cm, err := rw.pollBlock(ctx, tenantID, b)
 			if m != nil {
-				chMeta <- m
+				chMeta <- polledMeta{meta: m, polled: now}
 			} else if cm != nil {
 				chCompactedMeta <- cm
+			} else if err != nil && known {
+				// keep the block until it can be polled again
+				chMeta <- polledMeta{meta: previous, polled: previousPolled[b]}
 			}
 		}(blockID)
 	}
@@ -378,8 +603,10 @@ func (rw *readerWriter) pollTenant(ctx context.Context, tenantID string) ([]*bac
 	close(chCompactedMeta)
 
 	newBlockList := make([]*backend.BlockMeta, 0, len(blockIDs))
+	newPolled := make(map[uuid.UUID]time.Time, len(blockIDs))
 	for m := range chMeta {
-		newBlockList = append(newBlockList, m)
+		newBlockList = append(newBlockList, m.meta)
+		newPolled[m.meta.BlockID] = m.polled
 	}
 	sort.Slice(newBlockList, func(i, j int) bool {
 		return newBlockList[i].StartTime.Before(newBlockList[j].StartTime)
@@ -393,10 +620,45 @@ func (rw *readerWriter) pollTenant(ctx context.Context, tenantID string) ([]*bac
 		return newCompactedBlocklist[i].StartTime.Before(newCompactedBlocklist[j].StartTime)
 	})
 
-	return newBlockList, newCompactedBlocklist
+	return newBlockList, newCompactedBlocklist, newPolled, rw.pollTombstones(ctx, tenantID, tombstoneIDs), markers
+}
+
+// staleToleranceForTenant returns how long live block metas of the passed tenant are reused
+func (rw *readerWriter) staleToleranceForTenant(tenantID string) time.Duration {
+	if rw.cfg.PollOverrides != nil {
+		if t := rw.cfg.PollOverrides.BlocklistPollStaleTolerance(tenantID); t > 0 {
+			return t
+		}
+	}
+	return rw.cfg.BlocklistPollStaleTolerance
+}
+
+// previousPoll returns the metas of the tenant's blocks and the time they were fetched at by the previous poll
+func (rw *readerWriter) previousPoll(tenantID string) (map[uuid.UUID]*backend.BlockMeta, map[uuid.UUID]*backend.CompactedBlockMeta, map[uuid.UUID]time.Time) {
+	rw.blockListsMtx.Lock()
+	defer rw.blockListsMtx.Unlock()
+
+	metas := make(map[uuid.UUID]*backend.BlockMeta, len(rw.blockLists[tenantID]))
+	for _, m := range rw.blockLists[tenantID] {
+		metas[m.BlockID] = m
+	}
+
+	compactedMetas := make(map[uuid.UUID]*backend.CompactedBlockMeta, len(rw.compactedBlockLists[tenantID]))
+	for _, cm := range rw.compactedBlockLists[tenantID] {
+		compactedMetas[cm.BlockID] = cm
+	}
+
+	polled := make(map[uuid.UUID]time.Time, len(rw.blockMetaPolled[tenantID]))
+	for id, t := range rw.blockMetaPolled[tenantID] {
+		polled[id] = t
+	}
+
+	return metas, compactedMetas, polled
 }
 
-func (rw *readerWriter) pollBlock(ctx context.Context, tenantID string, blockID uuid.UUID) (*backend.BlockMeta, *backend.CompactedBlockMeta) {
+// pollBlock fetches the meta or compacted meta of a block.  Both are nil if the block has neither, which is
+//  expected for blocks that are being written or cleared.
+func (rw *readerWriter) pollBlock(ctx context.Context, tenantID string, blockID uuid.UUID) (*backend.BlockMeta, *backend.CompactedBlockMeta, error) {
 	var compactedBlockMeta *backend.CompactedBlockMeta
 	blockMeta, err := rw.r.BlockMeta(ctx, blockID, tenantID)
 	// if the normal meta doesn't exist maybe it's compacted.
@@ -408,16 +670,16 @@ func (rw *readerWriter) pollBlock(ctx context.Context, tenantID string, blockID
 	// blocks in intermediate states may not have a compacted or normal block meta.
 	//   this is not necessarily an error, just bail out
 	if err == backend.ErrMetaDoesNotExist {
-		return nil, nil
+		return nil, nil, nil
 	}
 
 	if err != nil {
 		metricBlocklistErrors.WithLabelValues(tenantID).Inc()
 		level.Error(rw.logger).Log("msg", "failed to retrieve block meta", "tenantID", tenantID, "blockID", blockID, "err", err)
-		return nil, nil
+		return nil, nil, err
 	}
 
-	return blockMeta, compactedBlockMeta
+	return blockMeta, compactedBlockMeta, nil
 }
 
 func (rw *readerWriter) blocklistTenants() []interface{} {
@@ -480,6 +742,24 @@ func (rw *readerWriter) cleanMissingTenants(tenants []string) {
 			level.Info(rw.logger).Log("msg", "deleted in-memory compacted blocklists", "tenantID", tenantID)
 		}
 	}
+
+	for tenantID := range rw.blockMetaPolled {
+		if _, present := tenantSet[tenantID]; !present {
+			delete(rw.blockMetaPolled, tenantID)
+		}
+	}
+
+	for tenantID := range rw.tombstones {
+		if _, present := tenantSet[tenantID]; !present {
+			delete(rw.tombstones, tenantID)
+		}
+	}
+
+	for tenantID := range rw.compactionMarkers {
+		if _, present := tenantSet[tenantID]; !present {
+			delete(rw.compactionMarkers, tenantID)
+		}
+	}
 	rw.blockListsMtx.Unlock()
 }
 
@@ -542,3 +822,15 @@ func includeCompactedBlock(c *backend.CompactedBlockMeta, id common.ID, blockSta
 	}
 	return includeBlock(&c.BlockMeta, id, blockStart, blockEnd)
 }
+
+// blockConfigWithVersion returns a copy of the passed block config with the version replaced.  If
+//  version is empty the original config is returned.
+func blockConfigWithVersion(cfg *encoding.BlockConfig, version string) *encoding.BlockConfig {
+	if version == "" {
+		return cfg
+	}
+
+	versioned := *cfg
+	versioned.Version = version
+	return &versioned
+}
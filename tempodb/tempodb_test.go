@@ -273,6 +273,163 @@ func TestBlockCleanup(t *testing.T) {
 	assert.False(t, ok)
 }
 
+// countingMetaReader counts the meta requests of the blocklist poll
+type countingMetaReader struct {
+	backend.Reader
+	backend.Compactor
+
+	requests int
+}
+
+func (c *countingMetaReader) BlockMeta(ctx context.Context, blockID uuid.UUID, tenantID string) (*backend.BlockMeta, error) {
+	c.requests++
+	return c.Reader.BlockMeta(ctx, blockID, tenantID)
+}
+
+func (c *countingMetaReader) CompactedBlockMeta(blockID uuid.UUID, tenantID string) (*backend.CompactedBlockMeta, error) {
+	c.requests++
+	return c.Compactor.CompactedBlockMeta(blockID, tenantID)
+}
+
+type mockPollOverrides struct {
+	staleTolerance time.Duration
+}
+
+func (m *mockPollOverrides) BlocklistPollStaleTolerance(string) time.Duration {
+	return m.staleTolerance
+}
+
+func TestBlocklistPollStaleTolerance(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, err, "unexpected error creating temp dir")
+
+	overrides := &mockPollOverrides{}
+	r, _, _, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll:               0,
+		BlocklistPollStaleTolerance: time.Hour,
+		PollOverrides:               overrides,
+	}, log.NewNopLogger())
+	assert.NoError(t, err)
+
+	rw := r.(*readerWriter)
+	counting := &countingMetaReader{Reader: rw.r, Compactor: rw.c}
+	rw.r = counting
+	rw.c = counting
+	blockID := uuid.New()
+
+	meta := backend.NewBlockMeta(testTenantID, blockID, encoding.CurrentVersion, backend.EncNone)
+	err = rw.w.WriteBlockMeta(context.Background(), meta)
+	assert.NoError(t, err)
+	checkBlocklists(t, blockID, 1, 0, rw)
+	assert.Equal(t, 1, counting.requests)
+
+	// the live meta is reused within the stale tolerance without a request
+	archived := *meta
+	archived.StorageClass = "STANDARD_IA"
+	err = rw.w.WriteBlockMeta(context.Background(), &archived)
+	assert.NoError(t, err)
+	checkBlocklists(t, blockID, 1, 0, rw)
+	assert.Equal(t, "", rw.blocklist(testTenantID)[0].StorageClass)
+	assert.Equal(t, 1, counting.requests)
+
+	// and fetched again once it is stale
+	rw.cfg.BlocklistPollStaleTolerance = 0
+	checkBlocklists(t, blockID, 1, 0, rw)
+	assert.Equal(t, "STANDARD_IA", rw.blocklist(testTenantID)[0].StorageClass)
+	assert.Equal(t, 2, counting.requests)
+
+	// the tolerance is overridden per tenant
+	overrides.staleTolerance = time.Hour
+
+	// blocks compacted without a marker stay in the blocklist until their meta is stale
+	err = rw.c.MarkBlockCompacted(blockID, testTenantID)
+	assert.NoError(t, err)
+	checkBlocklists(t, blockID, 1, 0, rw)
+
+	// blocks listed in a compaction marker leave the blocklist right away
+	rw.writeCompactionMarker(context.Background(), testTenantID, []uuid.UUID{blockID}, time.Now())
+	checkBlocklists(t, blockID, 0, 1, rw)
+	assert.Len(t, rw.compactionMarkersForTenant(testTenantID), 1)
+	assert.Equal(t, 2, counting.requests)
+
+	// compacted metas are never fetched again
+	err = os.Remove(path.Join(tempDir, "traces", testTenantID, blockID.String(), "meta.compacted.json"))
+	assert.NoError(t, err)
+	checkBlocklists(t, blockID, 0, 1, rw)
+
+	// blocks that are no longer listed are dropped
+	err = rw.c.ClearBlock(blockID, testTenantID)
+	assert.NoError(t, err)
+	checkBlocklists(t, blockID, 0, 0, rw)
+	assert.Equal(t, 2, counting.requests)
+}
+
+func TestReadBackend(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, err, "unexpected error creating temp dir")
+
+	writePath := path.Join(tempDir, "write")
+	readPath := path.Join(tempDir, "read")
+	assert.NoError(t, os.MkdirAll(readPath, os.ModePerm))
+
+	r, _, _, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: writePath,
+		},
+		ReadBackend: &BackendConfig{
+			Backend: "local",
+			Local: &local.Config{
+				Path: readPath,
+			},
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	assert.NoError(t, err)
+
+	rw := r.(*readerWriter)
+	blockID := uuid.New()
+
+	// blocks are written to the main backend and not visible until they reach the read backend
+	err = rw.w.WriteBlockMeta(context.Background(), backend.NewBlockMeta(testTenantID, blockID, encoding.CurrentVersion, backend.EncNone))
+	assert.NoError(t, err)
+	checkBlocklists(t, blockID, 0, 0, rw)
+
+	copyDir(t, path.Join(writePath, testTenantID, blockID.String()), path.Join(readPath, testTenantID, blockID.String()))
+	checkBlocklists(t, blockID, 1, 0, rw)
+
+	// blocks are marked compacted in the main backend
+	err = rw.c.MarkBlockCompacted(blockID, testTenantID)
+	assert.NoError(t, err)
+	_, err = os.Stat(path.Join(writePath, testTenantID, blockID.String(), "meta.compacted.json"))
+	assert.NoError(t, err)
+	checkBlocklists(t, blockID, 1, 0, rw)
+}
+
 func TestCleanMissingTenants(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -975,3 +1132,80 @@ func TestSearchCompactedBlocks(t *testing.T) {
 		assert.True(t, proto.Equal(out, reqs[i]))
 	}
 }
+
+func TestFindDeletedBlocks(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	assert.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 17,
+			BloomFP:              .01,
+			Encoding:             backend.EncLZ4_256k,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:     10,
+		MaxCompactionRange: time.Hour,
+	}, &mockSharder{}, &mockOverrides{})
+
+	// compacted blocks are searched for one poll interval.  it is set after New so no background poll races the
+	//  polls below
+	rw := r.(*readerWriter)
+	rw.cfg.BlocklistPoll = time.Minute
+
+	head, err := w.WAL().NewBlock(uuid.New(), testTenantID)
+	require.NoError(t, err)
+
+	id := make([]byte, 16)
+	rand.Read(id)
+	bReq, err := proto.Marshal(test.MakeRequest(10, id))
+	require.NoError(t, err)
+	require.NoError(t, head.Write(id, bReq))
+
+	complete, err := w.CompleteBlock(head, &mockSharder{})
+	require.NoError(t, err)
+	require.NoError(t, w.WriteBlock(context.Background(), complete))
+
+	rw.pollBlocklist()
+	require.NoError(t, rw.compact([]*backend.BlockMeta{complete.BlockMeta()}, testTenantID))
+	rw.pollBlocklist()
+
+	compactedBefore, err := test.GetCounterVecValue(metricFindBlocksDeleted, "true")
+	require.NoError(t, err)
+	liveBefore, err := test.GetCounterVecValue(metricFindBlocksDeleted, "false")
+	require.NoError(t, err)
+
+	// the compacted block is deleted after the poll.  the trace is found in the new block
+	require.NoError(t, rw.c.ClearBlock(complete.BlockMeta().BlockID, testTenantID))
+	bFound, err := r.Find(context.Background(), testTenantID, id, BlockIDMin, BlockIDMax)
+	require.NoError(t, err)
+	assert.Len(t, bFound, 1)
+
+	compactedAfter, err := test.GetCounterVecValue(metricFindBlocksDeleted, "true")
+	require.NoError(t, err)
+	assert.Equal(t, compactedBefore+1, compactedAfter)
+
+	// a deleted live block fails the search
+	blocklist := rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 1)
+	require.NoError(t, rw.c.ClearBlock(blocklist[0].BlockID, testTenantID))
+	_, err = r.Find(context.Background(), testTenantID, id, BlockIDMin, BlockIDMax)
+	assert.Error(t, err)
+
+	liveAfter, err := test.GetCounterVecValue(metricFindBlocksDeleted, "false")
+	require.NoError(t, err)
+	assert.Equal(t, liveBefore+1, liveAfter)
+}
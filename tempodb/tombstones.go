@@ -0,0 +1,171 @@
+package tempodb
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"github.com/go-kit/kit/log/level"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+)
+
+const tombstonesName = "tombstones.json"
+
+var metricCompactionObjectsDeleted = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "tempodb",
+	Name:      "compaction_objects_deleted_total",
+	Help:      "Total number of objects dropped during compaction because their trace was deleted.",
+}, []string{"tenant"})
+
+// tombstones are the trace ids of a tenant that were marked for deletion by one request
+type tombstones struct {
+	// TraceIDs maps hex encoded trace ids to the time their deletion was requested
+	TraceIDs map[string]time.Time `json:"traceIDs"`
+
+	// polled is the time the tombstones were first found by this process
+	polled time.Time
+}
+
+// DeleteTraces marks the passed trace ids of the tenant for deletion.  Every request writes its own tombstone
+//  object so requests handled by different compactors at the same time never overwrite each other.
+//  Tombstones are picked up by the next blocklist poll, after which the traces are no longer returned by Find.
+//  Compactors rewrite every block that still contains a deleted trace.  Tombstones are kept for the retention of
+//  the tenant, after which every block written before the deletion was requested is gone.
+func (rw *readerWriter) DeleteTraces(ctx context.Context, tenantID string, ids []common.ID) error {
+	if tenantID == "" {
+		return backend.ErrEmptyTenantID
+	}
+
+	t := &tombstones{
+		TraceIDs: make(map[string]time.Time, len(ids)),
+	}
+	now := time.Now()
+	for _, id := range ids {
+		t.TraceIDs[hex.EncodeToString(id)] = now
+	}
+
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	return rw.w.WriteReader(ctx, tombstonesName, backend.NewTombstoneID(), tenantID, bytes.NewReader(b), int64(len(b)))
+}
+
+// deletedTraces returns the set of hex encoded trace ids of the tenant that were marked for deletion as of the
+//  last blocklist poll
+func (rw *readerWriter) deletedTraces(tenantID string) map[string]struct{} {
+	deleted := map[string]struct{}{}
+	for _, t := range rw.tombstonesForTenant(tenantID) {
+		for id := range t.TraceIDs {
+			deleted[id] = struct{}{}
+		}
+	}
+	return deleted
+}
+
+// isTraceDeleted returns true if the trace of the tenant was marked for deletion as of the last blocklist poll
+func (rw *readerWriter) isTraceDeleted(tenantID string, id common.ID) bool {
+	rw.blockListsMtx.Lock()
+	defer rw.blockListsMtx.Unlock()
+
+	hexID := hex.EncodeToString(id)
+	for _, t := range rw.tombstones[tenantID] {
+		if _, ok := t.TraceIDs[hexID]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// tombstonesForTenant returns the tombstone objects of the tenant found by the last blocklist poll by id
+func (rw *readerWriter) tombstonesForTenant(tenantID string) map[uuid.UUID]*tombstones {
+	rw.blockListsMtx.Lock()
+	defer rw.blockListsMtx.Unlock()
+
+	all := make(map[uuid.UUID]*tombstones, len(rw.tombstones[tenantID]))
+	for id, t := range rw.tombstones[tenantID] {
+		all[id] = t
+	}
+	return all
+}
+
+// clearExpiredTombstones removes the tombstone objects of the tenant whose deletions were all requested longer
+//  than the retention of the tenant ago
+func (rw *readerWriter) clearExpiredTombstones(tenantID string) {
+	cutoff := time.Now().Add(-rw.retentionForTenant(tenantID))
+	for id, t := range rw.tombstonesForTenant(tenantID) {
+		if !expired(t, cutoff) || !rw.compactorSharder.Owns(id.String()) {
+			continue
+		}
+
+		level.Info(rw.logger).Log("msg", "deleting expired tombstones", "tombstoneID", id, "tenantID", tenantID)
+		err := rw.c.ClearBlock(id, tenantID)
+		if err != nil && err != backend.ErrObjectLocked {
+			level.Error(rw.logger).Log("msg", "failed to clear expired tombstones", "tombstoneID", id, "tenantID", tenantID, "err", err)
+			metricRetentionErrors.Inc()
+		}
+	}
+}
+
+// pollTombstones returns the tombstone objects with the passed ids.  Tombstones are never changed once written so
+//  only those unknown to the previous poll are read.  A tombstone that fails to read is retried on the next poll.
+func (rw *readerWriter) pollTombstones(ctx context.Context, tenantID string, ids []uuid.UUID) map[uuid.UUID]*tombstones {
+	previous := rw.tombstonesForTenant(tenantID)
+
+	all := make(map[uuid.UUID]*tombstones, len(ids))
+	for _, id := range ids {
+		if t, ok := previous[id]; ok {
+			all[id] = t
+			continue
+		}
+
+		b, err := rw.r.Read(ctx, tombstonesName, id, tenantID)
+		if err == backend.ErrDoesNotExist {
+			// removed by retention since listing
+			continue
+		}
+		if err != nil {
+			metricBlocklistErrors.WithLabelValues(tenantID).Inc()
+			level.Error(rw.logger).Log("msg", "failed to read tombstones", "tombstoneID", id, "tenantID", tenantID, "err", err)
+			continue
+		}
+
+		t := &tombstones{
+			polled: time.Now(),
+		}
+		err = json.Unmarshal(b, t)
+		if err != nil {
+			metricBlocklistErrors.WithLabelValues(tenantID).Inc()
+			level.Error(rw.logger).Log("msg", "failed to unmarshal tombstones", "tombstoneID", id, "tenantID", tenantID, "err", err)
+			continue
+		}
+		all[id] = t
+	}
+
+	return all
+}
+
+func expired(t *tombstones, cutoff time.Time) bool {
+	for _, requested := range t.TraceIDs {
+		if !requested.Before(cutoff) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDeleted(deleted map[string]struct{}, id []byte) bool {
+	if len(deleted) == 0 {
+		return false
+	}
+	_, ok := deleted[hex.EncodeToString(id)]
+	return ok
+}
@@ -0,0 +1,373 @@
+package tempodb
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/tempo/pkg/util/test"
+	"github.com/grafana/tempo/tempodb/backend"
+	"github.com/grafana/tempo/tempodb/backend/local"
+	"github.com/grafana/tempo/tempodb/encoding"
+	"github.com/grafana/tempo/tempodb/encoding/common"
+	"github.com/grafana/tempo/tempodb/pool"
+	"github.com/grafana/tempo/tempodb/wal"
+)
+
+func TestDeleteTraces(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:     10,
+		MaxCompactionRange: 24 * time.Hour,
+		BlockRetention:     time.Hour,
+	}, &mockSharder{}, &mockOverrides{})
+
+	cutTestBlocks(t, w, testTenantID, 2, 2)
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+
+	deletedStart, err := test.GetCounterVecValue(metricCompactionObjectsDeleted, testTenantID)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, c.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(0, 0)}))
+	require.NoError(t, c.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(1, 1)}))
+
+	// the tombstone block is ignored by polling, its traces are picked up
+	rw.pollBlocklist()
+	require.Len(t, rw.blocklist(testTenantID), 2)
+	require.Len(t, rw.deletedTraces(testTenantID), 2)
+
+	// deleted traces are no longer found although the blocks still contain them
+	objs, err := rw.Find(ctx, testTenantID, makeTraceID(0, 0), BlockIDMin, BlockIDMax)
+	require.NoError(t, err)
+	assert.Len(t, objs, 0)
+
+	// deleted traces are dropped by compaction
+	require.NoError(t, rw.compact(rw.blocklist(testTenantID), testTenantID))
+
+	blocklist := rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 1)
+	assert.Equal(t, 2, blocklist[0].TotalObjects)
+
+	for _, id := range [][]byte{makeTraceID(0, 0), makeTraceID(1, 1)} {
+		objs, err := rw.Find(ctx, testTenantID, id, BlockIDMin, BlockIDMax)
+		require.NoError(t, err)
+		assert.Len(t, objs, 0)
+	}
+	for _, id := range [][]byte{makeTraceID(0, 1), makeTraceID(1, 0)} {
+		objs, err := rw.Find(ctx, testTenantID, id, BlockIDMin, BlockIDMax)
+		require.NoError(t, err)
+		assert.Len(t, objs, 1)
+	}
+
+	deletedEnd, err := test.GetCounterVecValue(metricCompactionObjectsDeleted, testTenantID)
+	require.NoError(t, err)
+	assert.Equal(t, float64(2), deletedEnd-deletedStart)
+
+	// tombstones past retention are dropped
+	rw.compactorCfg.BlockRetention = time.Nanosecond
+	rw.clearExpiredTombstones(testTenantID)
+	rw.pollBlocklist()
+	assert.Len(t, rw.deletedTraces(testTenantID), 0)
+}
+
+func TestDeletionRewritesBlocks(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	r, w, c, err := New(&Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.EnableCompaction(&CompactorConfig{
+		ChunkSizeBytes:     10,
+		FlushSizeBytes:     10,
+		MaxCompactionRange: 24 * time.Hour,
+		BlockRetention:     time.Hour,
+	}, &mockSharder{}, &mockOverrides{})
+
+	cutTestBlocks(t, w, testTenantID, 2, 2)
+
+	ctx := context.Background()
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+
+	require.NoError(t, c.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(0, 0)}))
+	rw.pollBlocklist()
+
+	rewrittenStart, err := test.GetCounterValue(metricDeletionBlocks)
+	require.NoError(t, err)
+
+	// blocks in the active window are left to compaction
+	assert.True(t, rw.doDeletion(testTenantID, time.Now()))
+	assert.Len(t, rw.deletionChecked[testTenantID], 0)
+	for _, b := range rw.blocklist(testTenantID) {
+		b.StartTime = b.StartTime.Add(-48 * time.Hour)
+		b.EndTime = b.EndTime.Add(-48 * time.Hour)
+		require.NoError(t, rw.w.WriteBlockMeta(ctx, b))
+	}
+	rw.pollBlocklist()
+
+	// no block is searched past the deadline
+	assert.False(t, rw.doDeletion(testTenantID, time.Now().Add(-time.Second)))
+	assert.Len(t, rw.deletionChecked[testTenantID], 0)
+
+	// only the block containing the deleted trace is rewritten
+	assert.True(t, rw.doDeletion(testTenantID, time.Now().Add(time.Hour)))
+
+	rewrittenEnd, err := test.GetCounterValue(metricDeletionBlocks)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), rewrittenEnd-rewrittenStart)
+
+	rw.pollBlocklist()
+	blocklist := rw.blocklist(testTenantID)
+	require.Len(t, blocklist, 2)
+	for _, b := range blocklist {
+		contains, err := rw.containsAny(ctx, b, []common.ID{makeTraceID(0, 0)})
+		require.NoError(t, err)
+		assert.False(t, contains)
+	}
+
+	// searched blocks are not searched again until new tombstones are polled
+	assert.True(t, rw.doDeletion(testTenantID, time.Now().Add(time.Hour)))
+	assert.Len(t, rw.deletionChecked[testTenantID], 2)
+
+	rewrittenEnd, err = test.GetCounterValue(metricDeletionBlocks)
+	require.NoError(t, err)
+	assert.Equal(t, float64(1), rewrittenEnd-rewrittenStart)
+}
+
+func TestDeleteTracesConcurrently(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	cfg := &Config{
+		Backend: "local",
+		Pool: &pool.Config{
+			MaxWorkers: 10,
+			QueueDepth: 100,
+		},
+		Local: &local.Config{
+			Path: path.Join(tempDir, "traces"),
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}
+
+	// two compactors sharing a backend
+	_, _, c1, err := New(cfg, log.NewNopLogger())
+	require.NoError(t, err)
+	r2, _, c2, err := New(cfg, log.NewNopLogger())
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c := c1
+			if i%2 == 0 {
+				c = c2
+			}
+			assert.NoError(t, c.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(i, 0)}))
+		}(i)
+	}
+	wg.Wait()
+
+	rw2 := r2.(*readerWriter)
+	rw2.pollBlocklist()
+	assert.Len(t, rw2.deletedTraces(testTenantID), 10)
+}
+
+func TestPollTombstones(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	rawR, rawW, _, err := local.New(&local.Config{
+		Path: path.Join(tempDir, "traces"),
+	})
+	require.NoError(t, err)
+
+	r := &countingReader{Reader: rawR}
+	rw := &readerWriter{
+		r:                   r,
+		w:                   rawW,
+		logger:              log.NewNopLogger(),
+		cfg:                 &Config{BlocklistPollConcurrency: 1},
+		blockLists:          map[string][]*backend.BlockMeta{},
+		compactedBlockLists: map[string][]*backend.CompactedBlockMeta{},
+		blockMetaPolled:     map[string]map[uuid.UUID]time.Time{},
+		tombstones:          map[string]map[uuid.UUID]*tombstones{},
+		compactionMarkers:   map[string]map[uuid.UUID]*compactionMarker{},
+	}
+
+	ctx := context.Background()
+	require.NoError(t, rw.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(0, 0)}))
+	require.NoError(t, rw.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(1, 0)}))
+
+	rw.pollBlocklist()
+	assert.Len(t, rw.deletedTraces(testTenantID), 2)
+	assert.Equal(t, 2, r.reads)
+
+	// tombstones never change and are not read again
+	require.NoError(t, rw.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(2, 0)}))
+	rw.pollBlocklist()
+	assert.Len(t, rw.deletedTraces(testTenantID), 3)
+	assert.Equal(t, 3, r.reads)
+}
+
+func TestDeleteTracesWithReadBackend(t *testing.T) {
+	tempDir, err := ioutil.TempDir("/tmp", "")
+	defer os.RemoveAll(tempDir)
+	require.NoError(t, err, "unexpected error creating temp dir")
+
+	readPath := path.Join(tempDir, "read")
+	require.NoError(t, os.MkdirAll(readPath, os.ModePerm))
+
+	r, _, c, err := New(&Config{
+		Backend: "local",
+		Local: &local.Config{
+			Path: path.Join(tempDir, "write"),
+		},
+		ReadBackend: &BackendConfig{
+			Backend: "local",
+			Local: &local.Config{
+				Path: readPath,
+			},
+		},
+		Block: &encoding.BlockConfig{
+			IndexDownsampleBytes: 11,
+			BloomFP:              .01,
+			Encoding:             backend.EncNone,
+			IndexPageSizeBytes:   1000,
+		},
+		WAL: &wal.Config{
+			Filepath: path.Join(tempDir, "wal"),
+		},
+		BlocklistPoll: 0,
+	}, log.NewNopLogger())
+	require.NoError(t, err)
+
+	// tombstones are polled together with the blocks from the read backend
+	ctx := context.Background()
+	require.NoError(t, c.DeleteTraces(ctx, testTenantID, []common.ID{makeTraceID(0, 0)}))
+
+	rw := r.(*readerWriter)
+	rw.pollBlocklist()
+	assert.Len(t, rw.deletedTraces(testTenantID), 0)
+
+	_, readW, _, err := local.New(&local.Config{
+		Path: readPath,
+	})
+	require.NoError(t, err)
+	require.NoError(t, copyTombstones(ctx, path.Join(tempDir, "write"), readW))
+
+	rw.pollBlocklist()
+	assert.Len(t, rw.deletedTraces(testTenantID), 1)
+}
+
+// copyTombstones copies the tombstones of the local backend at the passed path to the passed writer, like
+//  replicating the backend would
+func copyTombstones(ctx context.Context, from string, to backend.Writer) error {
+	r, _, _, err := local.New(&local.Config{
+		Path: from,
+	})
+	if err != nil {
+		return err
+	}
+
+	ids, err := r.Blocks(ctx, testTenantID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		b, err := r.Read(ctx, tombstonesName, id, testTenantID)
+		if err != nil {
+			return err
+		}
+		err = to.Write(ctx, tombstonesName, id, testTenantID, b)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countingReader counts the tombstones read
+type countingReader struct {
+	backend.Reader
+	reads int
+}
+
+func (r *countingReader) Read(ctx context.Context, name string, blockID uuid.UUID, tenantID string) ([]byte, error) {
+	if name == tombstonesName {
+		r.reads++
+	}
+	return r.Reader.Read(ctx, name, blockID, tenantID)
+}